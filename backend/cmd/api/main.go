@@ -14,6 +14,10 @@ import (
 	"github.com/kinyichukwu/edu-pro-backend/internal/middleware"
 	"github.com/kinyichukwu/edu-pro-backend/internal/services/ai"
 	"github.com/kinyichukwu/edu-pro-backend/internal/services/database"
+	"github.com/kinyichukwu/edu-pro-backend/internal/services/extract"
+	"github.com/kinyichukwu/edu-pro-backend/internal/services/jobs"
+	"github.com/kinyichukwu/edu-pro-backend/internal/services/progress"
+	"github.com/kinyichukwu/edu-pro-backend/internal/services/storage"
 	"github.com/kinyichukwu/edu-pro-backend/internal/utils"
 
 	"github.com/gin-gonic/gin"
@@ -21,6 +25,11 @@ import (
 )
 
 func main() {
+	// The binary doubles as the isolated document extraction worker
+	if len(os.Args) > 1 && os.Args[1] == extract.WorkerCommand {
+		os.Exit(extract.RunWorker(os.Args[2:]))
+	}
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -61,16 +70,60 @@ func main() {
 	}
 	defer pgxClient.Close()
 
+	// The embedding column must accept the configured dimension
+	if err := pgxClient.CheckEmbeddingDimension(context.Background(), cfg.EmbeddingDimension); err != nil {
+		logger.Fatal("Embedding dimension check failed", zap.Error(err))
+	}
+
+	// Build and maintain the ANN index on chunk embeddings
+	vectorIndexManager := database.NewVectorIndexManager(pgxClient, database.VectorIndexConfig{
+		Method:             cfg.VectorIndexMethod,
+		Dimension:          cfg.EmbeddingDimension,
+		HNSWM:              cfg.VectorHNSWM,
+		HNSWEfConstruction: cfg.VectorHNSWEfConstruction,
+		IVFFlatLists:       cfg.VectorIVFFlatLists,
+		EfSearch:           cfg.VectorHNSWEfSearch,
+		Probes:             cfg.VectorIVFFlatProbes,
+		CheckInterval:      cfg.VectorIndexCheckInterval,
+	})
+	vectorIndexManager.Start()
+
 	// Initialize handlers
 	healthHandler := handlers.NewHealthHandler(aiService)
 	queryHandler := handlers.NewQueryHandler(aiService)
 	authHandler := handlers.NewAuthHandler(dbClient, cfg)
 	userHandler := handlers.NewUserHandler(dbClient)
-	ragHandler, err := handlers.NewRAGHandler(dbClient, pgxClient, cfg, aiService)
+	// Processing progress is relayed between instances through Postgres
+	progressBroker := progress.NewBroker(pgxClient.GetPool())
+	progressBroker.Start()
+
+	// Ingestion jobs are queued by the RAG handler and run by the workers
+	jobsClient := jobs.NewClient(pgxClient.GetPool(), cfg.IngestMaxAttempts)
+
+	ragHandler, err := handlers.NewRAGHandler(dbClient, pgxClient, cfg, aiService, progressBroker, jobsClient)
 	if err != nil {
 		logger.Fatal("Failed to initialize RAG handler", zap.Error(err))
 	}
 
+	// Start ingestion workers
+	ingestWorkers := jobs.NewWorkerPool(jobsClient, ragHandler.ProcessIngestionJob, cfg.IngestWorkers)
+	ingestWorkers.Start()
+
+	// Start orphaned blob reconciliation
+	blobStore, err := storage.NewBlobStore(cfg)
+	if err != nil {
+		logger.Fatal("Failed to initialize blob store", zap.Error(err))
+	}
+	storageReconciler := storage.NewReconciler(
+		blobStore,
+		pgxClient.ReferencedStoragePaths,
+		storage.KeyPrefix,
+		cfg.StorageReconcileInterval,
+		cfg.StorageOrphanGracePeriod,
+		!cfg.StorageReconcileDelete,
+	)
+	storageReconciler.Start()
+
 	// Setup router
 	router := setupRouter(cfg, healthHandler, queryHandler, authHandler, userHandler, ragHandler)
 
@@ -82,6 +135,8 @@ func main() {
 		WriteTimeout: 30 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	// Close open event streams so shutdown doesn't wait for them
+	srv.RegisterOnShutdown(progressBroker.Stop)
 
 	// Start server in a goroutine
 	go func() {
@@ -106,6 +161,12 @@ func main() {
 		logger.Error("Server forced to shutdown", zap.Error(err))
 	}
 
+	// Give in-flight ingestion jobs a grace period to finish; any still
+	// running after it are aborted and requeued without using an attempt
+	ingestWorkers.Stop()
+	storageReconciler.Stop()
+	vectorIndexManager.Stop()
+
 	logger.Info("Server exited")
 }
 
@@ -147,11 +208,24 @@ func setupRouter(cfg *config.Config, healthHandler *handlers.HealthHandler, quer
 			user.GET("/onboarding", userHandler.GetOnboarding)
 			user.PUT("/onboarding", userHandler.UpdateOnboarding)
 			user.PUT("/profile", userHandler.UpdateProfile)
+			user.POST("/reindex", ragHandler.StartReindex)
+			user.GET("/reindex", ragHandler.GetReindexStatus)
+			user.GET("/usage", ragHandler.GetUsage)
 		}
 
 		// RAG routes (protected) - Apply JWT middleware individually to avoid CORS conflicts
 		api.POST("/upload", middleware.JWTMiddleware(cfg), ragHandler.Upload)
+		api.POST("/uploads", middleware.JWTMiddleware(cfg), ragHandler.CreateUpload)
+		api.GET("/uploads/:id", middleware.JWTMiddleware(cfg), ragHandler.GetUpload)
+		api.PATCH("/uploads/:id", middleware.JWTMiddleware(cfg), ragHandler.AppendUpload)
+		api.DELETE("/uploads/:id", middleware.JWTMiddleware(cfg), ragHandler.DeleteUpload)
 		api.GET("/documents", middleware.JWTMiddleware(cfg), ragHandler.GetDocuments)
+		api.POST("/documents/import", middleware.JWTMiddleware(cfg), ragHandler.ImportDocument)
+		api.GET("/documents/batches/:id", middleware.JWTMiddleware(cfg), ragHandler.GetBatch)
+		api.DELETE("/documents/:id", middleware.JWTMiddleware(cfg), ragHandler.DeleteDocument)
+		api.POST("/documents/:id/reprocess", middleware.JWTMiddleware(cfg), ragHandler.ReprocessDocument)
+		api.GET("/documents/:id/chunks", middleware.JWTMiddleware(cfg), ragHandler.GetDocumentChunks)
+		api.GET("/documents/:id/events", middleware.JWTMiddleware(cfg), ragHandler.DocumentEvents)
 		api.GET("/chats", middleware.JWTMiddleware(cfg), ragHandler.GetChats)
 		api.POST("/chats", middleware.JWTMiddleware(cfg), ragHandler.CreateChat)
 		api.GET("/chats/:id", middleware.JWTMiddleware(cfg), ragHandler.GetChatMessages)
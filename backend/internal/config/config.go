@@ -3,8 +3,10 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -24,6 +26,84 @@ type Config struct {
 	DatabaseURL       string
 	// RAG Configuration
 	BucketName string
+	// Storage backend configuration
+	StorageDriver     string // supabase, s3 or local
+	StorageLocalDir   string
+	StoragePublicURL  string // base URL blobs are served from, optional
+	S3Endpoint        string // host[:port], e.g. localhost:9000 for MinIO
+	S3Region          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+	S3UseSSL          bool
+	// Orphaned blob cleanup; a zero interval disables it
+	StorageReconcileInterval time.Duration
+	StorageOrphanGracePeriod time.Duration
+	StorageReconcileDelete   bool // Orphans are only logged unless set
+	// Upload configuration
+	UploadPlanLimits   map[string]int64 // plan -> max file size in bytes
+	UploadStagingDir   string
+	UploadSessionTTL   time.Duration
+	UploadChunkMaxSize int64
+	// Per-user quotas, keyed by plan or onboarding role; users get their
+	// plan's limit, raised by their role's if that is higher. Missing
+	// plans use "free", and no "free" entry means unlimited.
+	QuotaDocuments     map[string]int64
+	QuotaStorage       map[string]int64 // bytes
+	QuotaChunks        map[string]int64
+	QuotaMonthlyTokens map[string]int64 // embedding tokens per calendar month
+	// ZIP archive limits
+	ArchiveMaxFiles     int
+	ArchiveMaxTotalSize int64   // uncompressed bytes
+	ArchiveMaxRatio     float64 // compression ratio above which a file is treated as a zip bomb
+	// URL import configuration
+	ImportTimeout             time.Duration
+	ImportAllowPrivateNetwork bool // allow importing from private addresses, for development
+	// Ingestion queue configuration
+	IngestWorkers     int
+	IngestMaxAttempts int
+	// Extraction safety limits; zero disables a limit
+	ExtractTimeout     time.Duration
+	ExtractMemoryLimit int64 // bytes
+	ExtractMaxText     int64 // bytes
+	PDFMaxPages        int
+	PDFMaxObjects      int64
+	PDFMaxRatio        float64 // decompression ratio of page content streams
+	// OCR configuration
+	OCREnabled  bool
+	OCRLanguage string
+	// Embedding provider configuration
+	EmbeddingProvider  string // gemini, openai or hash
+	EmbeddingModel     string
+	EmbeddingBaseURL   string // OpenAI-compatible endpoint, e.g. http://localhost:8000/v1
+	EmbeddingAPIKey    string
+	EmbeddingDimension int
+	// Bump when vectors from the same model are no longer comparable
+	EmbeddingModelVersion string
+	// Embedding batch configuration
+	EmbeddingBatchSize         int
+	EmbeddingConcurrency       int
+	EmbeddingRequestsPerMinute int
+	// Retrieval configuration
+	SearchKeywordWeight float64 // default weight of keyword against vector search, 0 to 1
+	SearchMaxDistance   float64 // cosine distance beyond which chunks are dropped, 0 disables
+	SearchMMRLambda     float64 // relevance against diversity when picking context, 1 disables MMR
+	RerankProvider      string  // llm, lexical or none
+	RerankCandidates    int     // chunks retrieved for reranking before the top ones are kept
+	// Conversation-aware retrieval
+	QueryRewriteEnabled bool
+	QueryRewriteHistory int // earlier chat messages used to rewrite a follow-up
+	QueryParaphrases    int // extra paraphrased queries searched alongside it
+	// Vector index configuration
+	VectorIndexMethod        string // hnsw, ivfflat or none
+	VectorHNSWM              int
+	VectorHNSWEfConstruction int
+	VectorHNSWEfSearch       int
+	VectorIVFFlatLists       int // 0 sizes lists from the row count
+	VectorIVFFlatProbes      int // 0 uses sqrt(lists)
+	VectorIndexCheckInterval time.Duration
+	// Chunking configuration
+	TokenizerModelPath string
+	ChunkStrategies    map[string]string // file type -> strategy name
 }
 
 func Load() (*Config, error) {
@@ -31,16 +111,18 @@ func Load() (*Config, error) {
 	_ = godotenv.Load()
 
 	config := &Config{
-		Port:              getEnv("PORT", "8080"),
-		GinMode:           getEnv("GIN_MODE", "release"),
-		Environment:       getEnv("ENVIRONMENT", "development"),
-		GeminiAPIKey:      getEnv("GEMINI_API_KEY", ""),
-		LogLevel:          getEnv("LOG_LEVEL", "info"),
-		SupabaseURL:       getEnv("SUPABASE_URL", ""),
-		SupabaseKey:       getEnv("SUPABASE_KEY", ""),
-		SupabaseJWTSecret: getEnv("SUPABASE_JWT_SECRET", ""),
-		DatabaseURL:       getEnv("DATABASE_URL", ""),
-		BucketName:        getEnv("BUCKET_NAME", "documents"),
+		Port:               getEnv("PORT", "8080"),
+		GinMode:            getEnv("GIN_MODE", "release"),
+		Environment:        getEnv("ENVIRONMENT", "development"),
+		GeminiAPIKey:       getEnv("GEMINI_API_KEY", ""),
+		LogLevel:           getEnv("LOG_LEVEL", "info"),
+		SupabaseURL:        getEnv("SUPABASE_URL", ""),
+		SupabaseKey:        getEnv("SUPABASE_KEY", ""),
+		SupabaseJWTSecret:  getEnv("SUPABASE_JWT_SECRET", ""),
+		DatabaseURL:        getEnv("DATABASE_URL", ""),
+		BucketName:         getEnv("BUCKET_NAME", "documents"),
+		OCRLanguage:        getEnv("OCR_LANGUAGE", "eng"),
+		TokenizerModelPath: getEnv("TOKENIZER_MODEL_PATH", ""),
 	}
 
 	// Parse allowed origins
@@ -58,15 +140,132 @@ func Load() (*Config, error) {
 	}
 	config.RateLimit = rateLimit
 
+	// Parse per plan upload size limits, e.g. "free=50,pro=200" (MB)
+	config.UploadPlanLimits = getEnvLimits("UPLOAD_PLAN_LIMITS_MB", "free=50,pro=200", 1024*1024)
+	if _, ok := config.UploadPlanLimits["free"]; !ok {
+		config.UploadPlanLimits["free"] = 50 * 1024 * 1024
+	}
+	config.UploadStagingDir = getEnv("UPLOAD_STAGING_DIR", filepath.Join(os.TempDir(), "edupro-uploads"))
+	config.UploadSessionTTL = time.Duration(getEnvInt("UPLOAD_SESSION_TTL_HOURS", 24)) * time.Hour
+	config.UploadChunkMaxSize = int64(getEnvInt("UPLOAD_CHUNK_MAX_MB", 16)) * 1024 * 1024
+
+	// Parse per-user quotas
+	config.QuotaDocuments = getEnvLimits("QUOTA_DOCUMENTS", "free=100,lecturer=300,pro=2000", 1)
+	config.QuotaStorage = getEnvLimits("QUOTA_STORAGE_MB", "free=1024,lecturer=3072,pro=20480", 1024*1024)
+	config.QuotaChunks = getEnvLimits("QUOTA_CHUNKS", "free=50000,lecturer=150000,pro=1000000", 1)
+	config.QuotaMonthlyTokens = getEnvLimits("QUOTA_MONTHLY_EMBEDDING_TOKENS", "free=2000000,lecturer=6000000,pro=50000000", 1)
+
+	// Parse storage backend settings
+	config.StorageDriver = strings.ToLower(getEnv("STORAGE_DRIVER", "supabase"))
+	config.StorageLocalDir = getEnv("STORAGE_LOCAL_DIR", "./data/blobs")
+	config.StoragePublicURL = getEnv("STORAGE_PUBLIC_URL", "")
+	config.S3Endpoint = getEnv("S3_ENDPOINT", "")
+	config.S3Region = getEnv("S3_REGION", "")
+	config.S3AccessKeyID = getEnv("S3_ACCESS_KEY_ID", "")
+	config.S3SecretAccessKey = getEnv("S3_SECRET_ACCESS_KEY", "")
+	config.S3UseSSL = getEnvBool("S3_USE_SSL", true)
+	config.StorageReconcileInterval = time.Duration(getEnvInt("STORAGE_RECONCILE_INTERVAL_MINUTES", 60)) * time.Minute
+	config.StorageReconcileDelete = getEnvBool("STORAGE_RECONCILE_DELETE", false)
+	config.StorageOrphanGracePeriod = time.Duration(getEnvInt("STORAGE_ORPHAN_GRACE_MINUTES", 60)) * time.Minute
+
+	// Parse ZIP archive limits
+	config.ArchiveMaxFiles = getEnvInt("ARCHIVE_MAX_FILES", 100)
+	config.ArchiveMaxTotalSize = int64(getEnvInt("ARCHIVE_MAX_TOTAL_MB", 500)) * 1024 * 1024
+	config.ArchiveMaxRatio = float64(getEnvInt("ARCHIVE_MAX_RATIO", 100))
+
+	// Parse URL import settings
+	config.ImportTimeout = time.Duration(getEnvInt("IMPORT_TIMEOUT_SECONDS", 30)) * time.Second
+	config.ImportAllowPrivateNetwork = getEnvBool("IMPORT_ALLOW_PRIVATE_NETWORK", false)
+
+	// Parse ingestion queue settings
+	config.IngestWorkers = getEnvInt("INGEST_WORKERS", 2)
+	config.IngestMaxAttempts = getEnvInt("INGEST_MAX_ATTEMPTS", 5)
+
+	// Parse embedding provider settings
+	config.EmbeddingProvider = getEnv("EMBEDDING_PROVIDER", "gemini")
+	config.EmbeddingModel = getEnv("EMBEDDING_MODEL", "")
+	config.EmbeddingBaseURL = getEnv("EMBEDDING_BASE_URL", "")
+	config.EmbeddingAPIKey = getEnv("EMBEDDING_API_KEY", "")
+	config.EmbeddingDimension = getEnvInt("EMBEDDING_DIMENSION", 768)
+	config.EmbeddingModelVersion = getEnv("EMBEDDING_MODEL_VERSION", "1")
+
+	// Parse embedding batch settings; the Gemini batch API takes up to 100 texts
+	config.EmbeddingBatchSize = getEnvInt("EMBEDDING_BATCH_SIZE", 100)
+	config.EmbeddingConcurrency = getEnvInt("EMBEDDING_CONCURRENCY", 4)
+	config.EmbeddingRequestsPerMinute = getEnvInt("EMBEDDING_REQUESTS_PER_MINUTE", 120)
+
+	// OCR needs tesseract and pdftoppm on the PATH, so it is opt-in
+	config.ExtractTimeout = time.Duration(getEnvInt("EXTRACT_TIMEOUT_SECONDS", 600)) * time.Second
+	config.ExtractMemoryLimit = int64(getEnvInt("EXTRACT_MEMORY_LIMIT_MB", 1024)) * 1024 * 1024
+	config.ExtractMaxText = int64(getEnvInt("EXTRACT_MAX_TEXT_MB", 50)) * 1024 * 1024
+	config.PDFMaxPages = getEnvInt("PDF_MAX_PAGES", 2000)
+	config.PDFMaxObjects = int64(getEnvInt("PDF_MAX_OBJECTS", 500000))
+	config.PDFMaxRatio = float64(getEnvInt("PDF_MAX_COMPRESSION_RATIO", 100))
+
+	config.OCREnabled = getEnvBool("OCR_ENABLED", false)
+
+	// Parse retrieval settings
+	config.SearchKeywordWeight = getEnvFloat("SEARCH_KEYWORD_WEIGHT", 0.3)
+	if config.SearchKeywordWeight < 0 || config.SearchKeywordWeight > 1 {
+		config.SearchKeywordWeight = 0.3
+	}
+	config.SearchMaxDistance = getEnvFloat("SEARCH_MAX_DISTANCE", 0.6)
+	config.SearchMMRLambda = getEnvFloat("SEARCH_MMR_LAMBDA", 0.7)
+	if config.SearchMMRLambda < 0 || config.SearchMMRLambda > 1 {
+		config.SearchMMRLambda = 0.7
+	}
+	config.RerankProvider = strings.ToLower(getEnv("RERANK_PROVIDER", "lexical"))
+	config.RerankCandidates = getEnvInt("RERANK_CANDIDATES", 24)
+	config.QueryRewriteEnabled = getEnvBool("QUERY_REWRITE_ENABLED", true)
+	config.QueryRewriteHistory = getEnvInt("QUERY_REWRITE_HISTORY", 6)
+	config.QueryParaphrases = getEnvInt("QUERY_PARAPHRASES", 0)
+
+	// Parse vector index settings
+	config.VectorIndexMethod = strings.ToLower(getEnv("VECTOR_INDEX_METHOD", "hnsw"))
+	config.VectorHNSWM = getEnvInt("VECTOR_HNSW_M", 16)
+	config.VectorHNSWEfConstruction = getEnvInt("VECTOR_HNSW_EF_CONSTRUCTION", 64)
+	config.VectorHNSWEfSearch = getEnvInt("VECTOR_HNSW_EF_SEARCH", 100)
+	config.VectorIVFFlatLists = getEnvInt("VECTOR_IVFFLAT_LISTS", 0)
+	config.VectorIVFFlatProbes = getEnvInt("VECTOR_IVFFLAT_PROBES", 0)
+	config.VectorIndexCheckInterval = time.Duration(getEnvInt("VECTOR_INDEX_CHECK_INTERVAL_MINUTES", 360)) * time.Minute
+
+	// Parse per file type chunking strategies, e.g. ".pdf=token,.docx=structure,default=token"
+	config.ChunkStrategies = make(map[string]string)
+	for _, entry := range strings.Split(getEnv("CHUNK_STRATEGIES", ""), ",") {
+		fileType, strategy, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			continue
+		}
+		config.ChunkStrategies[strings.TrimSpace(fileType)] = strings.TrimSpace(strategy)
+	}
+
 	// Validate required fields
+	// pgvector stores up to 16000 dimensions but indexes only up to 2000
+	if config.EmbeddingDimension <= 0 || config.EmbeddingDimension > 16000 {
+		return nil, fmt.Errorf("EMBEDDING_DIMENSION must be between 1 and 16000")
+	}
+	if config.EmbeddingDimension > 2000 && config.VectorIndexMethod != "none" {
+		return nil, fmt.Errorf("EMBEDDING_DIMENSION over 2000 cannot be indexed; set VECTOR_INDEX_METHOD=none")
+	}
+	switch config.VectorIndexMethod {
+	case "hnsw", "ivfflat", "none":
+	default:
+		return nil, fmt.Errorf("VECTOR_INDEX_METHOD must be hnsw, ivfflat or none")
+	}
+	if config.VectorHNSWM <= 0 || config.VectorHNSWEfConstruction <= 0 {
+		return nil, fmt.Errorf("VECTOR_HNSW_M and VECTOR_HNSW_EF_CONSTRUCTION must be positive")
+	}
 	if config.GeminiAPIKey == "" {
 		return nil, fmt.Errorf("GEMINI_API_KEY is required")
 	}
-	if config.SupabaseURL == "" {
-		return nil, fmt.Errorf("SUPABASE_URL is required")
-	}
-	if config.SupabaseKey == "" {
-		return nil, fmt.Errorf("SUPABASE_KEY is required")
+	// Supabase is only needed for storage when it is the storage driver
+	if config.StorageDriver == "supabase" {
+		if config.SupabaseURL == "" {
+			return nil, fmt.Errorf("SUPABASE_URL is required")
+		}
+		if config.SupabaseKey == "" {
+			return nil, fmt.Errorf("SUPABASE_KEY is required")
+		}
 	}
 	if config.SupabaseJWTSecret == "" {
 		return nil, fmt.Errorf("SUPABASE_JWT_SECRET is required")
@@ -84,3 +283,45 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(getEnv(key, strconv.Itoa(defaultValue)))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(getEnv(key, strconv.FormatFloat(defaultValue, 'f', -1, 64)), 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvLimits parses a list of name=value limits, e.g. "free=50,pro=200",
+// multiplying each value by unit. Malformed and non-positive entries are skipped.
+func getEnvLimits(key, defaultValue string, unit int64) map[string]int64 {
+	limits := make(map[string]int64)
+	for _, entry := range strings.Split(getEnv(key, defaultValue), ",") {
+		name, limit, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			continue
+		}
+		value, err := strconv.ParseInt(strings.TrimSpace(limit), 10, 64)
+		if err != nil || value <= 0 {
+			continue
+		}
+		limits[strings.TrimSpace(name)] = value * unit
+	}
+	return limits
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(getEnv(key, strconv.FormatBool(defaultValue)))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
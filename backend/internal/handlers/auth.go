@@ -26,6 +26,16 @@ type AuthHandler struct {
 
 // NewAuthHandler creates a new AuthHandler
 func NewAuthHandler(db *database.Client, cfg *config.Config) *AuthHandler {
+	// Without Supabase (e.g. local storage in development) only
+	// register and login are unavailable
+	if cfg.SupabaseURL == "" || cfg.SupabaseKey == "" {
+		utils.GetLogger().Warn("Supabase is not configured, register and login are disabled")
+		return &AuthHandler{
+			db:  db,
+			cfg: cfg,
+		}
+	}
+
 	supabaseClient, err := supabase.NewClient(cfg.SupabaseURL, cfg.SupabaseKey, &supabase.ClientOptions{})
 	if err != nil {
 		logger := utils.GetLogger()
@@ -206,6 +216,13 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
+	if h.supabase == nil {
+		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{
+			Error: "Authentication provider is not configured",
+		})
+		return
+	}
+
 	// Create user in Supabase
 	user, err := h.supabase.Auth.Signup(types.SignupRequest{
 		Email:    req.Email,
@@ -293,6 +310,13 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	if h.supabase == nil {
+		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{
+			Error: "Authentication provider is not configured",
+		})
+		return
+	}
+
 	// For testing, we'll create a simple login that creates a Supabase user if needed
 	// In production, you'd want proper password hashing and validation
 
@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/kinyichukwu/edu-pro-backend/internal/middleware"
+	"github.com/kinyichukwu/edu-pro-backend/internal/models"
+	"github.com/kinyichukwu/edu-pro-backend/internal/services/archive"
+	"github.com/kinyichukwu/edu-pro-backend/internal/services/storage"
+	"github.com/kinyichukwu/edu-pro-backend/internal/utils"
+	"go.uber.org/zap"
+)
+
+// errArchiveQuota stops archive expansion when the user runs out of quota
+var errArchiveQuota = errors.New("quota exceeded")
+
+// isArchive reports whether an uploaded file is a ZIP archive
+func isArchive(filename string) bool {
+	return strings.EqualFold(filepath.Ext(filename), ".zip")
+}
+
+// uploadArchive expands a ZIP upload into one document per supported file,
+// grouped under a new batch
+func (h *RAGHandler) uploadArchive(c *gin.Context, userID uuid.UUID, file *multipart.FileHeader, chatID string, maxSize int64) {
+	logger := utils.GetLogger()
+	ctx := c.Request.Context()
+
+	src, err := file.Open()
+	if err != nil {
+		logger.Error("Failed to open uploaded archive", zap.Error(err))
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to read archive",
+		})
+		return
+	}
+	defer src.Close()
+
+	// Storing every file may take longer than the server-wide write timeout
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Now().Add(15 * time.Minute))
+
+	limits := archive.Limits{
+		MaxFiles:     h.cfg.ArchiveMaxFiles,
+		MaxFileSize:  maxSize,
+		MaxTotalSize: h.cfg.ArchiveMaxTotalSize,
+		MaxRatio:     h.cfg.ArchiveMaxRatio,
+	}
+
+	response := &models.BatchUploadResponse{
+		Filename:  file.Filename,
+		Documents: []models.UploadResponse{},
+	}
+
+	// The batch is created with the first stored file, after the archive as
+	// a whole has passed validation
+	var quotaErr *models.APIError
+	duplicates := 0
+	skipped, err := archive.ExpandZip(src, file.Size, limits, h.storage.IsValidFileType, func(entry archive.Entry) error {
+		uploadResult, err := h.storage.UploadStream(entry.File, entry.Name, entry.Size, userID.String(), maxSize)
+		if errors.Is(err, storage.ErrContentMismatch) {
+			response.Skipped = append(response.Skipped, models.SkippedFile{Path: entry.Path, Reason: "content does not match file type"})
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to upload %s: %w", entry.Path, err)
+		}
+
+		if response.BatchID == "" {
+			batchID := uuid.New().String()
+			_, err := h.db.GetDB().ExecContext(ctx, `
+				INSERT INTO document_batches (id, user_id, filename)
+				VALUES ($1, $2, $3)
+			`, batchID, userID, file.Filename)
+			if err != nil {
+				return fmt.Errorf("failed to create batch: %w", err)
+			}
+			response.BatchID = batchID
+		}
+
+		// The quota is checked per file, as earlier files count against it
+		document, apiErr := h.createDocument(ctx, userID.String(), uploadResult, chatID, response.BatchID)
+		if apiErr != nil && apiErr.Code == http.StatusForbidden {
+			quotaErr = apiErr
+			return errArchiveQuota
+		}
+		if apiErr != nil {
+			return fmt.Errorf("failed to save %s: %s", entry.Path, apiErr.Message)
+		}
+		// A file the user already has belongs to an existing document, not
+		// this batch, so it is listed as skipped to keep the batch's
+		// documents the same here and in its status
+		if document.Duplicate {
+			response.Skipped = append(response.Skipped, models.SkippedFile{
+				Path:       entry.Path,
+				Reason:     "duplicate of an existing document",
+				DocumentID: document.DocumentID,
+			})
+			duplicates++
+			return nil
+		}
+		response.Documents = append(response.Documents, *document)
+		return nil
+	})
+
+	for _, s := range skipped {
+		response.Skipped = append(response.Skipped, models.SkippedFile{Path: s.Path, Reason: s.Reason})
+	}
+
+	if errors.Is(err, errArchiveQuota) {
+		if len(response.Documents) > 0 {
+			quotaErr.Details += fmt.Sprintf(" %d files were saved in batch %s.", len(response.Documents), response.BatchID)
+		}
+		utils.SendError(c, quotaErr)
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to expand archive",
+			zap.String("filename", file.Filename),
+			zap.String("batch_id", response.BatchID),
+			zap.Int("documents_created", len(response.Documents)),
+			zap.Error(err),
+		)
+		utils.SendError(c, archiveError(err, response))
+		return
+	}
+
+	if len(response.Documents) == 0 && duplicates == 0 {
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusBadRequest,
+			Message: "Archive contains no supported files",
+			Details: "Supported types are PDF, DOCX, PPTX, TXT and HTML",
+		})
+		return
+	}
+
+	// Skipped entries are kept with the batch so its status can report them
+	if len(response.Skipped) > 0 {
+		skippedJSON, _ := json.Marshal(response.Skipped)
+		if _, err := h.db.GetDB().ExecContext(ctx, "UPDATE document_batches SET skipped = $2 WHERE id = $1", response.BatchID, skippedJSON); err != nil {
+			logger.Warn("Failed to record skipped archive entries", zap.Error(err))
+		}
+	}
+
+	logger.Info("Archive expanded",
+		zap.String("batch_id", response.BatchID),
+		zap.String("filename", file.Filename),
+		zap.Int("documents", len(response.Documents)),
+		zap.Int("skipped", len(response.Skipped)),
+	)
+
+	utils.SendSuccess(c, response)
+}
+
+// archiveError maps an archive expansion failure to an API error
+func archiveError(err error, response *models.BatchUploadResponse) *models.APIError {
+	switch {
+	case errors.Is(err, archive.ErrTooManyFiles), errors.Is(err, archive.ErrTooLarge):
+		return &models.APIError{
+			Code:    http.StatusRequestEntityTooLarge,
+			Message: "Archive is too large",
+			Details: err.Error(),
+		}
+	case errors.Is(err, archive.ErrSuspicious), errors.Is(err, archive.ErrInvalidPath):
+		return &models.APIError{
+			Code:    http.StatusBadRequest,
+			Message: "Archive was rejected",
+			Details: err.Error(),
+		}
+	case response.BatchID == "":
+		return &models.APIError{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to read archive",
+			Details: err.Error(),
+		}
+	default:
+		// Files stored before the failure stay in the batch
+		return &models.APIError{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to process archive",
+			Details: fmt.Sprintf("%v; %d files were saved in batch %s", err, len(response.Documents), response.BatchID),
+		}
+	}
+}
+
+// GetBatch handles GET /api/documents/batches/:id
+func (h *RAGHandler) GetBatch(c *gin.Context) {
+	logger := utils.GetLogger()
+
+	userSupabaseID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusUnauthorized,
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	user, err := h.getOrCreateUser(c, userSupabaseID)
+	if err != nil {
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusNotFound,
+			Message: "User not found",
+		})
+		return
+	}
+
+	batchID := c.Param("id")
+	if _, err := uuid.Parse(batchID); err != nil {
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid batch ID",
+		})
+		return
+	}
+
+	response := &models.BatchStatusResponse{
+		BatchID:   batchID,
+		Documents: []models.DocumentResponse{},
+	}
+	var skipped []byte
+	err = h.db.GetDB().QueryRowContext(c.Request.Context(), `
+		SELECT filename, skipped, created_at
+		FROM document_batches
+		WHERE id = $1 AND user_id = $2
+	`, batchID, user.ID).Scan(&response.Filename, &skipped, &response.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.SendError(c, &models.APIError{
+				Code:    http.StatusNotFound,
+				Message: "Batch not found",
+			})
+			return
+		}
+		logger.Error("Failed to get batch", zap.Error(err))
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to get batch",
+		})
+		return
+	}
+	if len(skipped) > 0 {
+		json.Unmarshal(skipped, &response.Skipped)
+	}
+
+	rows, err := h.db.GetDB().QueryContext(c.Request.Context(), `
+		SELECT id, title, source_url, mime_type, processing_status, error, size, created_at
+		FROM documents
+		WHERE batch_id = $1
+		ORDER BY title
+	`, batchID)
+	if err != nil {
+		logger.Error("Failed to get batch documents", zap.Error(err))
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to get batch",
+		})
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var doc models.DocumentResponse
+		var sourceURL, errorMsg sql.NullString
+		var size sql.NullInt64
+		if err := rows.Scan(&doc.ID, &doc.Title, &sourceURL, &doc.MimeType, &doc.ProcessingStatus, &errorMsg, &size, &doc.CreatedAt); err != nil {
+			logger.Error("Failed to scan document", zap.Error(err))
+			continue
+		}
+		if sourceURL.Valid {
+			doc.SourceURL = &sourceURL.String
+		}
+		if errorMsg.Valid {
+			doc.Error = &errorMsg.String
+		}
+		if size.Valid {
+			doc.Size = &size.Int64
+		}
+		response.Documents = append(response.Documents, doc)
+
+		switch doc.ProcessingStatus {
+		case "queued":
+			response.Queued++
+		case "processing":
+			response.Processing++
+		case "completed":
+			response.Completed++
+		case "failed":
+			response.Failed++
+		}
+	}
+
+	response.Total = len(response.Documents)
+	if response.Total > 0 {
+		response.Progress = float64(response.Completed+response.Failed) / float64(response.Total)
+	}
+	switch {
+	case response.Queued+response.Processing > 0:
+		response.Status = "processing"
+	case response.Failed == 0:
+		response.Status = "completed"
+	case response.Completed == 0:
+		response.Status = "failed"
+	default:
+		response.Status = "partial"
+	}
+
+	utils.SendSuccess(c, response)
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/kinyichukwu/edu-pro-backend/internal/middleware"
+	"github.com/kinyichukwu/edu-pro-backend/internal/models"
+	"github.com/kinyichukwu/edu-pro-backend/internal/services/progress"
+	"github.com/kinyichukwu/edu-pro-backend/internal/utils"
+	"go.uber.org/zap"
+)
+
+// eventsKeepAlive is how often a comment is sent on an idle stream so proxies
+// don't close it
+const eventsKeepAlive = 15 * time.Second
+
+// DocumentEvents handles GET /api/documents/:id/events. It streams the
+// document's processing stages as Server-Sent Events, starting with its
+// current status, and ends the stream once processing completes or fails.
+func (h *RAGHandler) DocumentEvents(c *gin.Context) {
+	logger := utils.GetLogger()
+
+	userSupabaseID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusUnauthorized,
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	user, err := h.getOrCreateUser(c, userSupabaseID)
+	if err != nil {
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusNotFound,
+			Message: "User not found",
+		})
+		return
+	}
+
+	documentID := c.Param("id")
+	if _, err := uuid.Parse(documentID); err != nil {
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid document ID",
+		})
+		return
+	}
+
+	// Subscribe before reading the status so no transition is missed between
+	// the two
+	events, unsubscribe := h.progress.Subscribe(documentID)
+	defer unsubscribe()
+
+	var status string
+	var errorMsg sql.NullString
+	err = h.db.GetDB().QueryRowContext(c.Request.Context(), `
+		SELECT processing_status, error
+		FROM documents
+		WHERE id = $1 AND user_id = $2
+	`, documentID, user.ID).Scan(&status, &errorMsg)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.SendError(c, &models.APIError{
+				Code:    http.StatusNotFound,
+				Message: "Document not found",
+			})
+			return
+		}
+		logger.Error("Failed to get document status", zap.Error(err))
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to get document status",
+		})
+		return
+	}
+
+	// The stream outlives the server-wide write timeout
+	controller := http.NewResponseController(c.Writer)
+	controller.SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	snapshot := progress.Event{
+		DocumentID: documentID,
+		Stage:      progress.Stage(status),
+		Error:      errorMsg.String,
+		Time:       time.Now().UTC(),
+	}
+	sendEvent(c, snapshot)
+	if snapshot.Stage.Done() {
+		return
+	}
+
+	keepAlive := time.NewTicker(eventsKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				// The server is shutting down
+				return
+			}
+			sendEvent(c, event)
+			if event.Stage.Done() {
+				return
+			}
+		case <-keepAlive.C:
+			fmt.Fprint(c.Writer, ": keep-alive\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
+
+// sendEvent writes an event named after its stage and flushes it
+func sendEvent(c *gin.Context, event progress.Event) {
+	c.SSEvent(string(event.Stage), event)
+	c.Writer.Flush()
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kinyichukwu/edu-pro-backend/internal/middleware"
+	"github.com/kinyichukwu/edu-pro-backend/internal/models"
+	"github.com/kinyichukwu/edu-pro-backend/internal/services/fetch"
+	"github.com/kinyichukwu/edu-pro-backend/internal/services/storage"
+	"github.com/kinyichukwu/edu-pro-backend/internal/utils"
+	"go.uber.org/zap"
+)
+
+// ImportDocument handles POST /api/documents/import. It downloads a PDF,
+// Office document or web page and queues it like an uploaded file, keeping
+// the original URL as the document's source.
+func (h *RAGHandler) ImportDocument(c *gin.Context) {
+	logger := utils.GetLogger()
+
+	userSupabaseID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusUnauthorized,
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	user, err := h.db.GetUserBySupabaseID(userSupabaseID)
+	if err != nil {
+		logger.Error("Failed to get user", zap.Error(err))
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusNotFound,
+			Message: "User not found",
+		})
+		return
+	}
+
+	var req models.ImportDocumentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusBadRequest,
+			Message: "Validation failed",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	maxSize, err := h.uploadLimit(user.ID)
+	if err != nil {
+		logger.Error("Failed to get upload limit", zap.Error(err))
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to import document",
+		})
+		return
+	}
+
+	// Downloading and storing may take longer than the server-wide write timeout
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Now().Add(h.cfg.ImportTimeout + 2*time.Minute))
+
+	result, err := h.fetcher.Fetch(c.Request.Context(), req.URL, maxSize)
+	if err != nil {
+		logger.Warn("Failed to fetch document URL", zap.String("url", req.URL), zap.Error(err))
+		utils.SendError(c, importError(err, maxSize))
+		return
+	}
+	defer result.Close()
+
+	uploadResult, err := h.storage.UploadStream(result.File, result.Filename, result.Size, user.ID.String(), maxSize)
+	if err != nil {
+		logger.Error("Failed to store imported document", zap.Error(err))
+		if errors.Is(err, storage.ErrContentMismatch) || errors.Is(err, storage.ErrUnsupportedType) {
+			utils.SendError(c, uploadError(err))
+			return
+		}
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to import document",
+			Details: err.Error(),
+		})
+		return
+	}
+	uploadResult.PublicURL = req.URL
+
+	response, apiErr := h.createDocument(c.Request.Context(), user.ID.String(), uploadResult, req.ChatID, "")
+	if apiErr != nil {
+		utils.SendError(c, apiErr)
+		return
+	}
+
+	logger.Info("Document imported",
+		zap.String("document_id", response.DocumentID),
+		zap.String("url", req.URL),
+	)
+
+	utils.SendSuccess(c, response)
+}
+
+// importError maps a fetch failure to an API error
+func importError(err error, maxSize int64) *models.APIError {
+	switch {
+	case errors.Is(err, fetch.ErrInvalidURL):
+		return &models.APIError{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid URL",
+			Details: "Only http and https URLs can be imported",
+		}
+	case errors.Is(err, fetch.ErrBlockedHost):
+		return &models.APIError{
+			Code:    http.StatusBadRequest,
+			Message: "URL is not allowed",
+			Details: "The URL points to a private or internal address",
+		}
+	case errors.Is(err, fetch.ErrTooLarge):
+		return &models.APIError{
+			Code:    http.StatusRequestEntityTooLarge,
+			Message: "File is too large",
+			Details: fmt.Sprintf("Your plan allows files up to %dMB", maxSize/(1024*1024)),
+		}
+	case errors.Is(err, fetch.ErrUnsupportedType):
+		return &models.APIError{
+			Code:    http.StatusUnsupportedMediaType,
+			Message: "Unsupported content type",
+			Details: "Supported types are PDF, DOCX, PPTX, plain text and HTML pages",
+		}
+	default:
+		return &models.APIError{
+			Code:    http.StatusBadGateway,
+			Message: "Failed to download URL",
+			Details: err.Error(),
+		}
+	}
+}
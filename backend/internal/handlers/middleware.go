@@ -80,6 +80,11 @@ func LoggingMiddleware() gin.HandlerFunc {
 		requestID, _ := c.Get("request_id")
 
 		if raw != "" {
+			// Event streams may carry the JWT in the query string
+			if query := c.Request.URL.Query(); query.Has("access_token") {
+				query.Set("access_token", "REDACTED")
+				raw = query.Encode()
+			}
 			path = path + "?" + raw
 		}
 
@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/kinyichukwu/edu-pro-backend/internal/middleware"
+	"github.com/kinyichukwu/edu-pro-backend/internal/models"
+	"github.com/kinyichukwu/edu-pro-backend/internal/services/database"
+	"github.com/kinyichukwu/edu-pro-backend/internal/services/jobs"
+	"github.com/kinyichukwu/edu-pro-backend/internal/utils"
+	"go.uber.org/zap"
+)
+
+// GetUsage handles GET /api/user/usage
+func (h *RAGHandler) GetUsage(c *gin.Context) {
+	logger := utils.GetLogger()
+
+	userSupabaseID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusUnauthorized,
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	user, err := h.getOrCreateUser(c, userSupabaseID)
+	if err != nil {
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusNotFound,
+			Message: "User not found",
+		})
+		return
+	}
+
+	usage, err := h.userUsage(c.Request.Context(), user.ID)
+	if err != nil {
+		logger.Error("Failed to get usage", zap.Error(err))
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to get usage",
+		})
+		return
+	}
+
+	utils.SendSuccess(c, usage)
+}
+
+// userUsage returns a user's consumption and the quotas of their plan and role
+func (h *RAGHandler) userUsage(ctx context.Context, userID uuid.UUID) (*models.UsageResponse, error) {
+	plan, err := h.db.GetUserPlan(userID)
+	if err != nil {
+		return nil, err
+	}
+	role, err := h.db.GetUserRole(userID)
+	if err != nil {
+		return nil, err
+	}
+	usage, err := h.pgx.GetUserUsage(ctx, userID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	return &models.UsageResponse{
+		Plan:            plan,
+		Role:            role,
+		Documents:       usageMetric(usage.Documents, h.cfg.QuotaDocuments, plan, role),
+		StorageBytes:    usageMetric(usage.StorageBytes, h.cfg.QuotaStorage, plan, role),
+		Chunks:          usageMetric(usage.Chunks, h.cfg.QuotaChunks, plan, role),
+		EmbeddingTokens: usageMetric(usage.EmbeddingTokens, h.cfg.QuotaMonthlyTokens, plan, role),
+		PeriodResetsAt:  time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC),
+	}, nil
+}
+
+// usageMetric pairs a usage figure with the limit that applies to the user
+func usageMetric(used int64, limits map[string]int64, plan, role string) models.UsageMetric {
+	metric := models.UsageMetric{Used: used}
+	limit, ok := limits[plan]
+	if !ok {
+		limit, ok = limits["free"]
+	}
+	if roleLimit, found := limits[role]; role != "" && found && (!ok || roleLimit > limit) {
+		limit, ok = roleLimit, true
+	}
+	if ok {
+		metric.Limit = &limit
+	}
+	return metric
+}
+
+// checkQuota returns the user's usage and limits, or an error if the chunk
+// or monthly token quota is already used up. Chunk and token use can't be
+// predicted before processing, so here they only block new documents once
+// reached; ingestion jobs enforce them exactly as chunks are embedded and
+// stored. The document and storage quotas are enforced when the document is
+// inserted, see insertDocumentError.
+func (h *RAGHandler) checkQuota(ctx context.Context, userID uuid.UUID) (*models.UsageResponse, *models.APIError) {
+	usage, err := h.userUsage(ctx, userID)
+	if err != nil {
+		utils.GetLogger().Error("Failed to check quota", zap.Error(err))
+		return nil, &models.APIError{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to check quota",
+		}
+	}
+
+	if limit := usage.Chunks.Limit; limit != nil && usage.Chunks.Used >= *limit {
+		return nil, &models.APIError{
+			Code:    http.StatusForbidden,
+			Message: "Chunk quota exceeded",
+			Details: fmt.Sprintf("Your plan allows %d indexed chunks. Delete documents to add more.", *limit),
+		}
+	}
+	if apiErr := embeddingQuotaError(usage); apiErr != nil {
+		return nil, apiErr
+	}
+	return usage, nil
+}
+
+// insertDocumentError maps a failure to insert a document to an API error,
+// explaining the document and storage quotas
+func insertDocumentError(err error, usage *models.UsageResponse) *models.APIError {
+	switch {
+	case errors.Is(err, database.ErrDocumentQuotaExceeded):
+		return &models.APIError{
+			Code:    http.StatusForbidden,
+			Message: "Document quota exceeded",
+			Details: fmt.Sprintf("Your plan allows %d documents. Delete documents to add more.", *usage.Documents.Limit),
+		}
+	case errors.Is(err, database.ErrStorageQuotaExceeded):
+		return &models.APIError{
+			Code:    http.StatusForbidden,
+			Message: "Storage quota exceeded",
+			Details: fmt.Sprintf("Your plan allows %dMB of storage", *usage.StorageBytes.Limit/(1024*1024)),
+		}
+	}
+	utils.GetLogger().Error("Failed to insert document", zap.Error(err))
+	return &models.APIError{
+		Code:    http.StatusInternalServerError,
+		Message: "Failed to save document",
+	}
+}
+
+// checkEmbeddingQuota returns an error if the user has used up this month's
+// embedding tokens. It guards work that embeds existing chunks again, which
+// adds no documents, storage or chunks.
+func (h *RAGHandler) checkEmbeddingQuota(ctx context.Context, userID uuid.UUID) *models.APIError {
+	usage, err := h.userUsage(ctx, userID)
+	if err != nil {
+		utils.GetLogger().Error("Failed to check quota", zap.Error(err))
+		return &models.APIError{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to check quota",
+		}
+	}
+	return embeddingQuotaError(usage)
+}
+
+// embeddingQuotaError returns an error if the monthly embedding tokens are
+// used up
+func embeddingQuotaError(usage *models.UsageResponse) *models.APIError {
+	if limit := usage.EmbeddingTokens.Limit; limit != nil && usage.EmbeddingTokens.Used >= *limit {
+		return &models.APIError{
+			Code:    http.StatusForbidden,
+			Message: "Monthly processing quota exceeded",
+			Details: fmt.Sprintf("Your plan allows %d embedding tokens per month. The quota resets on %s.", *limit, usage.PeriodResetsAt.Format("2 January 2006")),
+		}
+	}
+	return nil
+}
+
+// quotaLimits are the chunk and monthly embedding token limits that apply to
+// a user; nil is unlimited
+type quotaLimits struct {
+	Chunks          *int64
+	EmbeddingTokens *int64
+}
+
+// documentQuotaLimits returns the limits of a document's owner, which
+// ingestion jobs enforce as they store chunks and spend embedding tokens
+func (h *RAGHandler) documentQuotaLimits(ctx context.Context, documentID string) (*quotaLimits, error) {
+	var userID uuid.UUID
+	err := h.db.GetDB().QueryRowContext(ctx, "SELECT user_id FROM documents WHERE id = $1", documentID).Scan(&userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up document owner: %w", err)
+	}
+	plan, err := h.db.GetUserPlan(userID)
+	if err != nil {
+		return nil, err
+	}
+	role, err := h.db.GetUserRole(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &quotaLimits{
+		Chunks:          usageMetric(0, h.cfg.QuotaChunks, plan, role).Limit,
+		EmbeddingTokens: usageMetric(0, h.cfg.QuotaMonthlyTokens, plan, role).Limit,
+	}, nil
+}
+
+// reserveEmbeddingTokens counts the tokens of texts about to be embedded
+// against the document owner's monthly quota and returns how many were
+// reserved. Going over the quota fails the job permanently, as retrying
+// within the month would fail again.
+func (h *RAGHandler) reserveEmbeddingTokens(ctx context.Context, documentID string, texts []string, limits *quotaLimits) (int, error) {
+	tokens := 0
+	for _, text := range texts {
+		tokens += h.chunker.CountTokens(text)
+	}
+
+	err := h.pgx.ReserveEmbeddingTokens(ctx, documentID, tokens, limits.EmbeddingTokens)
+	if errors.Is(err, database.ErrEmbeddingQuotaExceeded) {
+		return 0, jobs.Permanent(fmt.Errorf("%w: your plan allows %d embedding tokens per month and this document needs %d", err, *limits.EmbeddingTokens, tokens))
+	}
+	if err != nil {
+		return 0, err
+	}
+	return tokens, nil
+}
+
+// releaseEmbeddingTokens returns tokens reserved for texts that were not
+// embedded, so a retry is not charged twice
+func (h *RAGHandler) releaseEmbeddingTokens(ctx context.Context, documentID string, tokens int) {
+	if err := h.pgx.ReleaseEmbeddingTokens(context.WithoutCancel(ctx), documentID, tokens); err != nil {
+		utils.GetLogger().Warn("Failed to release embedding tokens",
+			zap.String("document_id", documentID),
+			zap.Error(err),
+		)
+	}
+}
+
+// chunkQuotaError turns a chunk quota failure into a permanent job error
+// explaining the limit; other errors are returned as they are
+func chunkQuotaError(err error, limits *quotaLimits) error {
+	if errors.Is(err, database.ErrChunkQuotaExceeded) {
+		return jobs.Permanent(fmt.Errorf("%w: your plan allows %d indexed chunks", err, *limits.Chunks))
+	}
+	return err
+}
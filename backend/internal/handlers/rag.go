@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -19,7 +20,12 @@ import (
 	"github.com/kinyichukwu/edu-pro-backend/internal/services/database"
 	"github.com/kinyichukwu/edu-pro-backend/internal/services/embeddings"
 	"github.com/kinyichukwu/edu-pro-backend/internal/services/extract"
+	"github.com/kinyichukwu/edu-pro-backend/internal/services/fetch"
+	"github.com/kinyichukwu/edu-pro-backend/internal/services/jobs"
+	"github.com/kinyichukwu/edu-pro-backend/internal/services/progress"
+	"github.com/kinyichukwu/edu-pro-backend/internal/services/rerank"
 	"github.com/kinyichukwu/edu-pro-backend/internal/services/storage"
+	"github.com/kinyichukwu/edu-pro-backend/internal/services/uploads"
 	"github.com/kinyichukwu/edu-pro-backend/internal/utils"
 	"go.uber.org/zap"
 )
@@ -30,27 +36,76 @@ type RAGHandler struct {
 	pgx        *database.PgxClient
 	cfg        *config.Config
 	storage    *storage.Client
-	embeddings *embeddings.Client
-	chunker    *chunker.Client
-	extractor  *extract.Client
-	aiClient   ai.Service
+	embeddings embeddings.Embedder
+	// Model version of the embeddings client; only chunks embedded with it are searched
+	embeddingVersion database.EmbeddingVersion
+	chunker          *chunker.Client
+	extractor        *extract.Client
+	jobs             *jobs.Client
+	uploads          *uploads.Manager
+	fetcher          *fetch.Client
+	progress         *progress.Broker
+	reranker         rerank.Reranker
+	aiClient         ai.Service
 }
 
+// askContextChunks is how many chunks an answer's prompt is built from
+const askContextChunks = 8
+
 // NewRAGHandler creates a new RAG handler
 func NewRAGHandler(
 	db *database.Client,
 	pgx *database.PgxClient,
 	cfg *config.Config,
 	aiClient ai.Service,
+	progressBroker *progress.Broker,
+	jobsClient *jobs.Client,
 ) (*RAGHandler, error) {
 	storageClient, err := storage.NewClient(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create storage client: %w", err)
 	}
 
-	embeddingsClient := embeddings.NewClient(cfg.GeminiAPIKey)
+	embeddingsClient, err := embeddings.NewEmbedder(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embeddings client: %w", err)
+	}
 	chunkerClient := chunker.NewClient()
+	if cfg.TokenizerModelPath != "" {
+		tokenizer, err := chunker.NewSentencePieceTokenizer(cfg.TokenizerModelPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create tokenizer: %w", err)
+		}
+		chunkerClient.SetTokenizer(tokenizer)
+	}
+	for fileType, strategy := range cfg.ChunkStrategies {
+		if err := chunkerClient.SetStrategyForType(fileType, strategy); err != nil {
+			return nil, fmt.Errorf("invalid CHUNK_STRATEGIES: %w", err)
+		}
+	}
 	extractorClient := extract.NewClient()
+	extractorClient.SetLimits(extract.Limits{
+		MaxPDFPages:   cfg.PDFMaxPages,
+		MaxPDFObjects: cfg.PDFMaxObjects,
+		MaxPDFRatio:   cfg.PDFMaxRatio,
+		Timeout:       cfg.ExtractTimeout,
+		MemoryLimit:   cfg.ExtractMemoryLimit,
+		MaxTextBytes:  cfg.ExtractMaxText,
+
+		MaxZipPartSize:  cfg.ArchiveMaxTotalSize,
+		MaxZipPartRatio: cfg.ArchiveMaxRatio,
+	})
+	if cfg.OCREnabled {
+		extractorClient.SetOCR(extract.NewTesseractOCR(cfg.OCRLanguage))
+	}
+	uploadManager, err := uploads.NewManager(pgx.GetPool(), cfg.UploadStagingDir, cfg.UploadSessionTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload manager: %w", err)
+	}
+	reranker, err := rerank.NewReranker(cfg.RerankProvider, aiClient)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RERANK_PROVIDER: %w", err)
+	}
 
 	return &RAGHandler{
 		db:         db,
@@ -58,9 +113,19 @@ func NewRAGHandler(
 		cfg:        cfg,
 		storage:    storageClient,
 		embeddings: embeddingsClient,
-		chunker:    chunkerClient,
-		extractor:  extractorClient,
-		aiClient:   aiClient,
+		embeddingVersion: database.EmbeddingVersion{
+			Model:     embeddings.ModelID(embeddingsClient),
+			Version:   cfg.EmbeddingModelVersion,
+			Dimension: cfg.EmbeddingDimension,
+		},
+		chunker:   chunkerClient,
+		extractor: extractorClient,
+		jobs:      jobsClient,
+		uploads:   uploadManager,
+		fetcher:   fetch.NewClient(cfg.ImportTimeout, cfg.ImportAllowPrivateNetwork),
+		progress:  progressBroker,
+		reranker:  reranker,
+		aiClient:  aiClient,
 	}, nil
 }
 
@@ -103,49 +168,170 @@ func (h *RAGHandler) Upload(c *gin.Context) {
 	// Get optional chat_id
 	chatID := c.PostForm("chat_id")
 
-	// Upload file to storage
-	uploadResult, err := h.storage.UploadFile(file, user.ID.String())
+	// Check the file against the user's plan limit
+	maxSize, err := h.uploadLimit(user.ID)
 	if err != nil {
-		logger.Error("Failed to upload file", zap.Error(err))
+		logger.Error("Failed to get upload limit", zap.Error(err))
 		utils.SendError(c, &models.APIError{
 			Code:    http.StatusInternalServerError,
 			Message: "Failed to upload file",
-			Details: err.Error(),
+		})
+		return
+	}
+	if file.Size > maxSize {
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusRequestEntityTooLarge,
+			Message: "File is too large",
+			Details: fmt.Sprintf("Your plan allows files up to %dMB", maxSize/(1024*1024)),
 		})
 		return
 	}
 
-	// Insert document record
-	documentID := uuid.New()
-	_, err = h.db.GetDB().Exec(`
-		INSERT INTO documents (id, user_id, title, source_url, mime_type, processing_status)
-		VALUES ($1, $2, $3, $4, $5, 'queued')
-	`, documentID, user.ID, uploadResult.Filename, uploadResult.PublicURL, uploadResult.MimeType)
+	// Archives are expanded into one document per supported file
+	if isArchive(file.Filename) {
+		h.uploadArchive(c, user.ID, file, chatID, maxSize)
+		return
+	}
+
+	// Upload file to storage
+	uploadResult, err := h.storage.UploadFile(file, user.ID.String(), maxSize)
 	if err != nil {
-		logger.Error("Failed to insert document", zap.Error(err))
-		utils.SendError(c, &models.APIError{
+		logger.Error("Failed to upload file", zap.Error(err))
+		utils.SendError(c, uploadError(err))
+		return
+	}
+
+	response, apiErr := h.createDocument(c.Request.Context(), user.ID.String(), uploadResult, chatID, "")
+	if apiErr != nil {
+		utils.SendError(c, apiErr)
+		return
+	}
+
+	utils.SendSuccess(c, response)
+}
+
+// createDocument records a stored file as a document and queues it for
+// processing. A file the user already uploaded returns the existing document
+// and does not count against the quota; otherwise the quota is checked here,
+// once the content is known, and the stored file is deleted if it fails or
+// the document can't be saved.
+// batchID groups files expanded from one archive and may be empty.
+func (h *RAGHandler) createDocument(ctx context.Context, userID string, uploadResult *storage.UploadResult, chatID, batchID string) (*models.UploadResponse, *models.APIError) {
+	logger := utils.GetLogger()
+
+	// A re-upload of a file the user already has returns the existing document
+	existing, err := h.findUserDocumentByChecksum(userID, uploadResult.Checksum)
+	if err != nil {
+		logger.Warn("Failed to check for duplicate upload", zap.Error(err))
+	}
+	if existing != nil {
+		logger.Info("Duplicate upload detected",
+			zap.String("document_id", existing.DocumentID),
+			zap.String("checksum", uploadResult.Checksum),
+		)
+		if err := h.storage.DeleteFile(uploadResult.StoragePath); err != nil {
+			logger.Warn("Failed to delete duplicate upload", zap.Error(err))
+		}
+		if chatID != "" {
+			uploadResult.Filename = existing.Title
+			uploadResult.PublicURL = existing.SourceURL
+			h.addFileMessageToChat(chatID, userID, uploadResult, existing.DocumentID)
+		}
+		return existing, nil
+	}
+
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		logger.Error("Invalid user ID", zap.Error(err))
+		return nil, &models.APIError{
 			Code:    http.StatusInternalServerError,
 			Message: "Failed to save document",
-		})
-		return
+		}
 	}
 
-	// Process document in background
-	go h.processDocument(documentID.String(), uploadResult, user.ID.String())
+	// The document and storage quotas are checked as the document is
+	// inserted, so concurrent uploads can't together exceed them
+	documentID := uuid.New()
+	usage, apiErr := h.checkQuota(ctx, userUUID)
+	if apiErr == nil {
+		err = h.pgx.InsertDocument(ctx, database.DocumentInsert{
+			ID:          documentID.String(),
+			UserID:      userID,
+			Title:       uploadResult.Filename,
+			SourceURL:   uploadResult.PublicURL,
+			StoragePath: uploadResult.StoragePath,
+			MimeType:    uploadResult.MimeType,
+			Size:        uploadResult.Size,
+			Checksum:    uploadResult.Checksum,
+			BatchID:     batchID,
+		}, usage.Documents.Limit, usage.StorageBytes.Limit)
+		if err != nil {
+			apiErr = insertDocumentError(err, usage)
+		}
+	}
+	if apiErr != nil {
+		if err := h.storage.DeleteFile(uploadResult.StoragePath); err != nil {
+			logger.Warn("Failed to delete upload that was not saved", zap.Error(err))
+		}
+		return nil, apiErr
+	}
+
+	// Queue document for background processing
+	if _, err := h.jobs.Enqueue(ctx, jobs.KindProcessDocument, documentID.String(), uploadResult); err != nil {
+		logger.Error("Failed to queue document for processing", zap.Error(err))
+		h.updateDocumentError(documentID.String(), fmt.Sprintf("Failed to queue document: %v", err))
+		return nil, &models.APIError{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to queue document for processing",
+		}
+	}
 
 	// If chat_id is provided, add file message to chat
 	if chatID != "" {
-		h.addFileMessageToChat(chatID, user.ID.String(), uploadResult, documentID.String())
+		h.addFileMessageToChat(chatID, userID, uploadResult, documentID.String())
 	}
 
-	response := &models.UploadResponse{
+	return &models.UploadResponse{
 		DocumentID: documentID.String(),
 		Title:      uploadResult.Filename,
 		SourceURL:  uploadResult.PublicURL,
 		MimeType:   uploadResult.MimeType,
+	}, nil
+}
+
+// uploadError maps a failure to store an uploaded file to an API error
+func uploadError(err error) *models.APIError {
+	switch {
+	case errors.Is(err, storage.ErrUnsupportedType):
+		return &models.APIError{
+			Code:    http.StatusUnsupportedMediaType,
+			Message: "Unsupported file type",
+			Details: err.Error(),
+		}
+	case errors.Is(err, storage.ErrContentMismatch):
+		return &models.APIError{
+			Code:    http.StatusUnsupportedMediaType,
+			Message: "File content does not match its type",
+			Details: err.Error(),
+		}
+	}
+	return &models.APIError{
+		Code:    http.StatusInternalServerError,
+		Message: "Failed to upload file",
+		Details: err.Error(),
 	}
+}
 
-	utils.SendSuccess(c, response)
+// uploadLimit returns the maximum upload size in bytes for the user's plan
+func (h *RAGHandler) uploadLimit(userID uuid.UUID) (int64, error) {
+	plan, err := h.db.GetUserPlan(userID)
+	if err != nil {
+		return 0, err
+	}
+	if limit, ok := h.cfg.UploadPlanLimits[plan]; ok {
+		return limit, nil
+	}
+	return h.cfg.UploadPlanLimits["free"], nil
 }
 
 // GetDocuments handles GET /api/documents
@@ -585,34 +771,63 @@ func (h *RAGHandler) Ask(c *gin.Context) {
 
 	ctx := context.Background()
 
-	// Generate embedding for query
-	queryEmbedding, err := h.embeddings.GenerateEmbedding(req.Query)
-	if err != nil {
-		logger.Error("Failed to generate query embedding", zap.Error(err))
-		utils.SendError(c, &models.APIError{
-			Code:    http.StatusInternalServerError,
-			Message: "Failed to process query",
-		})
-		return
+	keywordWeight := h.cfg.SearchKeywordWeight
+	if req.KeywordWeight != nil {
+		keywordWeight = *req.KeywordWeight
 	}
 
-	// Search similar chunks (with optional document filtering)
-	var chunks []database.ChunkResult
-	if len(req.DocumentIDs) > 0 {
-		// Filter by specific documents
-		chunks, err = h.pgx.SearchSimilarChunksInDocuments(ctx, queryEmbedding, user.ID.String(), req.DocumentIDs, 8)
-	} else {
-		// Search all user documents
-		chunks, err = h.pgx.SearchSimilarChunks(ctx, queryEmbedding, user.ID.String(), 8)
+	// Rewrite follow-ups into a standalone query using the conversation, so
+	// "explain the second one more" searches for what "the second one" was
+	searchQueries := h.searchQueries(ctx, user.ID.String(), req.ChatID, req.Query)
+	searchQuery := searchQueries[0]
+
+	// Generate embeddings for the queries, unless the search is keyword only
+	queryEmbeddings := make([][]float32, len(searchQueries))
+	if keywordWeight < 1 {
+		queryEmbeddings, err = h.embeddings.GenerateEmbeddings(ctx, searchQueries, nil)
+		if err != nil {
+			logger.Error("Failed to generate query embedding", zap.Error(err))
+			utils.SendError(c, &models.APIError{
+				Code:    http.StatusInternalServerError,
+				Message: "Failed to process query",
+			})
+			return
+		}
 	}
-	if err != nil {
-		logger.Error("Failed to search chunks", zap.Error(err))
-		utils.SendError(c, &models.APIError{
-			Code:    http.StatusInternalServerError,
-			Message: "Failed to search documents",
+
+	// Reranking and MMR pick the context from a larger candidate pool
+	limit := askContextChunks
+	if (h.reranker.Name() != rerank.ProviderNone || h.cfg.SearchMMRLambda < 1) && h.cfg.RerankCandidates > limit {
+		limit = h.cfg.RerankCandidates
+	}
+
+	// Search chunks by vector similarity and keywords (with optional document
+	// filtering) for each query, and merge the results
+	searches := make([][]database.ChunkResult, 0, len(searchQueries))
+	for i, query := range searchQueries {
+		results, err := h.pgx.HybridSearchChunks(ctx, database.HybridSearch{
+			Query:         query,
+			Embedding:     queryEmbeddings[i],
+			Version:       h.embeddingVersion,
+			UserID:        user.ID.String(),
+			DocumentIDs:   req.DocumentIDs,
+			Limit:         limit,
+			KeywordWeight: keywordWeight,
+			MaxDistance:   h.cfg.SearchMaxDistance,
 		})
-		return
+		if err != nil {
+			logger.Error("Failed to search chunks", zap.Error(err))
+			utils.SendError(c, &models.APIError{
+				Code:    http.StatusInternalServerError,
+				Message: "Failed to search documents",
+			})
+			return
+		}
+		searches = append(searches, results)
 	}
+	chunks := database.MergeSearches(searches, limit)
+
+	chunks = h.selectContextChunks(ctx, searchQuery, chunks, askContextChunks)
 
 	// Convert chunks to AI prompt format
 	var aiChunks []ai.DocumentChunk
@@ -623,29 +838,25 @@ func (h *RAGHandler) Ask(c *gin.Context) {
 		if chunk.SourceURL != nil {
 			sourceURL = *chunk.SourceURL
 		}
-		
+
+		// Create citation
+		citation := h.citationFromChunk(chunk)
+		citations = append(citations, citation)
+
 		aiChunks = append(aiChunks, ai.DocumentChunk{
 			DocumentID:    chunk.DocumentID,
 			DocumentTitle: chunk.DocumentTitle,
 			SourceURL:     sourceURL,
 			Ordinal:       chunk.Ordinal,
+			Location:      citation.Location,
 			Content:       chunk.Content,
 		})
-
-		// Create citation
-		citation := models.Citation{
-			DocumentID:    chunk.DocumentID,
-			DocumentTitle: chunk.DocumentTitle,
-			Ordinal:       chunk.Ordinal,
-			Snippet:       h.truncateText(chunk.Content, 200),
-			SourceURL:     chunk.SourceURL,
-		}
-		citations = append(citations, citation)
 	}
 
-	// Build context and prompt using AI service
+	// Build context and prompt using AI service; the standalone query carries
+	// what a follow-up refers to
 	context := ai.BuildRAGContext(aiChunks)
-	prompt := ai.RAGPrompt(req.Query, context)
+	prompt := ai.RAGPrompt(searchQuery, context)
 
 	// Generate answer using AI
 	aiReq := &ai.GeminiRequest{
@@ -665,11 +876,20 @@ func (h *RAGHandler) Ask(c *gin.Context) {
 
 	answer := explanation.Explanation
 
-	// Save user question
+	// Save user question, with the queries it was searched by when rewritten
+	var questionMetadata *string
+	if len(searchQueries) > 1 || searchQuery != req.Query {
+		metadataJSON, _ := json.Marshal(map[string]interface{}{
+			"search_query":   searchQuery,
+			"search_queries": searchQueries,
+		})
+		metadata := string(metadataJSON)
+		questionMetadata = &metadata
+	}
 	_, err = h.db.GetDB().Exec(`
-		INSERT INTO chat_messages (id, chat_id, role, content)
-		VALUES ($1, $2, 'user', $3)
-	`, uuid.New(), chatID, req.Query)
+		INSERT INTO chat_messages (id, chat_id, role, content, metadata)
+		VALUES ($1, $2, 'user', $3, $4)
+	`, uuid.New(), chatID, req.Query, questionMetadata)
 	if err != nil {
 		logger.Error("Failed to save user message", zap.Error(err))
 	}
@@ -688,10 +908,146 @@ func (h *RAGHandler) Ask(c *gin.Context) {
 		Answer:    answer,
 		Citations: citations,
 	}
+	if searchQuery != req.Query {
+		response.SearchQuery = searchQuery
+	}
 
 	utils.SendSuccess(c, response)
 }
 
+// searchQueries returns the queries to search for a question: a standalone
+// rewrite of it given the chat's recent messages, followed by any
+// paraphrases. Without history or paraphrases, or if rewriting fails, the
+// question is searched as asked.
+func (h *RAGHandler) searchQueries(ctx context.Context, userID, chatID, query string) []string {
+	logger := utils.GetLogger()
+
+	if !h.cfg.QueryRewriteEnabled {
+		return []string{query}
+	}
+
+	var history []ai.ChatTurn
+	if chatID != "" {
+		var err error
+		history, err = h.recentChatTurns(ctx, userID, chatID, h.cfg.QueryRewriteHistory)
+		if err != nil {
+			logger.Warn("Failed to load chat history for query rewrite", zap.Error(err))
+		}
+	}
+	if len(history) == 0 && h.cfg.QueryParaphrases == 0 {
+		return []string{query}
+	}
+
+	queries, err := h.aiClient.RewriteQuery(history, query, h.cfg.QueryParaphrases)
+	if err != nil {
+		logger.Warn("Failed to rewrite query, searching it as asked", zap.Error(err))
+		return []string{query}
+	}
+	return queries
+}
+
+// recentChatTurns returns up to limit of the latest user and assistant
+// messages in one of the user's chats, oldest first
+func (h *RAGHandler) recentChatTurns(ctx context.Context, userID, chatID string, limit int) ([]ai.ChatTurn, error) {
+	rows, err := h.db.GetDB().QueryContext(ctx, `
+		SELECT m.role, m.content
+		FROM chat_messages m
+		JOIN chats c ON c.id = m.chat_id
+		WHERE m.chat_id = $1 AND c.user_id = $2 AND m.role IN ('user', 'assistant')
+		ORDER BY m.created_at DESC
+		LIMIT $3
+	`, chatID, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chat messages: %w", err)
+	}
+	defer rows.Close()
+
+	var turns []ai.ChatTurn
+	for rows.Next() {
+		var turn ai.ChatTurn
+		if err := rows.Scan(&turn.Role, &turn.Content); err != nil {
+			return nil, fmt.Errorf("failed to scan chat message: %w", err)
+		}
+		turns = append(turns, turn)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating chat messages: %w", err)
+	}
+
+	// Oldest first, as the conversation happened
+	for i, j := 0, len(turns)-1; i < j; i, j = i+1, j-1 {
+		turns[i], turns[j] = turns[j], turns[i]
+	}
+	return turns, nil
+}
+
+// selectContextChunks picks the k chunks an answer is built from. Candidates
+// are reordered by the reranker's scores, then chosen with MMR so that
+// overlapping neighbours don't crowd out the rest of the material. If
+// reranking fails the search order is kept, and if the embeddings can't be
+// loaded MMR is skipped.
+func (h *RAGHandler) selectContextChunks(ctx context.Context, query string, chunks []database.ChunkResult, k int) []database.ChunkResult {
+	logger := utils.GetLogger()
+
+	passages := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		passages[i] = chunk.Content
+	}
+
+	scores, err := h.reranker.Rerank(ctx, query, passages)
+	if err != nil {
+		logger.Warn("Failed to rerank chunks, keeping search order",
+			zap.String("reranker", h.reranker.Name()),
+			zap.Error(err),
+		)
+		scores = nil
+	}
+
+	ranked := make([]database.ChunkResult, 0, len(chunks))
+	for _, i := range rerank.Order(scores, len(chunks)) {
+		chunk := chunks[i]
+		if scores != nil {
+			score := scores[i]
+			chunk.RerankScore = &score
+		}
+		ranked = append(ranked, chunk)
+	}
+
+	if h.cfg.SearchMMRLambda >= 1 || len(ranked) <= k {
+		return ranked[:min(k, len(ranked))]
+	}
+
+	ids := make([]string, len(ranked))
+	for i, chunk := range ranked {
+		ids[i] = chunk.ID
+	}
+	embeddings, err := h.pgx.GetChunkEmbeddings(ctx, ids, h.embeddingVersion)
+	if err != nil {
+		logger.Warn("Failed to load chunk embeddings, skipping MMR", zap.Error(err))
+		return ranked[:k]
+	}
+
+	// Relevance is the rerank score where there is one, otherwise it falls
+	// off linearly with the search rank
+	relevance := make([]float64, len(ranked))
+	vectors := make([][]float32, len(ranked))
+	for i, chunk := range ranked {
+		if chunk.RerankScore != nil {
+			relevance[i] = *chunk.RerankScore
+		} else {
+			relevance[i] = 1 - float64(i)/float64(len(ranked))
+		}
+		vectors[i] = embeddings[chunk.ID]
+	}
+
+	selected := make([]database.ChunkResult, 0, k)
+	for _, i := range rerank.MMR(relevance, vectors, k, h.cfg.SearchMMRLambda) {
+		selected = append(selected, ranked[i])
+	}
+
+	return selected
+}
+
 // Helper methods
 
 func (h *RAGHandler) parsePage(pageStr string) int {
@@ -702,6 +1058,50 @@ func (h *RAGHandler) parsePage(pageStr string) int {
 	return page
 }
 
+// citationFromChunk builds a citation, resolving the chunk's location
+// (e.g. "page 3" or "slide 14") from its metadata when the extractor
+// recorded one
+func (h *RAGHandler) citationFromChunk(chunk database.ChunkResult) models.Citation {
+	citation := models.Citation{
+		DocumentID:    chunk.DocumentID,
+		DocumentTitle: chunk.DocumentTitle,
+		Ordinal:       chunk.Ordinal,
+		Snippet:       h.truncateText(chunk.Content, 200),
+		SourceURL:     chunk.SourceURL,
+		RerankScore:   chunk.RerankScore,
+	}
+
+	metadata, _ := chunk.Metadata.(map[string]interface{})
+	if page, ok := metadataInt(metadata, "page"); ok {
+		citation.Page = &page
+		citation.Location = fmt.Sprintf("page %d", page)
+	}
+	if slide, ok := metadataInt(metadata, "slide"); ok {
+		citation.Slide = &slide
+		citation.Location = fmt.Sprintf("slide %d", slide)
+	}
+	if start, ok := metadataInt(metadata, "char_start"); ok {
+		citation.CharStart = &start
+	}
+	if end, ok := metadataInt(metadata, "char_end"); ok {
+		citation.CharEnd = &end
+	}
+	citation.ChunkType, _ = metadata["chunk_type"].(string)
+
+	return citation
+}
+
+// metadataInt reads an integer from chunk metadata decoded from JSONB
+func metadataInt(metadata map[string]interface{}, key string) (int, bool) {
+	switch v := metadata[key].(type) {
+	case float64:
+		return int(v), true
+	case int:
+		return v, true
+	}
+	return 0, false
+}
+
 func (h *RAGHandler) truncateText(text string, maxLen int) string {
 	if len(text) <= maxLen {
 		return text
@@ -709,19 +1109,64 @@ func (h *RAGHandler) truncateText(text string, maxLen int) string {
 	return text[:maxLen] + "..."
 }
 
-func (h *RAGHandler) processDocument(documentID string, uploadResult *storage.UploadResult, userID string) {
+// ProcessIngestionJob is the job handler for queued document ingestion. It is
+// run by the ingestion worker pool and keeps the document's processing_status
+// in sync with the job: failed attempts that will be retried put the document
+// back to 'queued', and only a dead-lettered job marks it 'failed'.
+// Re-embedding jobs leave the status alone, as the document stays searchable
+// with its existing chunks until they are replaced.
+func (h *RAGHandler) ProcessIngestionJob(ctx context.Context, job *jobs.Job) error {
+	switch job.Kind {
+	case jobs.KindProcessDocument:
+	case jobs.KindReembedDocument:
+		return h.reembedDocument(ctx, job.DocumentID)
+	default:
+		return fmt.Errorf("unknown job kind: %s", job.Kind)
+	}
+
+	var uploadResult storage.UploadResult
+	if err := json.Unmarshal(job.Payload, &uploadResult); err != nil {
+		h.updateDocumentError(job.DocumentID, fmt.Sprintf("Invalid ingestion job payload: %v", err))
+		return jobs.Permanent(fmt.Errorf("failed to decode job payload: %w", err))
+	}
+
+	err := h.processDocument(ctx, job.DocumentID, &uploadResult)
+	if err == nil {
+		return nil
+	}
+
+	// Aborted by shutdown or a lost lease; the worker puts the job and the
+	// document back in the queue, so leave the document's error alone
+	if ctx.Err() != nil {
+		return err
+	}
+
+	// Documents that tripped the safety limits or a quota would only fail
+	// again
+	if errors.Is(err, extract.ErrUnsafeDocument) || jobs.IsPermanent(err) {
+		h.updateDocumentError(job.DocumentID, err.Error())
+		return jobs.Permanent(err)
+	}
+	if job.IsLastAttempt() {
+		h.updateDocumentError(job.DocumentID, err.Error())
+	} else {
+		h.updateDocumentRetry(job.DocumentID, err.Error())
+	}
+	return err
+}
+
+func (h *RAGHandler) processDocument(ctx context.Context, documentID string, uploadResult *storage.UploadResult) error {
 	logger := utils.GetLogger()
-	ctx := context.Background()
 
 	// Update status to processing
-	_, err := h.db.GetDB().Exec(`
+	_, err := h.db.GetDB().ExecContext(ctx, `
 		UPDATE documents 
 		SET processing_status = 'processing', error = NULL 
 		WHERE id = $1
 	`, documentID)
 	if err != nil {
 		logger.Error("Failed to update document status to processing", zap.Error(err))
-		return
+		return fmt.Errorf("failed to update document status: %w", err)
 	}
 
 	startTime := time.Now()
@@ -731,35 +1176,46 @@ func (h *RAGHandler) processDocument(documentID string, uploadResult *storage.Up
 		zap.String("mime_type", uploadResult.MimeType),
 	)
 
-	// Get signed URL for file access
-	signedURL, err := h.storage.GetSignedURL(uploadResult.StoragePath, 3600) // 1 hour
+	// The owner's chunk and embedding token quotas are enforced as chunks are
+	// stored and embedded, as other uploads may be using them up meanwhile
+	limits, err := h.documentQuotaLimits(ctx, documentID)
 	if err != nil {
-		logger.Error("Failed to get signed URL", zap.Error(err))
-		h.updateDocumentError(documentID, fmt.Sprintf("Failed to get file access: %v", err))
-		return
+		return fmt.Errorf("failed to get quota limits: %w", err)
 	}
 
-	// Download file via HTTP GET
-	resp, err := http.Get(signedURL)
-	if err != nil {
-		logger.Error("Failed to download file", zap.Error(err))
-		h.updateDocumentError(documentID, fmt.Sprintf("Failed to download file: %v", err))
-		return
+	// Identical content has already been chunked and embedded, possibly for
+	// another user's copy, so reuse those chunks instead of paying again
+	if uploadResult.Checksum != "" {
+		reused, err := h.reuseChunksByChecksum(ctx, documentID, uploadResult.Filename, uploadResult.Checksum, limits.Chunks)
+		if errors.Is(err, database.ErrChunkQuotaExceeded) {
+			return chunkQuotaError(err, limits)
+		}
+		if err != nil {
+			logger.Warn("Failed to reuse chunks, processing from scratch",
+				zap.String("document_id", documentID),
+				zap.Error(err),
+			)
+		} else if reused {
+			h.reportStage(ctx, documentID, progress.StageCompleted)
+			return nil
+		}
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		logger.Error("Failed to download file - bad status", zap.Int("status", resp.StatusCode))
-		h.updateDocumentError(documentID, fmt.Sprintf("Failed to download file: HTTP %d", resp.StatusCode))
-		return
+	// Read the file from the storage backend
+	h.reportStage(ctx, documentID, progress.StageDownloading)
+	file, err := h.storage.Open(ctx, uploadResult.StoragePath)
+	if err != nil {
+		logger.Error("Failed to download file", zap.Error(err))
+		return fmt.Errorf("failed to download file: %w", err)
 	}
+	defer file.Close()
 
 	// Extract text from file
-	extraction, err := h.extractor.ExtractText(resp.Body, uploadResult.Filename)
+	h.reportStage(ctx, documentID, progress.StageExtracting)
+	extraction, err := h.extractor.ExtractText(ctx, file, uploadResult.Filename)
 	if err != nil {
 		logger.Error("Failed to extract text", zap.Error(err))
-		h.updateDocumentError(documentID, fmt.Sprintf("Failed to extract text: %v", err))
-		return
+		return fmt.Errorf("failed to extract text: %w", err)
 	}
 
 	logger.Info("Text extracted successfully",
@@ -767,12 +1223,21 @@ func (h *RAGHandler) processDocument(documentID string, uploadResult *storage.Up
 		zap.Int("text_length", len(extraction.Text)),
 	)
 
-	// Chunk the text
-	chunks, err := h.chunker.ChunkText(extraction.Text, extraction.Metadata)
+	// Chunk the text, keeping sections (pages, slides) apart when the extractor found them
+	h.reportStage(ctx, documentID, progress.StageChunking)
+	var chunks []chunker.Chunk
+	if len(extraction.Sections) > 0 {
+		sections := make([]chunker.Section, 0, len(extraction.Sections))
+		for _, section := range extraction.Sections {
+			sections = append(sections, chunker.Section{Text: section.Text, Metadata: section.Metadata, Offset: section.Offset})
+		}
+		chunks, err = h.chunker.ChunkSections(sections, extraction.Metadata)
+	} else {
+		chunks, err = h.chunker.ChunkText(extraction.Text, extraction.Metadata)
+	}
 	if err != nil {
 		logger.Error("Failed to chunk text", zap.Error(err))
-		h.updateDocumentError(documentID, fmt.Sprintf("Failed to chunk text: %v", err))
-		return
+		return fmt.Errorf("failed to chunk text: %w", err)
 	}
 
 	logger.Info("Text chunked successfully",
@@ -786,18 +1251,41 @@ func (h *RAGHandler) processDocument(documentID string, uploadResult *storage.Up
 		chunkTexts = append(chunkTexts, chunk.Content)
 	}
 
-	// Generate embeddings in batch
-	embeddings, err := h.embeddings.GenerateEmbeddings(chunkTexts)
+	reservedTokens, err := h.reserveEmbeddingTokens(ctx, documentID, chunkTexts, limits)
+	if err != nil {
+		logger.Warn("Failed to reserve embedding tokens", zap.String("document_id", documentID), zap.Error(err))
+		return err
+	}
+
+	// Generate embeddings in batch, reporting progress for large documents
+	h.progress.Publish(ctx, progress.Event{DocumentID: documentID, Stage: progress.StageEmbedding, Total: len(chunkTexts)})
+	embeddings, err := h.embeddings.GenerateEmbeddings(ctx, chunkTexts, func(completed, total int) {
+		logger.Info("Embedding progress",
+			zap.String("document_id", documentID),
+			zap.Int("completed", completed),
+			zap.Int("total", total),
+		)
+		h.progress.Publish(ctx, progress.Event{
+			DocumentID: documentID,
+			Stage:      progress.StageEmbedding,
+			Completed:  completed,
+			Total:      total,
+		})
+	})
 	if err != nil {
 		logger.Error("Failed to generate embeddings", zap.Error(err))
-		h.updateDocumentError(documentID, fmt.Sprintf("Failed to generate embeddings: %v", err))
-		return
+		h.releaseEmbeddingTokens(ctx, documentID, reservedTokens)
+		return fmt.Errorf("failed to generate embeddings: %w", err)
 	}
 
 	logger.Info("Embeddings generated successfully",
 		zap.String("document_id", documentID),
 		zap.Int("embeddings_count", len(embeddings)),
 	)
+	if err := h.checkEmbeddingDimension(embeddings); err != nil {
+		logger.Error("Embedding dimension mismatch", zap.Error(err))
+		return err
+	}
 
 	// Prepare chunks for database insertion
 	var chunkInserts []database.ChunkInsert
@@ -808,39 +1296,332 @@ func (h *RAGHandler) processDocument(documentID string, uploadResult *storage.Up
 		}
 
 		chunkInserts = append(chunkInserts, database.ChunkInsert{
-			DocumentID: documentID,
-			Ordinal:    chunk.Ordinal,
-			Content:    chunk.Content,
-			Embedding:  embeddings[i],
-			Metadata:   chunk.Metadata,
+			DocumentID:       documentID,
+			Ordinal:          chunk.Ordinal,
+			Content:          chunk.Content,
+			Embedding:        embeddings[i],
+			Metadata:         chunk.Metadata,
+			EmbeddingModel:   h.embeddingVersion.Model,
+			EmbeddingVersion: h.embeddingVersion.Version,
 		})
 	}
 
-	// Insert chunks into database
-	err = h.pgx.InsertChunks(ctx, chunkInserts)
+	// Replace any chunks left behind by an earlier attempt
+	h.reportStage(ctx, documentID, progress.StageStoring)
+	err = h.pgx.ReplaceDocumentChunks(ctx, documentID, chunkInserts, limits.Chunks)
 	if err != nil {
 		logger.Error("Failed to insert chunks", zap.Error(err))
-		h.updateDocumentError(documentID, fmt.Sprintf("Failed to save chunks: %v", err))
-		return
+		return chunkQuotaError(fmt.Errorf("failed to save chunks: %w", err), limits)
 	}
 
 	// Update document status to completed
 	processingDuration := time.Since(startTime)
-	_, err = h.db.GetDB().Exec(`
+	_, err = h.db.GetDB().ExecContext(ctx, `
 		UPDATE documents 
 		SET processing_status = 'completed', error = NULL 
 		WHERE id = $1
 	`, documentID)
 	if err != nil {
 		logger.Error("Failed to update document status to completed", zap.Error(err))
-		return
+		return fmt.Errorf("failed to update document status: %w", err)
 	}
+	h.reportStage(ctx, documentID, progress.StageCompleted)
 
 	logger.Info("Document processing completed successfully",
 		zap.String("document_id", documentID),
 		zap.Int("chunks_inserted", len(chunkInserts)),
 		zap.Duration("processing_time", processingDuration),
 	)
+
+	return nil
+}
+
+// StartReindex handles POST /api/user/reindex. It queues a re-embedding job
+// for every document of the user that still has vectors from another
+// embedding model version.
+func (h *RAGHandler) StartReindex(c *gin.Context) {
+	logger := utils.GetLogger()
+
+	userSupabaseID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusUnauthorized,
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	user, err := h.getOrCreateUser(c, userSupabaseID)
+	if err != nil {
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusNotFound,
+			Message: "User not found",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	// Re-embedding spends embedding tokens like a new upload does
+	if apiErr := h.checkEmbeddingQuota(ctx, user.ID); apiErr != nil {
+		utils.SendError(c, apiErr)
+		return
+	}
+
+	documentIDs, err := h.pgx.ListDocumentsNeedingReembed(ctx, user.ID.String(), h.embeddingVersion, jobs.KindReembedDocument)
+	if err != nil {
+		logger.Error("Failed to list documents for re-embedding", zap.Error(err))
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to start re-indexing",
+		})
+		return
+	}
+
+	queued := 0
+	for _, documentID := range documentIDs {
+		if _, err := h.jobs.Enqueue(ctx, jobs.KindReembedDocument, documentID, h.embeddingVersion); err != nil {
+			logger.Error("Failed to queue document for re-embedding",
+				zap.String("document_id", documentID),
+				zap.Error(err),
+			)
+			continue
+		}
+		queued++
+	}
+
+	logger.Info("Re-indexing started",
+		zap.String("user_id", user.ID.String()),
+		zap.String("embedding_model", h.embeddingVersion.Model),
+		zap.Int("jobs_queued", queued),
+	)
+
+	status, err := h.reindexStatus(ctx, user.ID.String())
+	if err != nil {
+		logger.Error("Failed to get reindex progress", zap.Error(err))
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to get re-indexing progress",
+		})
+		return
+	}
+	status.JobsQueued = queued
+
+	utils.SendSuccess(c, status)
+}
+
+// GetReindexStatus handles GET /api/user/reindex
+func (h *RAGHandler) GetReindexStatus(c *gin.Context) {
+	logger := utils.GetLogger()
+
+	userSupabaseID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusUnauthorized,
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	user, err := h.getOrCreateUser(c, userSupabaseID)
+	if err != nil {
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusNotFound,
+			Message: "User not found",
+		})
+		return
+	}
+
+	status, err := h.reindexStatus(c.Request.Context(), user.ID.String())
+	if err != nil {
+		logger.Error("Failed to get reindex progress", zap.Error(err))
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to get re-indexing progress",
+		})
+		return
+	}
+
+	utils.SendSuccess(c, status)
+}
+
+// reindexStatus builds the re-indexing progress response for a user
+func (h *RAGHandler) reindexStatus(ctx context.Context, userID string) (*models.ReindexStatusResponse, error) {
+	progress, err := h.pgx.GetReindexProgress(ctx, userID, h.embeddingVersion, jobs.KindReembedDocument)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &models.ReindexStatusResponse{
+		EmbeddingModel:   h.embeddingVersion.Model,
+		EmbeddingVersion: h.embeddingVersion.Version,
+		TotalDocuments:   progress.TotalDocuments,
+		CurrentDocuments: progress.CurrentDocuments,
+		QueuedDocuments:  progress.QueuedJobs,
+		RunningDocuments: progress.RunningJobs,
+		FailedDocuments:  progress.FailedJobs,
+		Progress:         1,
+		Complete:         progress.CurrentDocuments == progress.TotalDocuments,
+	}
+	if progress.TotalDocuments > 0 {
+		status.Progress = float64(progress.CurrentDocuments) / float64(progress.TotalDocuments)
+	}
+	return status, nil
+}
+
+// reembedDocument re-embeds the chunks of a document that have vectors from
+// another model version, updating them in place. A chunk the model returns
+// no vector for keeps its old one and is picked up by the next re-index.
+func (h *RAGHandler) reembedDocument(ctx context.Context, documentID string) error {
+	logger := utils.GetLogger()
+
+	staleChunks, err := h.pgx.ListStaleChunks(ctx, documentID, h.embeddingVersion)
+	if err != nil {
+		return err
+	}
+	if len(staleChunks) == 0 {
+		return nil
+	}
+
+	texts := make([]string, len(staleChunks))
+	for i, chunk := range staleChunks {
+		texts[i] = chunk.Content
+	}
+
+	limits, err := h.documentQuotaLimits(ctx, documentID)
+	if err != nil {
+		return fmt.Errorf("failed to get quota limits: %w", err)
+	}
+	reservedTokens, err := h.reserveEmbeddingTokens(ctx, documentID, texts, limits)
+	if err != nil {
+		return err
+	}
+
+	vectors, err := h.embeddings.GenerateEmbeddings(ctx, texts, nil)
+	if err != nil {
+		h.releaseEmbeddingTokens(ctx, documentID, reservedTokens)
+		return fmt.Errorf("failed to re-embed chunks: %w", err)
+	}
+	if err := h.checkEmbeddingDimension(vectors); err != nil {
+		return err
+	}
+
+	updates := make([]database.ChunkEmbedding, 0, len(staleChunks))
+	for i, chunk := range staleChunks {
+		if i >= len(vectors) || vectors[i] == nil {
+			continue
+		}
+		chunk.Embedding = vectors[i]
+		updates = append(updates, chunk)
+	}
+
+	updated, err := h.pgx.UpdateChunkEmbeddings(ctx, updates, h.embeddingVersion)
+	if err != nil {
+		return fmt.Errorf("failed to save re-embedded chunks: %w", err)
+	}
+
+	logger.Info("Document re-embedded successfully",
+		zap.String("document_id", documentID),
+		zap.String("embedding_model", h.embeddingVersion.Model),
+		zap.String("embedding_version", h.embeddingVersion.Version),
+		zap.Int64("chunks", updated),
+		zap.Int("chunks_without_vector", len(staleChunks)-len(updates)),
+	)
+	return nil
+}
+
+// checkEmbeddingDimension returns a permanent error if the embedder returned
+// vectors of another dimension than EMBEDDING_DIMENSION, which the search
+// queries and vector index are built for. Retrying cannot fix the mismatch.
+func (h *RAGHandler) checkEmbeddingDimension(vectors [][]float32) error {
+	for _, vector := range vectors {
+		if vector != nil && len(vector) != h.embeddingVersion.Dimension {
+			return jobs.Permanent(fmt.Errorf("embedding model returned %d dimensions but EMBEDDING_DIMENSION is %d", len(vector), h.embeddingVersion.Dimension))
+		}
+	}
+	return nil
+}
+
+// findUserDocumentByChecksum returns the user's existing document with the
+// given content checksum, or nil. Failed documents are ignored so the user can
+// upload the file again.
+func (h *RAGHandler) findUserDocumentByChecksum(userID, checksum string) (*models.UploadResponse, error) {
+	if checksum == "" {
+		return nil, nil
+	}
+
+	var doc models.UploadResponse
+	var sourceURL sql.NullString
+	err := h.db.GetDB().QueryRow(`
+		SELECT id, title, source_url, mime_type
+		FROM documents
+		WHERE user_id = $1 AND checksum = $2 AND processing_status <> 'failed'
+		ORDER BY created_at
+		LIMIT 1
+	`, userID, checksum).Scan(&doc.DocumentID, &doc.Title, &sourceURL, &doc.MimeType)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up document by checksum: %w", err)
+	}
+
+	doc.SourceURL = sourceURL.String
+	doc.Duplicate = true
+	return &doc, nil
+}
+
+// reuseChunksByChecksum copies the chunks and embeddings of a completed
+// document with identical content and marks the document completed. The
+// copied chunks are labelled with filename rather than the source's name and
+// count against chunkLimit. It reports false when there is no such document.
+func (h *RAGHandler) reuseChunksByChecksum(ctx context.Context, documentID, filename, checksum string, chunkLimit *int64) (bool, error) {
+	logger := utils.GetLogger()
+
+	var sourceDocumentID string
+	err := h.db.GetDB().QueryRowContext(ctx, `
+		SELECT d.id
+		FROM documents d
+		WHERE d.checksum = $1
+		  AND d.id <> $2
+		  AND d.processing_status = 'completed'
+		  AND EXISTS (
+			SELECT 1 FROM chunks c
+			WHERE c.document_id = d.id AND c.embedding_model = $3 AND c.embedding_version = $4
+		  )
+		ORDER BY d.created_at
+		LIMIT 1
+	`, checksum, documentID, h.embeddingVersion.Model, h.embeddingVersion.Version).Scan(&sourceDocumentID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to look up document by checksum: %w", err)
+	}
+
+	copied, err := h.pgx.CopyDocumentChunks(ctx, sourceDocumentID, documentID, filename, h.embeddingVersion, chunkLimit)
+	if err != nil {
+		return false, err
+	}
+	if copied == 0 {
+		return false, nil
+	}
+
+	_, err = h.db.GetDB().ExecContext(ctx, `
+		UPDATE documents 
+		SET processing_status = 'completed', error = NULL 
+		WHERE id = $1
+	`, documentID)
+	if err != nil {
+		return false, fmt.Errorf("failed to update document status: %w", err)
+	}
+
+	logger.Info("Reused chunks from identical document",
+		zap.String("document_id", documentID),
+		zap.String("source_document_id", sourceDocumentID),
+		zap.Int64("chunks_copied", copied),
+	)
+	return true, nil
 }
 
 func (h *RAGHandler) addFileMessageToChat(chatID, userID string, uploadResult *storage.UploadResult, documentID string) {
@@ -931,6 +1712,30 @@ func (h *RAGHandler) updateDocumentError(documentID, errorMsg string) {
 			zap.String("document_id", documentID),
 		)
 	}
+	h.progress.Publish(context.Background(), progress.Event{DocumentID: documentID, Stage: progress.StageFailed, Error: errorMsg})
+}
+
+// updateDocumentRetry puts a document back to queued after a failed attempt that will be retried
+func (h *RAGHandler) updateDocumentRetry(documentID, errorMsg string) {
+	logger := utils.GetLogger()
+
+	_, err := h.db.GetDB().Exec(`
+		UPDATE documents 
+		SET processing_status = 'queued', error = $2 
+		WHERE id = $1
+	`, documentID, errorMsg)
+	if err != nil {
+		logger.Error("Failed to update document retry status",
+			zap.Error(err),
+			zap.String("document_id", documentID),
+		)
+	}
+	h.progress.Publish(context.Background(), progress.Event{DocumentID: documentID, Stage: progress.StageQueued, Error: errorMsg})
+}
+
+// reportStage publishes a processing stage transition of a document
+func (h *RAGHandler) reportStage(ctx context.Context, documentID string, stage progress.Stage) {
+	h.progress.Publish(ctx, progress.Event{DocumentID: documentID, Stage: stage})
 }
 
 // DeleteDocument handles DELETE /api/documents/:id
@@ -967,14 +1772,14 @@ func (h *RAGHandler) DeleteDocument(c *gin.Context) {
 
 	// Get document details and verify ownership
 	var doc struct {
-		UserID    string
-		SourceURL *string
+		UserID      string
+		StoragePath *string
 	}
 	err = h.db.GetDB().QueryRow(`
-		SELECT user_id, source_url 
+		SELECT user_id, storage_path
 		FROM documents 
 		WHERE id = $1
-	`, documentID).Scan(&doc.UserID, &doc.SourceURL)
+	`, documentID).Scan(&doc.UserID, &doc.StoragePath)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			utils.SendError(c, &models.APIError{
@@ -999,8 +1804,11 @@ func (h *RAGHandler) DeleteDocument(c *gin.Context) {
 		return
 	}
 
-	// Delete document from database (cascades to chunks)
-	_, err = h.db.GetDB().Exec("DELETE FROM documents WHERE id = $1", documentID)
+	// Delete document from database; chunks, jobs and the row go in one
+	// statement. The file is removed afterwards, so a document never points
+	// at a missing file. If that fails the file is orphaned and the storage
+	// reconciler removes it later.
+	_, err = h.db.GetDB().ExecContext(c.Request.Context(), "DELETE FROM documents WHERE id = $1 AND user_id = $2", documentID, user.ID)
 	if err != nil {
 		logger.Error("Failed to delete document", zap.Error(err))
 		utils.SendError(c, &models.APIError{
@@ -1010,14 +1818,15 @@ func (h *RAGHandler) DeleteDocument(c *gin.Context) {
 		return
 	}
 
-	// Delete file from storage if it exists
-	if doc.SourceURL != nil {
-		// Extract storage path from URL (this depends on your storage implementation)
-		// For now, we'll skip this step - in production, implement proper file deletion
-		logger.Info("Document file should be deleted from storage", 
-			zap.String("document_id", documentID),
-			zap.String("source_url", *doc.SourceURL),
-		)
+	// Delete file from storage
+	if doc.StoragePath != nil && *doc.StoragePath != "" {
+		if err := h.storage.DeleteFile(*doc.StoragePath); err != nil {
+			logger.Warn("Failed to delete document file, leaving it for reconciliation",
+				zap.String("document_id", documentID),
+				zap.String("storage_path", *doc.StoragePath),
+				zap.Error(err),
+			)
+		}
 	}
 
 	logger.Info("Document deleted successfully", zap.String("document_id", documentID))
@@ -1058,16 +1867,18 @@ func (h *RAGHandler) ReprocessDocument(c *gin.Context) {
 
 	// Get document details and verify ownership
 	var doc struct {
-		UserID    string
-		Title     string
-		SourceURL *string
-		MimeType  string
+		UserID      string
+		Title       string
+		SourceURL   *string
+		StoragePath *string
+		MimeType    string
+		Size        *int64
 	}
 	err = h.db.GetDB().QueryRow(`
-		SELECT user_id, title, source_url, mime_type 
+		SELECT user_id, title, source_url, storage_path, mime_type, size
 		FROM documents 
 		WHERE id = $1
-	`, documentID).Scan(&doc.UserID, &doc.Title, &doc.SourceURL, &doc.MimeType)
+	`, documentID).Scan(&doc.UserID, &doc.Title, &doc.SourceURL, &doc.StoragePath, &doc.MimeType, &doc.Size)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			utils.SendError(c, &models.APIError{
@@ -1092,22 +1903,25 @@ func (h *RAGHandler) ReprocessDocument(c *gin.Context) {
 		return
 	}
 
-	// Delete existing chunks
-	_, err = h.db.GetDB().Exec("DELETE FROM chunks WHERE document_id = $1", documentID)
-	if err != nil {
-		logger.Error("Failed to delete existing chunks", zap.Error(err))
+	if doc.StoragePath == nil || *doc.StoragePath == "" {
 		utils.SendError(c, &models.APIError{
-			Code:    http.StatusInternalServerError,
-			Message: "Failed to prepare for reprocessing",
+			Code:    http.StatusConflict,
+			Message: "Original file is not available for reprocessing",
 		})
 		return
 	}
 
-	// Reset document status
-	_, err = h.db.GetDB().Exec(`
+	// Reset the document status unless a job for it is already waiting or
+	// running. The existing chunks stay searchable until the job swaps in
+	// the new ones atomically.
+	result, err := h.db.GetDB().Exec(`
 		UPDATE documents 
 		SET processing_status = 'queued', error = NULL 
 		WHERE id = $1
+			AND NOT EXISTS (
+				SELECT 1 FROM ingestion_jobs
+				WHERE document_id = $1 AND status IN ('queued', 'running')
+			)
 	`, documentID)
 	if err != nil {
 		logger.Error("Failed to reset document status", zap.Error(err))
@@ -1117,18 +1931,39 @@ func (h *RAGHandler) ReprocessDocument(c *gin.Context) {
 		})
 		return
 	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusConflict,
+			Message: "Document is already being processed",
+		})
+		return
+	}
 
-	// Create upload result for reprocessing
+	// Create upload result for reprocessing. The checksum is left out so the
+	// file is processed from scratch rather than copying existing chunks.
 	uploadResult := &storage.UploadResult{
-		Filename:  doc.Title,
-		MimeType:  doc.MimeType,
-		PublicURL: *doc.SourceURL,
-		// Note: StoragePath would need to be reconstructed from URL
-		// This is a simplified implementation
+		Filename:    doc.Title,
+		MimeType:    doc.MimeType,
+		StoragePath: *doc.StoragePath,
+	}
+	if doc.SourceURL != nil {
+		uploadResult.PublicURL = *doc.SourceURL
+	}
+	if doc.Size != nil {
+		uploadResult.Size = *doc.Size
 	}
 
-	// Start reprocessing in background
-	go h.processDocument(documentID, uploadResult, user.ID.String())
+	// Queue document for reprocessing; a concurrent request for the same
+	// document shares the job
+	if _, err := h.jobs.Enqueue(c.Request.Context(), jobs.KindProcessDocument, documentID, uploadResult); err != nil {
+		logger.Error("Failed to queue document for reprocessing", zap.Error(err))
+		h.updateDocumentError(documentID, fmt.Sprintf("Failed to queue document: %v", err))
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to queue document for reprocessing",
+		})
+		return
+	}
 
 	logger.Info("Document reprocessing started", zap.String("document_id", documentID))
 	utils.SendSuccess(c, map[string]string{"message": "Document reprocessing started"})
@@ -1442,6 +2277,13 @@ func (h *RAGHandler) RAGHealth(c *gin.Context) {
 	// Check storage health (simplified)
 	storageHealth := true // In production, implement proper storage health check
 
+	// Report the vector index; a missing or building index slows search
+	// down but doesn't break it, so it doesn't affect the status
+	vectorIndex, err := h.pgx.GetVectorIndexHealth(ctx)
+	if err != nil {
+		logger.Warn("Vector index health check failed", zap.Error(err))
+	}
+
 	// Determine overall status
 	status := "healthy"
 	if !embeddingsHealth || !databaseHealth || !storageHealth {
@@ -1453,6 +2295,7 @@ func (h *RAGHandler) RAGHealth(c *gin.Context) {
 		EmbeddingsHealth: embeddingsHealth,
 		DatabaseHealth:   databaseHealth,
 		StorageHealth:    storageHealth,
+		VectorIndex:      vectorIndex,
 		Timestamp:        time.Now(),
 	}
 
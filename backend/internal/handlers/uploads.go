@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kinyichukwu/edu-pro-backend/internal/middleware"
+	"github.com/kinyichukwu/edu-pro-backend/internal/models"
+	"github.com/kinyichukwu/edu-pro-backend/internal/services/storage"
+	"github.com/kinyichukwu/edu-pro-backend/internal/services/uploads"
+	"github.com/kinyichukwu/edu-pro-backend/internal/utils"
+	"go.uber.org/zap"
+)
+
+// uploadOffsetHeader carries the byte offset of a chunk, as in the tus protocol
+const uploadOffsetHeader = "Upload-Offset"
+
+// CreateUpload handles POST /api/uploads. It starts a resumable upload
+// session; the file is then sent in chunks with PATCH /api/uploads/:id.
+func (h *RAGHandler) CreateUpload(c *gin.Context) {
+	logger := utils.GetLogger()
+
+	userSupabaseID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusUnauthorized,
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	user, err := h.db.GetUserBySupabaseID(userSupabaseID)
+	if err != nil {
+		logger.Error("Failed to get user", zap.Error(err))
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusNotFound,
+			Message: "User not found",
+		})
+		return
+	}
+
+	var req models.CreateUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid request body",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusBadRequest,
+			Message: "Validation failed",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if !h.storage.IsValidFileType(req.Filename) {
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusBadRequest,
+			Message: "Unsupported file type",
+		})
+		return
+	}
+
+	maxSize, err := h.uploadLimit(user.ID)
+	if err != nil {
+		logger.Error("Failed to get upload limit", zap.Error(err))
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to create upload",
+		})
+		return
+	}
+	if req.Size > maxSize {
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusRequestEntityTooLarge,
+			Message: "File is too large",
+			Details: fmt.Sprintf("Your plan allows files up to %dMB", maxSize/(1024*1024)),
+		})
+		return
+	}
+
+	// The quota is checked once the upload completes, as the file may turn
+	// out to duplicate one the user already has
+
+	session, err := h.uploads.Create(c.Request.Context(), user.ID.String(), req.Filename, req.Size, req.ChatID)
+	if err != nil {
+		logger.Error("Failed to create upload session", zap.Error(err))
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to create upload",
+		})
+		return
+	}
+
+	c.Header(uploadOffsetHeader, "0")
+	utils.SendSuccess(c, h.uploadSessionResponse(session, nil))
+}
+
+// GetUpload handles GET /api/uploads/:id. Clients call it after an
+// interruption to find the offset to resume from.
+func (h *RAGHandler) GetUpload(c *gin.Context) {
+	session, ok := h.uploadSessionFromRequest(c)
+	if !ok {
+		return
+	}
+
+	c.Header(uploadOffsetHeader, strconv.FormatInt(session.Offset, 10))
+	utils.SendSuccess(c, h.uploadSessionResponse(session, nil))
+}
+
+// AppendUpload handles PATCH /api/uploads/:id. The request body is the next
+// chunk of the file and the Upload-Offset header must match the number of
+// bytes received so far. The chunk that completes the file also stores it
+// and queues the document for processing.
+func (h *RAGHandler) AppendUpload(c *gin.Context) {
+	logger := utils.GetLogger()
+
+	session, ok := h.uploadSessionFromRequest(c)
+	if !ok {
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.GetHeader(uploadOffsetHeader), 10, 64)
+	if err != nil || offset < 0 {
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusBadRequest,
+			Message: "Upload-Offset header is required",
+		})
+		return
+	}
+
+	// Chunks may take longer than the server-wide read timeout
+	controller := http.NewResponseController(c.Writer)
+	controller.SetReadDeadline(time.Now().Add(5 * time.Minute))
+
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, h.cfg.UploadChunkMaxSize)
+	newOffset, err := h.uploads.Append(c.Request.Context(), session, offset, body)
+	c.Header(uploadOffsetHeader, strconv.FormatInt(newOffset, 10))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.Is(err, uploads.ErrOffsetMismatch):
+			utils.SendError(c, &models.APIError{
+				Code:    http.StatusConflict,
+				Message: "Upload offset mismatch",
+				Details: fmt.Sprintf("Resume from offset %d", newOffset),
+			})
+		case errors.Is(err, uploads.ErrBusy):
+			utils.SendError(c, &models.APIError{
+				Code:    http.StatusConflict,
+				Message: "Another chunk is being uploaded",
+				Details: "Wait for it to finish, then query the offset and resume",
+			})
+		case errors.Is(err, uploads.ErrNotActive):
+			utils.SendError(c, &models.APIError{
+				Code:    http.StatusConflict,
+				Message: "Upload is already complete",
+			})
+		case errors.As(err, &maxBytesErr):
+			utils.SendError(c, &models.APIError{
+				Code:    http.StatusRequestEntityTooLarge,
+				Message: "Chunk is too large",
+				Details: fmt.Sprintf("Chunks may be at most %dMB", h.cfg.UploadChunkMaxSize/(1024*1024)),
+			})
+		default:
+			logger.Error("Failed to append upload chunk",
+				zap.String("upload_id", session.ID),
+				zap.Error(err),
+			)
+			utils.SendError(c, &models.APIError{
+				Code:    http.StatusInternalServerError,
+				Message: "Failed to save upload chunk",
+				Details: fmt.Sprintf("Resume from offset %d", newOffset),
+			})
+		}
+		return
+	}
+
+	if session.Offset < session.Size {
+		utils.SendSuccess(c, h.uploadSessionResponse(session, nil))
+		return
+	}
+
+	// The file is complete; stream it to storage
+	controller.SetWriteDeadline(time.Now().Add(15 * time.Minute))
+
+	file, err := h.uploads.Open(session)
+	if err != nil {
+		logger.Error("Failed to open completed upload", zap.Error(err))
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to upload file",
+		})
+		return
+	}
+	defer file.Close()
+
+	uploadResult, err := h.storage.UploadStream(file, session.Filename, session.Size, session.UserID, 0)
+	if err != nil {
+		logger.Error("Failed to upload file", zap.Error(err))
+		// A file with the wrong type or content can't be fixed by resuming
+		if errors.Is(err, storage.ErrContentMismatch) || errors.Is(err, storage.ErrUnsupportedType) {
+			if err := h.uploads.Delete(c.Request.Context(), session); err != nil {
+				logger.Warn("Failed to delete rejected upload session", zap.Error(err))
+			}
+		}
+		utils.SendError(c, uploadError(err))
+		return
+	}
+
+	document, apiErr := h.createDocument(c.Request.Context(), session.UserID, uploadResult, session.ChatID, "")
+	if apiErr != nil {
+		// Nor can a file over quota
+		if apiErr.Code == http.StatusForbidden {
+			if err := h.uploads.Delete(c.Request.Context(), session); err != nil {
+				logger.Warn("Failed to delete rejected upload session", zap.Error(err))
+			}
+		}
+		utils.SendError(c, apiErr)
+		return
+	}
+
+	if err := h.uploads.Complete(c.Request.Context(), session, document.DocumentID); err != nil {
+		logger.Warn("Failed to mark upload session completed", zap.Error(err))
+	}
+
+	utils.SendSuccess(c, h.uploadSessionResponse(session, document))
+}
+
+// DeleteUpload handles DELETE /api/uploads/:id, aborting an upload
+func (h *RAGHandler) DeleteUpload(c *gin.Context) {
+	logger := utils.GetLogger()
+
+	session, ok := h.uploadSessionFromRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := h.uploads.Delete(c.Request.Context(), session); err != nil {
+		logger.Error("Failed to delete upload session", zap.Error(err))
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to delete upload",
+		})
+		return
+	}
+
+	utils.SendSuccess(c, map[string]string{"message": "Upload deleted successfully"})
+}
+
+// uploadSessionFromRequest loads the session named in the URL for the
+// authenticated user, sending an error response when it can't
+func (h *RAGHandler) uploadSessionFromRequest(c *gin.Context) (*uploads.Session, bool) {
+	logger := utils.GetLogger()
+
+	userSupabaseID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusUnauthorized,
+			Message: "User not authenticated",
+		})
+		return nil, false
+	}
+
+	user, err := h.db.GetUserBySupabaseID(userSupabaseID)
+	if err != nil {
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusNotFound,
+			Message: "User not found",
+		})
+		return nil, false
+	}
+
+	session, err := h.uploads.Get(c.Request.Context(), c.Param("id"), user.ID.String())
+	if err != nil {
+		if errors.Is(err, uploads.ErrNotFound) {
+			utils.SendError(c, &models.APIError{
+				Code:    http.StatusNotFound,
+				Message: "Upload not found",
+			})
+			return nil, false
+		}
+		logger.Error("Failed to get upload session", zap.Error(err))
+		utils.SendError(c, &models.APIError{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to get upload",
+		})
+		return nil, false
+	}
+
+	return session, true
+}
+
+// uploadSessionResponse builds the API response for an upload session
+func (h *RAGHandler) uploadSessionResponse(session *uploads.Session, document *models.UploadResponse) *models.UploadSessionResponse {
+	return &models.UploadSessionResponse{
+		UploadID:  session.ID,
+		Filename:  session.Filename,
+		Size:      session.Size,
+		Offset:    session.Offset,
+		Status:    session.Status,
+		ExpiresAt: session.ExpiresAt,
+		Document:  document,
+	}
+}
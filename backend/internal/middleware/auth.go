@@ -30,8 +30,14 @@ func JWTMiddleware(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
-		// Get the Authorization header
+		// Get the Authorization header. Browsers can't set headers on an
+		// EventSource, so event streams may pass the token as a query parameter.
 		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" && c.GetHeader("Accept") == "text/event-stream" {
+			if token := c.Query("access_token"); token != "" {
+				authHeader = "Bearer " + token
+			}
+		}
 		if authHeader == "" {
 			logger.Warn("Missing Authorization header")
 			c.JSON(http.StatusUnauthorized, gin.H{
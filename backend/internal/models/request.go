@@ -64,6 +64,22 @@ type AskRequest struct {
 	Query       string   `json:"query" validate:"required,min=3,max=1000"`
 	ChatID      string   `json:"chat_id,omitempty"`
 	DocumentIDs []string `json:"document_ids,omitempty"`
+	// Weight of keyword search against vector search: 0 is pure vector,
+	// 1 pure keyword; the server default applies when omitted
+	KeywordWeight *float64 `json:"keyword_weight,omitempty" validate:"omitempty,min=0,max=1"`
+}
+
+// CreateUploadRequest starts a resumable upload
+type CreateUploadRequest struct {
+	Filename string `json:"filename" validate:"required,max=255"`
+	Size     int64  `json:"size" validate:"required,min=1"`
+	ChatID   string `json:"chat_id,omitempty"`
+}
+
+// ImportDocumentRequest imports a document from a URL
+type ImportDocumentRequest struct {
+	URL    string `json:"url" validate:"required,url,max=2048"`
+	ChatID string `json:"chat_id,omitempty"`
 }
 
 // UpdateChatRequest represents a request to update chat details
@@ -141,8 +141,16 @@ type Citation struct {
 	DocumentID    string  `json:"document_id"`
 	DocumentTitle string  `json:"document_title"`
 	Ordinal       int     `json:"ordinal"`
+	Location      string  `json:"location,omitempty"`
+	Page          *int    `json:"page,omitempty"`
+	Slide         *int    `json:"slide,omitempty"`
+	CharStart     *int    `json:"char_start,omitempty"`
+	CharEnd       *int    `json:"char_end,omitempty"`
+	ChunkType     string  `json:"chunk_type,omitempty"`
 	Snippet       string  `json:"snippet"`
 	SourceURL     *string `json:"source_url"`
+	// Relevance from the reranking stage, 0 to 1; omitted when not reranked
+	RerankScore *float64 `json:"rerank_score,omitempty"`
 }
 
 // AskResponse represents the response to an ask query
@@ -150,6 +158,8 @@ type AskResponse struct {
 	ChatID    string     `json:"chat_id"`
 	Answer    string     `json:"answer"`
 	Citations []Citation `json:"citations"`
+	// Standalone query the question was searched by, when it was rewritten
+	SearchQuery string `json:"search_query,omitempty"`
 }
 
 // UploadResponse represents file upload response
@@ -158,6 +168,19 @@ type UploadResponse struct {
 	Title      string `json:"title"`
 	SourceURL  string `json:"source_url"`
 	MimeType   string `json:"mime_type"`
+	Duplicate  bool   `json:"duplicate,omitempty"` // Same file was already uploaded by this user
+}
+
+// UploadSessionResponse represents the state of a resumable upload. Document
+// is set once the final chunk has been received.
+type UploadSessionResponse struct {
+	UploadID  string          `json:"upload_id"`
+	Filename  string          `json:"filename"`
+	Size      int64           `json:"size"`
+	Offset    int64           `json:"offset"`
+	Status    string          `json:"status"`
+	ExpiresAt time.Time       `json:"expires_at"`
+	Document  *UploadResponse `json:"document,omitempty"`
 }
 
 // ChunkResponse represents a document chunk
@@ -179,9 +202,100 @@ type DocumentChunksResponse struct {
 
 // RAGHealthResponse represents RAG system health
 type RAGHealthResponse struct {
-	Status           string    `json:"status"`
-	EmbeddingsHealth bool      `json:"embeddings_health"`
-	DatabaseHealth   bool      `json:"database_health"`
-	StorageHealth    bool      `json:"storage_health"`
-	Timestamp        time.Time `json:"timestamp"`
+	Status           string             `json:"status"`
+	EmbeddingsHealth bool               `json:"embeddings_health"`
+	DatabaseHealth   bool               `json:"database_health"`
+	StorageHealth    bool               `json:"storage_health"`
+	VectorIndex      *VectorIndexHealth `json:"vector_index,omitempty"`
+	Timestamp        time.Time          `json:"timestamp"`
+}
+
+// VectorIndexHealth reports the ANN indexes on chunk embeddings
+type VectorIndexHealth struct {
+	Method          string              `json:"method"` // Configured method: hnsw, ivfflat or none
+	Ready           bool                `json:"ready"`  // A valid index of the configured method is in use
+	Dimension       int                 `json:"dimension"`
+	EfSearch        int                 `json:"ef_search,omitempty"`
+	Probes          int                 `json:"probes,omitempty"`
+	EstimatedChunks int64               `json:"estimated_chunks"`
+	Building        bool                `json:"building"`
+	BuildPhase      string              `json:"build_phase,omitempty"`
+	BuildProgress   float64             `json:"build_progress,omitempty"` // 0-1
+	Indexes         []VectorIndexStatus `json:"indexes"`
+}
+
+// VectorIndexStatus describes one ANN index
+type VectorIndexStatus struct {
+	Name      string   `json:"name"`
+	Method    string   `json:"method"`
+	Dimension int      `json:"dimension"` // 0 for a legacy index on the bare column
+	Valid     bool     `json:"valid"`     // False while building or after a failed build
+	SizeBytes int64    `json:"size_bytes"`
+	Scans     int64    `json:"scans"`
+	Options   []string `json:"options"`
+}
+
+// SkippedFile is an archive entry that was not ingested
+type SkippedFile struct {
+	Path       string `json:"path"`
+	Reason     string `json:"reason"`
+	DocumentID string `json:"document_id,omitempty"` // Existing document a duplicate matched
+}
+
+// BatchUploadResponse represents the documents created from a ZIP archive
+type BatchUploadResponse struct {
+	BatchID   string           `json:"batch_id"`
+	Filename  string           `json:"filename"`
+	Documents []UploadResponse `json:"documents"`
+	Skipped   []SkippedFile    `json:"skipped,omitempty"`
+}
+
+// BatchStatusResponse aggregates the processing status of a batch's documents.
+// Status is processing while any document is queued or processing, then
+// completed, partial (some failed) or failed.
+type BatchStatusResponse struct {
+	BatchID    string             `json:"batch_id"`
+	Filename   string             `json:"filename"`
+	Status     string             `json:"status"`
+	Total      int                `json:"total"`
+	Queued     int                `json:"queued"`
+	Processing int                `json:"processing"`
+	Completed  int                `json:"completed"`
+	Failed     int                `json:"failed"`
+	Progress   float64            `json:"progress"` // Fraction of documents finished, 0-1
+	Documents  []DocumentResponse `json:"documents"`
+	Skipped    []SkippedFile      `json:"skipped,omitempty"`
+	CreatedAt  time.Time          `json:"created_at"`
+}
+
+// ReindexStatusResponse represents the progress of re-embedding a user's
+// documents with the active embedding model
+type ReindexStatusResponse struct {
+	EmbeddingModel   string  `json:"embedding_model"`
+	EmbeddingVersion string  `json:"embedding_version"`
+	TotalDocuments   int     `json:"total_documents"`
+	CurrentDocuments int     `json:"current_documents"`
+	QueuedDocuments  int     `json:"queued_documents"`
+	RunningDocuments int     `json:"running_documents"`
+	FailedDocuments  int     `json:"failed_documents"`
+	Progress         float64 `json:"progress"` // Fraction of documents on the active model, 0-1
+	Complete         bool    `json:"complete"`
+	JobsQueued       int     `json:"jobs_queued,omitempty"` // Set when starting a re-index
+}
+
+// UsageResponse reports a user's consumption against their quotas
+type UsageResponse struct {
+	Plan            string      `json:"plan"`
+	Role            string      `json:"role,omitempty"`
+	Documents       UsageMetric `json:"documents"`
+	StorageBytes    UsageMetric `json:"storage_bytes"`
+	Chunks          UsageMetric `json:"chunks"`
+	EmbeddingTokens UsageMetric `json:"embedding_tokens"` // Current calendar month
+	PeriodResetsAt  time.Time   `json:"period_resets_at"` // When monthly usage starts over
+}
+
+// UsageMetric is the consumption of one resource. Limit is null when unlimited.
+type UsageMetric struct {
+	Used  int64  `json:"used"`
+	Limit *int64 `json:"limit"`
 }
@@ -138,6 +138,102 @@ func (c *Client) GenerateExplanation(req *GeminiRequest) (*models.ExplanationRes
 	return response, nil
 }
 
+// ScoreRelevance rates how well each passage answers the query, from 0
+// (irrelevant) to 1 (directly answers it), in the order given
+func (c *Client) ScoreRelevance(query string, passages []string) ([]float64, error) {
+	logger := utils.GetLogger()
+
+	if len(passages) == 0 {
+		return nil, nil
+	}
+
+	prompt := RerankPrompt(SanitizeInput(query), passages)
+
+	content, err := c.callGeminiAPI(prompt)
+	if err != nil {
+		logger.Error("Failed to call Gemini API for relevance scoring", zap.Error(err))
+		return nil, fmt.Errorf("failed to score relevance: %w", err)
+	}
+
+	cleanedContent := cleanJSONResponse(content)
+
+	var scoreData struct {
+		Scores []float64 `json:"scores"`
+	}
+
+	if err := json.Unmarshal([]byte(cleanedContent), &scoreData); err != nil {
+		logger.Error("Failed to parse relevance scores", zap.Error(err), zap.String("content", cleanedContent))
+		return nil, fmt.Errorf("failed to parse relevance scores: %w", err)
+	}
+
+	if len(scoreData.Scores) != len(passages) {
+		return nil, fmt.Errorf("got %d relevance scores for %d passages", len(scoreData.Scores), len(passages))
+	}
+
+	// The prompt asks for 0-10; clamp and scale to 0-1
+	scores := make([]float64, len(passages))
+	for i, score := range scoreData.Scores {
+		scores[i] = min(max(score, 0), 10) / 10
+	}
+
+	return scores, nil
+}
+
+// RewriteQuery turns a follow-up question into a standalone search query
+// using the conversation so far, optionally adding paraphrases of it. The
+// standalone query comes first; duplicates are dropped.
+func (c *Client) RewriteQuery(history []ChatTurn, query string, paraphrases int) ([]string, error) {
+	logger := utils.GetLogger()
+
+	query = SanitizeInput(query)
+	prompt := QueryRewritePrompt(history, query, paraphrases)
+
+	content, err := c.callGeminiAPI(prompt)
+	if err != nil {
+		logger.Error("Failed to call Gemini API for query rewrite", zap.Error(err))
+		return nil, fmt.Errorf("failed to rewrite query: %w", err)
+	}
+
+	cleanedContent := cleanJSONResponse(content)
+
+	var rewriteData struct {
+		Query       string   `json:"query"`
+		Paraphrases []string `json:"paraphrases"`
+	}
+
+	if err := json.Unmarshal([]byte(cleanedContent), &rewriteData); err != nil {
+		logger.Error("Failed to parse query rewrite", zap.Error(err), zap.String("content", cleanedContent))
+		return nil, fmt.Errorf("failed to parse query rewrite: %w", err)
+	}
+
+	standalone := SanitizeInput(rewriteData.Query)
+	if standalone == "" {
+		return nil, fmt.Errorf("no rewritten query generated")
+	}
+
+	queries := []string{standalone}
+	seen := map[string]bool{strings.ToLower(standalone): true}
+	for _, paraphrase := range rewriteData.Paraphrases {
+		if len(queries) > paraphrases {
+			break
+		}
+		paraphrase = SanitizeInput(paraphrase)
+		if paraphrase == "" || seen[strings.ToLower(paraphrase)] {
+			continue
+		}
+		seen[strings.ToLower(paraphrase)] = true
+		queries = append(queries, paraphrase)
+	}
+
+	logger.Info("Query rewritten",
+		zap.String("query", query),
+		zap.String("standalone_query", standalone),
+		zap.Int("paraphrases_count", len(queries)-1),
+	)
+
+	return queries, nil
+}
+
 // IsHealthy checks if the AI service is available
 func (c *Client) IsHealthy() bool {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
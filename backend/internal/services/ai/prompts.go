@@ -166,6 +166,8 @@ IMPORTANT INSTRUCTIONS:
 - Maintain academic rigor appropriate for tertiary education
 - Use clear, educational language
 - If the question cannot be answered from the context, explain what information is missing
+- Equations in the context are written in LaTeX between $ or $$ delimiters; reason over them as formulas, and write any formulas in your answer in LaTeX the same way
+- Tables in the context are written in Markdown; read values by their row and column headers
 
 Context from uploaded documents:
 %s
@@ -177,6 +179,93 @@ Provide a comprehensive answer based on the context above. If the context is ins
 	return prompt
 }
 
+// rerankPassageMaxLen caps each passage shown to the model when scoring
+const rerankPassageMaxLen = 1500
+
+// RerankPrompt generates a prompt asking for a relevance score per passage
+func RerankPrompt(query string, passages []string) string {
+	var passageBuilder strings.Builder
+
+	for i, passage := range passages {
+		if len(passage) > rerankPassageMaxLen {
+			passage = passage[:rerankPassageMaxLen] + "..."
+		}
+		passageBuilder.WriteString(fmt.Sprintf("[%d]\n%s\n\n", i+1, passage))
+	}
+
+	return fmt.Sprintf(`You are ranking study material passages by how useful they are for answering a student's question.
+
+Rate each passage from 0 to 10:
+- 10: directly answers the question
+- 5: relevant background, but does not answer it
+- 0: unrelated to the question
+
+Judge only relevance to the question, not writing quality. Exact matches of course codes, names and technical terms matter.
+
+Question: %s
+
+Passages:
+%s
+IMPORTANT: Return ONLY valid JSON. Do not wrap in markdown code blocks or backticks.
+
+Return one score per passage, in passage order, in this exact format:
+{
+  "scores": [7, 0, 10]
+}`, query, passageBuilder.String())
+}
+
+// rewriteTurnMaxLen caps each earlier message shown to the model when
+// rewriting a query; answers can be long and only their subject matters
+const rewriteTurnMaxLen = 800
+
+// QueryRewritePrompt generates a prompt asking for a standalone search query
+// for a follow-up question, plus up to paraphrases alternative phrasings
+func QueryRewritePrompt(history []ChatTurn, query string, paraphrases int) string {
+	var historyBuilder strings.Builder
+
+	for _, turn := range history {
+		content := turn.Content
+		if len(content) > rewriteTurnMaxLen {
+			content = content[:rewriteTurnMaxLen] + "..."
+		}
+		role := "Student"
+		if turn.Role == "assistant" {
+			role = "Tutor"
+		}
+		historyBuilder.WriteString(fmt.Sprintf("%s: %s\n", role, content))
+	}
+	if historyBuilder.Len() == 0 {
+		historyBuilder.WriteString("(no earlier messages)\n")
+	}
+
+	paraphraseInstruction := `- Return an empty "paraphrases" list`
+	if paraphrases > 0 {
+		paraphraseInstruction = fmt.Sprintf(`- Also write up to %d paraphrases of the standalone query that use different wording or terminology, to find passages phrased differently`, paraphrases)
+	}
+
+	return fmt.Sprintf(`You rewrite a student's latest question into a search query for their uploaded study materials.
+
+Conversation so far:
+%s
+Latest question: %s
+
+Requirements:
+- Resolve references such as "it", "that formula" or "the second one" using the conversation
+- The standalone query must make sense on its own, without the conversation
+- Keep course codes, names, formulas and technical terms exactly as written
+- If the question is already standalone, return it unchanged
+- Do not answer the question
+%s
+
+IMPORTANT: Return ONLY valid JSON. Do not wrap in markdown code blocks or backticks.
+
+Return in this exact format:
+{
+  "query": "Standalone search query",
+  "paraphrases": ["Alternative phrasing"]
+}`, historyBuilder.String(), query, paraphraseInstruction)
+}
+
 // BuildRAGContext creates a formatted context string from document chunks
 func BuildRAGContext(chunks []DocumentChunk) string {
 	if len(chunks) == 0 {
@@ -194,7 +283,11 @@ func BuildRAGContext(chunks []DocumentChunk) string {
 		if chunk.SourceURL != "" {
 			contextBuilder.WriteString(fmt.Sprintf("Source: %s\n", chunk.SourceURL))
 		}
-		contextBuilder.WriteString(fmt.Sprintf("Section %d: %s", chunk.Ordinal+1, chunk.Content))
+		if chunk.Location != "" {
+			contextBuilder.WriteString(fmt.Sprintf("Section %d (%s): %s", chunk.Ordinal+1, chunk.Location, chunk.Content))
+		} else {
+			contextBuilder.WriteString(fmt.Sprintf("Section %d: %s", chunk.Ordinal+1, chunk.Content))
+		}
 	}
 	
 	return contextBuilder.String()
@@ -206,5 +299,6 @@ type DocumentChunk struct {
 	DocumentTitle string
 	SourceURL     string
 	Ordinal       int
+	Location      string // e.g. "slide 14"; empty when unknown
 	Content       string
 }
\ No newline at end of file
@@ -23,10 +23,18 @@ type Usage struct {
 	TotalTokens      int `json:"total_tokens"`
 }
 
+// ChatTurn is an earlier message in a conversation
+type ChatTurn struct {
+	Role    string // user or assistant
+	Content string
+}
+
 // Service defines the AI service interface
 type Service interface {
 	GenerateQuiz(req *GeminiRequest) (*models.QuizResponse, error)
 	GenerateExplanation(req *GeminiRequest) (*models.ExplanationResponse, error)
+	ScoreRelevance(query string, passages []string) ([]float64, error)
+	RewriteQuery(history []ChatTurn, query string, paraphrases int) ([]string, error)
 	IsHealthy() bool
 }
 
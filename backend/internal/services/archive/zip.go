@@ -0,0 +1,157 @@
+package archive
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+var (
+	// ErrTooManyFiles is returned when an archive holds more files than allowed
+	ErrTooManyFiles = errors.New("archive contains too many files")
+	// ErrTooLarge is returned when the expanded content exceeds the limits
+	ErrTooLarge = errors.New("archive content is too large")
+	// ErrSuspicious is returned for archives that look like zip bombs or whose
+	// headers don't match their content
+	ErrSuspicious = errors.New("archive looks malformed or malicious")
+	// ErrInvalidPath is returned for entries with absolute or escaping paths
+	ErrInvalidPath = errors.New("archive contains an invalid path")
+)
+
+// Limits bounds what an archive may expand to
+type Limits struct {
+	MaxFiles     int     // supported files, after skipping the rest
+	MaxFileSize  int64   // uncompressed size of a single file
+	MaxTotalSize int64   // uncompressed size of all supported files
+	MaxRatio     float64 // uncompressed to compressed size of a single file
+}
+
+// Entry is an expanded file. File is removed once the callback returns.
+type Entry struct {
+	Path string // path inside the archive
+	Name string // base name
+	Size int64
+	File *os.File
+}
+
+// Skipped records an archive entry that was not expanded
+type Skipped struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// ExpandZip validates a ZIP archive and calls fn with each file whose name
+// supported accepts, one at a time. Files are never written under their
+// archive path, and the declared sizes are checked before anything is
+// expanded and again against the bytes actually read.
+func ExpandZip(r io.ReaderAt, size int64, limits Limits, supported func(name string) bool, fn func(Entry) error) ([]Skipped, error) {
+	archive, err := zip.NewReader(r, size)
+	if errors.Is(err, zip.ErrInsecurePath) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPath, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ZIP archive: %w", err)
+	}
+
+	// Validate the whole archive up front so nothing is ingested from one
+	// that will be rejected
+	var files []*zip.File
+	var skipped []Skipped
+	var declaredTotal uint64
+	for _, f := range archive.File {
+		if err := checkPath(f.Name); err != nil {
+			return nil, err
+		}
+		if f.FileInfo().IsDir() || isJunk(f.Name) {
+			continue
+		}
+		if !supported(path.Base(f.Name)) {
+			skipped = append(skipped, Skipped{Path: f.Name, Reason: "unsupported file type"})
+			continue
+		}
+		if f.UncompressedSize64 > uint64(limits.MaxFileSize) {
+			skipped = append(skipped, Skipped{Path: f.Name, Reason: "file is too large"})
+			continue
+		}
+		if f.CompressedSize64 > 0 && float64(f.UncompressedSize64)/float64(f.CompressedSize64) > limits.MaxRatio {
+			return nil, fmt.Errorf("%w: %s has a compression ratio above %.0f", ErrSuspicious, f.Name, limits.MaxRatio)
+		}
+
+		declaredTotal += f.UncompressedSize64
+		files = append(files, f)
+	}
+
+	if len(files) > limits.MaxFiles {
+		return nil, fmt.Errorf("%w: %d files, limit is %d", ErrTooManyFiles, len(files), limits.MaxFiles)
+	}
+	if declaredTotal > uint64(limits.MaxTotalSize) {
+		return nil, fmt.Errorf("%w: %d bytes, limit is %d", ErrTooLarge, declaredTotal, limits.MaxTotalSize)
+	}
+
+	for _, f := range files {
+		if err := expandFile(f, fn); err != nil {
+			return skipped, err
+		}
+	}
+
+	return skipped, nil
+}
+
+// expandFile copies one entry to a temp file and passes it to fn
+func expandFile(f *zip.File, fn func(Entry) error) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	tmp, err := os.CreateTemp("", "edupro-archive-*"+path.Ext(f.Name))
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	// Read at most one byte more than declared; a header that understates
+	// the size is a classic zip bomb
+	written, err := io.Copy(tmp, io.LimitReader(rc, int64(f.UncompressedSize64)+1))
+	if err != nil {
+		return fmt.Errorf("failed to expand %s: %w", f.Name, err)
+	}
+	if written != int64(f.UncompressedSize64) {
+		return fmt.Errorf("%w: %s size does not match its header", ErrSuspicious, f.Name)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read %s: %w", f.Name, err)
+	}
+
+	return fn(Entry{
+		Path: f.Name,
+		Name: path.Base(f.Name),
+		Size: written,
+		File: tmp,
+	})
+}
+
+// checkPath rejects absolute paths and paths that climb out of the archive
+func checkPath(name string) error {
+	if strings.Contains(name, "\\") || strings.HasPrefix(name, "/") || strings.ContainsRune(name, 0) {
+		return fmt.Errorf("%w: %q", ErrInvalidPath, name)
+	}
+	for _, segment := range strings.Split(name, "/") {
+		if segment == ".." {
+			return fmt.Errorf("%w: %q", ErrInvalidPath, name)
+		}
+	}
+	return nil
+}
+
+// isJunk reports files added by archivers and operating systems
+func isJunk(name string) bool {
+	base := path.Base(name)
+	return strings.HasPrefix(name, "__MACOSX/") || strings.HasPrefix(base, ".") || base == "Thumbs.db"
+}
@@ -0,0 +1,226 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testFile is an archive entry; store keeps it uncompressed
+type testFile struct {
+	name    string
+	content string
+	store   bool
+}
+
+// zipOf builds a ZIP archive from files
+func zipOf(t *testing.T, files ...testFile) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	w := zip.NewWriter(&b)
+	for _, file := range files {
+		method := zip.Deflate
+		if file.store {
+			method = zip.Store
+		}
+		f, err := w.CreateHeader(&zip.FileHeader{Name: file.name, Method: method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(file.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+var testLimits = Limits{MaxFiles: 3, MaxFileSize: 1024, MaxTotalSize: 2048, MaxRatio: 100}
+
+// supportedDocuments accepts the document types ingestion handles
+func supportedDocuments(name string) bool {
+	switch path.Ext(name) {
+	case ".pdf", ".txt", ".docx":
+		return true
+	}
+	return false
+}
+
+// expand runs ExpandZip over content, returning the paths and contents it passed on
+func expand(t *testing.T, content []byte, limits Limits) ([]string, map[string]string, []Skipped, error) {
+	t.Helper()
+	var paths []string
+	contents := make(map[string]string)
+	skipped, err := ExpandZip(bytes.NewReader(content), int64(len(content)), limits, supportedDocuments, func(entry Entry) error {
+		data, err := io.ReadAll(entry.File)
+		if err != nil {
+			return err
+		}
+		if entry.Name != path.Base(entry.Path) || entry.Size != int64(len(data)) {
+			t.Errorf("entry %+v doesn't describe its %d bytes", entry, len(data))
+		}
+		paths = append(paths, entry.Path)
+		contents[entry.Path] = string(data)
+		return nil
+	})
+	return paths, contents, skipped, err
+}
+
+func TestExpandZip(t *testing.T) {
+	content := zipOf(t,
+		testFile{name: "week1/notes.txt", content: "Heat flows from hot to cold."},
+		testFile{name: "week1/", content: ""},
+		testFile{name: "week1/figure.png", content: "\x89PNG"},
+		testFile{name: "__MACOSX/week1/._notes.txt", content: "resource fork"},
+		testFile{name: "week1/.DS_Store", content: "junk"},
+		testFile{name: "week2/slides.docx", content: strings.Repeat("x", 2000), store: true},
+		testFile{name: "week2/summary.pdf", content: "%PDF-1.4", store: true},
+	)
+
+	paths, contents, skipped, err := expand(t, content, testLimits)
+	if err != nil {
+		t.Fatalf("ExpandZip() error = %v", err)
+	}
+	if want := []string{"week1/notes.txt", "week2/summary.pdf"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("expanded %v, want %v", paths, want)
+	}
+	if contents["week1/notes.txt"] != "Heat flows from hot to cold." {
+		t.Errorf("notes.txt = %q", contents["week1/notes.txt"])
+	}
+
+	wantSkipped := []Skipped{
+		{Path: "week1/figure.png", Reason: "unsupported file type"},
+		{Path: "week2/slides.docx", Reason: "file is too large"},
+	}
+	if !reflect.DeepEqual(skipped, wantSkipped) {
+		t.Errorf("skipped = %+v, want %+v", skipped, wantSkipped)
+	}
+}
+
+func TestExpandZipRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   []testFile
+		limits  Limits
+		wantErr error
+	}{
+		{
+			"too many files",
+			[]testFile{{name: "a.txt", content: "a"}, {name: "b.txt", content: "b"}, {name: "c.txt", content: "c"}, {name: "d.txt", content: "d"}},
+			testLimits,
+			ErrTooManyFiles,
+		},
+		{
+			"unsupported files don't count",
+			[]testFile{{name: "a.txt", content: "a"}, {name: "b.png", content: "b"}, {name: "c.png", content: "c"}, {name: "d.png", content: "d"}},
+			testLimits,
+			nil,
+		},
+		{
+			"too large in total",
+			[]testFile{{name: "a.txt", content: strings.Repeat("a", 1000), store: true}, {name: "b.txt", content: strings.Repeat("b", 1000), store: true}, {name: "c.txt", content: strings.Repeat("c", 1000), store: true}},
+			testLimits,
+			ErrTooLarge,
+		},
+		{
+			"compression ratio",
+			[]testFile{{name: "bomb.txt", content: strings.Repeat("\x00", 1000)}},
+			Limits{MaxFiles: 3, MaxFileSize: 1024, MaxTotalSize: 2048, MaxRatio: 10},
+			ErrSuspicious,
+		},
+		{
+			"ratio within limit",
+			[]testFile{{name: "notes.txt", content: strings.Repeat("\x00", 1000)}},
+			Limits{MaxFiles: 3, MaxFileSize: 1024, MaxTotalSize: 2048, MaxRatio: 1000},
+			nil,
+		},
+		{"parent directory", []testFile{{name: "../etc/passwd.txt", content: "x"}}, testLimits, ErrInvalidPath},
+		{"nested parent directory", []testFile{{name: "notes/../../x.txt", content: "x"}}, testLimits, ErrInvalidPath},
+		{"absolute path", []testFile{{name: "/tmp/x.txt", content: "x"}}, testLimits, ErrInvalidPath},
+		{"backslashes", []testFile{{name: `notes\..\x.txt`, content: "x"}}, testLimits, ErrInvalidPath},
+		{"invalid path in a skipped file", []testFile{{name: "../evil.png", content: "x"}}, testLimits, ErrInvalidPath},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths, _, _, err := expand(t, zipOf(t, tt.files...), tt.limits)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("ExpandZip() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ExpandZip() error = %v, want %v", err, tt.wantErr)
+			}
+			// Nothing is ingested from a rejected archive
+			if len(paths) != 0 {
+				t.Errorf("expanded %v from a rejected archive", paths)
+			}
+		})
+	}
+}
+
+func TestExpandZipUnderstatedSize(t *testing.T) {
+	// The header claims 10 bytes but the entry inflates to far more
+	var compressed bytes.Buffer
+	fw, err := flate.NewWriter(&compressed, flate.BestCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := strings.Repeat("abcdefghij", 50)
+	fw.Write([]byte(content))
+	fw.Close()
+
+	var b bytes.Buffer
+	w := zip.NewWriter(&b)
+	f, err := w.CreateRaw(&zip.FileHeader{
+		Name:               "bomb.txt",
+		Method:             zip.Deflate,
+		CompressedSize64:   uint64(compressed.Len()),
+		UncompressedSize64: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write(compressed.Bytes())
+	w.Close()
+
+	// Reading stops at the declared size, so the extra bytes never reach disk
+	paths, _, _, err := expand(t, b.Bytes(), testLimits)
+	if err == nil {
+		t.Fatal("ExpandZip() accepted an entry larger than its header")
+	}
+	if len(paths) != 0 {
+		t.Errorf("expanded %v", paths)
+	}
+}
+
+func TestExpandZipCallbackError(t *testing.T) {
+	content := zipOf(t, testFile{name: "a.txt", content: "a"}, testFile{name: "b.txt", content: "b"})
+	stop := errors.New("storage is full")
+
+	calls := 0
+	_, err := ExpandZip(bytes.NewReader(content), int64(len(content)), testLimits, supportedDocuments, func(Entry) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("ExpandZip() = %v after %d calls, want to stop after the first", err, calls)
+	}
+}
+
+func TestExpandZipNotAnArchive(t *testing.T) {
+	content := []byte("plain text, not a zip")
+	if _, _, _, err := expand(t, content, testLimits); err == nil {
+		t.Error("ExpandZip() accepted a non-ZIP file")
+	}
+}
@@ -3,8 +3,10 @@ package chunker
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/kinyichukwu/edu-pro-backend/internal/utils"
 	"go.uber.org/zap"
@@ -15,62 +17,161 @@ type Client struct {
 	chunkSize    int
 	overlapSize  int
 	minChunkSize int
+
+	tokenizer       Tokenizer
+	strategies      map[string]Strategy
+	typeStrategies  map[string]string // file type -> strategy name
+	defaultStrategy string
 }
 
 // NewClient creates a new chunker client
 func NewClient() *Client {
-	return &Client{
-		chunkSize:    1000, // Target chunk size in tokens (approximately)
+	c := &Client{
+		chunkSize:    1000, // Target chunk size in tokens
 		overlapSize:  100,  // Overlap between chunks
 		minChunkSize: 100,  // Minimum chunk size
+		tokenizer:    EstimateTokenizer{},
+		strategies:   make(map[string]Strategy),
+		typeStrategies: map[string]string{
+			// Word documents and plain text carry headings and lists
+			".docx": StrategyStructure,
+			".txt":  StrategyStructure,
+			".md":   StrategyStructure,
+			".html": StrategyStructure,
+			".htm":  StrategyStructure,
+		},
+		defaultStrategy: StrategyToken,
 	}
+	c.registerDefaultStrategies()
+	return c
 }
 
-// ChunkText splits text into overlapping chunks
+// ChunkText splits text into chunks using the strategy for the document's file type
 func (c *Client) ChunkText(text string, metadata map[string]interface{}) ([]Chunk, error) {
 	logger := utils.GetLogger()
 
+	strategy := c.strategyFor(metadata)
+	chunks, err := strategy.Chunk(text, metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Text chunked successfully",
+		zap.String("strategy", strategy.Name()),
+		zap.Int("original_length", len(text)),
+		zap.Int("chunks_created", len(chunks)),
+		zap.Int("chunk_size_target", c.chunkSize),
+	)
+
+	markMath(chunks)
+	return chunks, nil
+}
+
+// ChunkSections chunks each section separately so no chunk spans two
+// sections, and merges each section's metadata into its chunks. Ordinals
+// are numbered continuously across sections. Sections marked as tables
+// (chunk_type "table") become chunks of their own.
+func (c *Client) ChunkSections(sections []Section, metadata map[string]interface{}) ([]Chunk, error) {
+	logger := utils.GetLogger()
+
+	strategy := c.strategyFor(metadata)
+
+	var chunks []Chunk
+	totalLength := 0
+	for _, section := range sections {
+		if strings.TrimSpace(section.Text) == "" {
+			continue
+		}
+		totalLength += len(section.Text)
+
+		// Tables stay whole whatever the strategy
+		var sectionChunks []Chunk
+		if isTable(section.Metadata) {
+			sectionChunks = chunkTable(section.Text, mergeMetadata(metadata, section.Metadata), c.tokenizer, c.chunkSize)
+		} else {
+			var err error
+			sectionChunks, err = strategy.Chunk(section.Text, mergeMetadata(metadata, section.Metadata))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, chunk := range sectionChunks {
+			chunk.Ordinal = len(chunks)
+			shiftOffsets(chunk.Metadata, section.Offset)
+			chunks = append(chunks, chunk)
+		}
+	}
+
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("no content found in sections")
+	}
+
+	logger.Info("Sections chunked successfully",
+		zap.String("strategy", strategy.Name()),
+		zap.Int("sections", len(sections)),
+		zap.Int("original_length", totalLength),
+		zap.Int("chunks_created", len(chunks)),
+		zap.Int("chunk_size_target", c.chunkSize),
+	)
+
+	markMath(chunks)
+	return chunks, nil
+}
+
+// chunk splits a single piece of text into overlapping chunks using the
+// original character-estimate sentence packer
+func (c *Client) chunk(text string, metadata map[string]interface{}) ([]Chunk, error) {
 	if text == "" {
 		return nil, fmt.Errorf("text cannot be empty")
 	}
 
-	// Clean and normalize the text
-	cleanedText := c.cleanText(text)
+	// Clean and normalize the text; offsets are reported in the original
+	cleaned := cleanText(text)
+	cleanedText := cleaned.text
 	if len(cleanedText) < c.minChunkSize {
 		// If text is too small, return as single chunk
+		charStart, charEnd := cleaned.sourceSpan(0, len(cleanedText))
 		return []Chunk{{
-			Content:  cleanedText,
-			Ordinal:  0,
-			Metadata: metadata,
+			Content: cleanedText,
+			Ordinal: 0,
+			Metadata: mergeMetadata(metadata, map[string]interface{}{
+				"char_start": charStart,
+				"char_end":   charEnd,
+			}),
 		}}, nil
 	}
 
 	// Split text into sentences first
-	sentences := c.splitIntoSentences(cleanedText)
+	sentences := splitIntoSentences(cleanedText)
 	if len(sentences) == 0 {
 		return nil, fmt.Errorf("no sentences found in text")
 	}
 
 	var chunks []Chunk
 	var currentChunk strings.Builder
-	var currentSentences []string
+	var currentSentences []sentenceSpan
+	firstSentence := 0
 	ordinal := 0
 
 	for i, sentence := range sentences {
 		// Estimate token count (rough approximation: 1 token ≈ 4 characters)
-		potentialLength := currentChunk.Len() + len(sentence)
+		potentialLength := currentChunk.Len() + len(sentence.Text)
 		estimatedTokens := potentialLength / 4
 
 		// If adding this sentence would exceed chunk size, create a chunk
 		if estimatedTokens > c.chunkSize && currentChunk.Len() > 0 {
 			chunkContent := strings.TrimSpace(currentChunk.String())
 			if len(chunkContent) >= c.minChunkSize {
+				charStart, charEnd := cleaned.sourceSpan(currentSentences[0].Start, currentSentences[len(currentSentences)-1].End)
 				chunks = append(chunks, Chunk{
 					Content: chunkContent,
 					Ordinal: ordinal,
-					Metadata: c.mergeMetadata(metadata, map[string]interface{}{
-						"sentence_start": len(chunks) * (c.chunkSize - c.overlapSize) / 4,
+					Metadata: mergeMetadata(metadata, map[string]interface{}{
+						"sentence_start": firstSentence,
 						"sentence_count": len(currentSentences),
+						"char_start":     charStart,
+						"char_end":       charEnd,
 					}),
 				})
 				ordinal++
@@ -78,23 +179,24 @@ func (c *Client) ChunkText(text string, metadata map[string]interface{}) ([]Chun
 
 			// Start new chunk with overlap
 			currentChunk.Reset()
-			currentSentences = []string{}
+			currentSentences = []sentenceSpan{}
 
 			// Add overlap from previous chunk
 			overlapTokens := c.overlapSize / 4 // Rough token estimate
 			overlapSentences := c.getLastSentences(sentences[:i], overlapTokens)
 			for _, overlapSentence := range overlapSentences {
-				currentChunk.WriteString(overlapSentence)
+				currentChunk.WriteString(overlapSentence.Text)
 				currentChunk.WriteString(" ")
 				currentSentences = append(currentSentences, overlapSentence)
 			}
+			firstSentence = i - len(overlapSentences)
 		}
 
 		// Add current sentence
 		if currentChunk.Len() > 0 {
 			currentChunk.WriteString(" ")
 		}
-		currentChunk.WriteString(sentence)
+		currentChunk.WriteString(sentence.Text)
 		currentSentences = append(currentSentences, sentence)
 	}
 
@@ -102,92 +204,198 @@ func (c *Client) ChunkText(text string, metadata map[string]interface{}) ([]Chun
 	if currentChunk.Len() > 0 {
 		chunkContent := strings.TrimSpace(currentChunk.String())
 		if len(chunkContent) >= c.minChunkSize {
+			charStart, charEnd := cleaned.sourceSpan(currentSentences[0].Start, currentSentences[len(currentSentences)-1].End)
 			chunks = append(chunks, Chunk{
 				Content: chunkContent,
 				Ordinal: ordinal,
-				Metadata: c.mergeMetadata(metadata, map[string]interface{}{
-					"sentence_start": ordinal * (c.chunkSize - c.overlapSize) / 4,
+				Metadata: mergeMetadata(metadata, map[string]interface{}{
+					"sentence_start": firstSentence,
 					"sentence_count": len(currentSentences),
+					"char_start":     charStart,
+					"char_end":       charEnd,
 				}),
 			})
 		}
 	}
 
-	logger.Info("Text chunked successfully",
-		zap.Int("original_length", len(text)),
-		zap.Int("chunks_created", len(chunks)),
-		zap.Int("chunk_size_target", c.chunkSize),
-	)
-
 	return chunks, nil
 }
 
-// cleanText normalizes and cleans the input text
-func (c *Client) cleanText(text string) string {
-	// Remove excessive whitespace
-	text = regexp.MustCompile(`\s+`).ReplaceAllString(text, " ")
+// cleanText normalizes and cleans the input text, collapsing whitespace
+// runs to a single space and dropping control characters. Equations are
+// kept verbatim, as their spacing and line breaks can carry meaning.
+func cleanText(text string) mappedText {
+	var b mappedBuilder
+	spans := mathSpans(text)
+
+	// Whitespace is written lazily so runs collapse and none is left at
+	// either end; pendingSpace is where the current run started
+	pendingSpace := -1
+	for i := 0; i < len(text); {
+		if len(spans) > 0 && spans[0].Start == i {
+			if pendingSpace >= 0 && b.len() > 0 {
+				b.write(" ", pendingSpace)
+			}
+			pendingSpace = -1
+			b.write(text[spans[0].Start:spans[0].End], spans[0].Start)
+			i = spans[0].End
+			spans = spans[1:]
+			continue
+		}
 
-	// Remove control characters except newlines and tabs
-	text = strings.Map(func(r rune) rune {
-		if unicode.IsControl(r) && r != '\n' && r != '\t' {
-			return -1
+		r, size := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f':
+			if pendingSpace < 0 {
+				pendingSpace = i
+			}
+		case unicode.IsControl(r):
+			// Dropped
+		default:
+			if pendingSpace >= 0 && b.len() > 0 {
+				b.write(" ", pendingSpace)
+			}
+			pendingSpace = -1
+			b.write(text[i:i+size], i)
 		}
-		return r
-	}, text)
+		i += size
+	}
+
+	return b.mapped()
+}
+
+// mappedText is text rewritten for chunking that remembers where each of
+// its bytes came from, so chunk offsets can point into the original text
+type mappedText struct {
+	text string
+	// Each run starts a stretch of text copied from one place in the
+	// original, so the map stays small when little is rewritten
+	runs []textRun
+}
+
+// textRun maps text from byte at onwards to the original from source onwards
+type textRun struct {
+	at     int
+	source int
+}
+
+// sourceOffset maps a byte of the rewritten text to the original text
+func (m mappedText) sourceOffset(i int) int {
+	run := sort.Search(len(m.runs), func(j int) bool { return m.runs[j].at > i }) - 1
+	if run < 0 {
+		return i
+	}
+	return m.runs[run].source + i - m.runs[run].at
+}
+
+// sourceSpan maps a byte range of the rewritten text to the original text
+func (m mappedText) sourceSpan(start, end int) (int, int) {
+	if start >= end {
+		return m.sourceOffset(start), m.sourceOffset(start)
+	}
+	return m.sourceOffset(start), m.sourceOffset(end-1) + 1
+}
 
-	// Normalize line breaks
-	text = regexp.MustCompile(`\r\n|\r`).ReplaceAllString(text, "\n")
+// mappedBuilder builds a mappedText
+type mappedBuilder struct {
+	b    strings.Builder
+	runs []textRun
+}
 
-	// Remove excessive line breaks
-	text = regexp.MustCompile(`\n{3,}`).ReplaceAllString(text, "\n\n")
+// write appends s, which starts at offset at in the original text
+func (m *mappedBuilder) write(s string, at int) {
+	if n := len(m.runs); n == 0 || m.runs[n-1].source+m.b.Len()-m.runs[n-1].at != at {
+		m.runs = append(m.runs, textRun{at: m.b.Len(), source: at})
+	}
+	m.b.WriteString(s)
+}
+
+func (m *mappedBuilder) len() int {
+	return m.b.Len()
+}
 
-	return strings.TrimSpace(text)
+func (m *mappedBuilder) mapped() mappedText {
+	return mappedText{text: m.b.String(), runs: m.runs}
+}
+
+// sentenceSpan is a sentence together with its byte offsets in the text it was split from
+type sentenceSpan struct {
+	Text  string
+	Start int
+	End   int
 }
 
 // splitIntoSentences splits text into sentences using simple heuristics
-func (c *Client) splitIntoSentences(text string) []string {
+func splitIntoSentences(text string) []sentenceSpan {
 	// Simple sentence splitting regex
 	// This is a basic implementation - for production, consider using a proper NLP library
 	sentenceRegex := regexp.MustCompile(`[.!?]+\s+`)
 
-	sentences := sentenceRegex.Split(text, -1)
-
-	var result []string
-	for _, sentence := range sentences {
-		sentence = strings.TrimSpace(sentence)
-		if len(sentence) > 10 { // Minimum sentence length
-			result = append(result, sentence)
+	// Walk the separators so each sentence keeps its position in the text
+	var result []sentenceSpan
+	start := 0
+	math := mathSpans(text)
+	bounds := append(sentenceRegex.FindAllStringIndex(text, -1), []int{len(text), len(text)})
+	for _, bound := range bounds {
+		// Punctuation inside an equation doesn't end a sentence
+		if insideSpan(math, bound[0]) {
+			continue
+		}
+		// Keep the terminating punctuation with its sentence
+		punctuation := len(strings.TrimRightFunc(text[bound[0]:bound[1]], unicode.IsSpace))
+		raw := text[start : bound[0]+punctuation]
+		trimmed := strings.TrimSpace(raw)
+		if len(trimmed) > 10 { // Minimum sentence length
+			offset := start + strings.Index(raw, trimmed)
+			result = append(result, sentenceSpan{
+				Text:  trimmed,
+				Start: offset,
+				End:   offset + len(trimmed),
+			})
 		}
+		start = bound[1]
 	}
 
 	return result
 }
 
 // getLastSentences returns the last N sentences that fit within the token limit
-func (c *Client) getLastSentences(sentences []string, maxTokens int) []string {
+func (c *Client) getLastSentences(sentences []sentenceSpan, maxTokens int) []sentenceSpan {
 	if len(sentences) == 0 {
-		return []string{}
+		return []sentenceSpan{}
 	}
 
-	var result []string
+	var result []sentenceSpan
 	currentTokens := 0
 
 	// Start from the end and work backwards
 	for i := len(sentences) - 1; i >= 0; i-- {
-		sentenceTokens := len(sentences[i]) / 4 // Rough token estimate
+		sentenceTokens := len(sentences[i].Text) / 4 // Rough token estimate
 		if currentTokens+sentenceTokens > maxTokens {
 			break
 		}
 
-		result = append([]string{sentences[i]}, result...)
+		result = append([]sentenceSpan{sentences[i]}, result...)
 		currentTokens += sentenceTokens
 	}
 
 	return result
 }
 
+// shiftOffsets moves a chunk's char_start and char_end by offset
+func shiftOffsets(metadata map[string]interface{}, offset int) {
+	if offset == 0 {
+		return
+	}
+	for _, key := range []string{"char_start", "char_end"} {
+		if value, ok := metadata[key].(int); ok {
+			metadata[key] = value + offset
+		}
+	}
+}
+
 // mergeMetadata merges base metadata with additional metadata
-func (c *Client) mergeMetadata(base map[string]interface{}, additional map[string]interface{}) map[string]interface{} {
+func mergeMetadata(base map[string]interface{}, additional map[string]interface{}) map[string]interface{} {
 	result := make(map[string]interface{})
 
 	// Copy base metadata
@@ -207,6 +415,7 @@ func (c *Client) mergeMetadata(base map[string]interface{}, additional map[strin
 func (c *Client) SetChunkSize(size int) {
 	if size > 0 {
 		c.chunkSize = size
+		c.registerDefaultStrategies()
 	}
 }
 
@@ -214,9 +423,21 @@ func (c *Client) SetChunkSize(size int) {
 func (c *Client) SetOverlapSize(size int) {
 	if size >= 0 && size < c.chunkSize {
 		c.overlapSize = size
+		c.registerDefaultStrategies()
 	}
 }
 
+// Section represents a located part of a document, such as a page or a
+// slide. Chunk char_start/char_end offsets are byte offsets into the
+// extracted text of the page or slide the section belongs to, or of the
+// whole document when it has neither, whatever the chunking strategy.
+type Section struct {
+	Text     string                 `json:"text"`
+	Metadata map[string]interface{} `json:"metadata"`
+	// Offset is where Text starts in that page, slide or document
+	Offset int `json:"offset,omitempty"`
+}
+
 // Chunk represents a text chunk
 type Chunk struct {
 	Content  string                 `json:"content"`
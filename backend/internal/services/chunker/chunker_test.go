@@ -0,0 +1,141 @@
+package chunker
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// sampleText returns prose with the irregular spacing extraction produces:
+// CRLF line breaks, runs of spaces and tabs, and stray control characters
+func sampleText(paragraphs int) string {
+	var b strings.Builder
+	b.WriteString("# Thermal Physics\r\n\r\n")
+	for i := 0; i < paragraphs; i++ {
+		fmt.Fprintf(&b, "Paragraph %d explains heat  transfer\tby conduction.\x00 ", i)
+		fmt.Fprintf(&b, "It then covers   convection and radiation in turn.\r\n")
+		fmt.Fprintf(&b, "Each mode is illustrated with a worked example.\r\n\r\n")
+	}
+	return b.String()
+}
+
+// normalizeSpace collapses whitespace and drops control characters, which
+// is all cleaning is allowed to change
+func normalizeSpace(text string) string {
+	text = strings.Map(func(r rune) rune {
+		if r < 0x20 && r != '\n' && r != '\r' && r != '\t' {
+			return -1
+		}
+		return r
+	}, text)
+	return strings.Join(strings.Fields(text), " ")
+}
+
+func TestChunkOffsetsPointIntoSource(t *testing.T) {
+	client := NewClient()
+	client.SetChunkSize(40)
+	client.SetOverlapSize(10)
+	client.minChunkSize = 10
+
+	text := sampleText(12)
+	for _, name := range []string{StrategySentence, StrategyToken, StrategyStructure} {
+		t.Run(name, func(t *testing.T) {
+			chunks, err := client.strategies[name].Chunk(text, nil)
+			if err != nil {
+				t.Fatalf("Chunk() error = %v", err)
+			}
+			if len(chunks) < 2 {
+				t.Fatalf("got %d chunks, want several", len(chunks))
+			}
+
+			for _, chunk := range chunks {
+				start, startOK := chunk.Metadata["char_start"].(int)
+				end, endOK := chunk.Metadata["char_end"].(int)
+				if !startOK || !endOK {
+					t.Fatalf("chunk %d has no offsets: %v", chunk.Ordinal, chunk.Metadata)
+				}
+				if start < 0 || end > len(text) || start >= end {
+					t.Fatalf("chunk %d offsets [%d, %d) out of range", chunk.Ordinal, start, end)
+				}
+				if got, want := normalizeSpace(text[start:end]), normalizeSpace(chunk.Content); got != want {
+					t.Errorf("chunk %d: source[%d:%d] = %q, content %q", chunk.Ordinal, start, end, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestChunkSectionsShiftsOffsets(t *testing.T) {
+	client := NewClient()
+	if err := client.SetStrategyForType("default", StrategyToken); err != nil {
+		t.Fatal(err)
+	}
+
+	page := "Heat flows   from hot to cold. Entropy never decreases in an isolated system."
+	prefix := "| a | b |\n| --- | --- |\n| 1 | 2 |\n"
+	sections := []Section{
+		{Text: prefix, Metadata: map[string]interface{}{"page": 1, "chunk_type": ChunkTypeTable}},
+		{Text: page, Metadata: map[string]interface{}{"page": 1}, Offset: len(prefix)},
+	}
+
+	chunks, err := client.ChunkSections(sections, nil)
+	if err != nil {
+		t.Fatalf("ChunkSections() error = %v", err)
+	}
+	if len(chunks) != 2 {
+		t.Fatalf("got %d chunks, want 2", len(chunks))
+	}
+
+	full := prefix + page
+	start := chunks[1].Metadata["char_start"].(int)
+	end := chunks[1].Metadata["char_end"].(int)
+	if got, want := normalizeSpace(full[start:end]), normalizeSpace(chunks[1].Content); got != want {
+		t.Errorf("page[%d:%d] = %q, content %q", start, end, got, want)
+	}
+	if chunks[1].Ordinal != 1 {
+		t.Errorf("ordinal = %d, want 1", chunks[1].Ordinal)
+	}
+}
+
+func TestCleanText(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"collapses whitespace", "  a \t\r\n b  ", "a b"},
+		{"drops control characters", "a\x00b\x07 c", "ab c"},
+		{"keeps equations verbatim", "see $$x  =\n y$$  now", "see $$x  =\n y$$ now"},
+		{"equation at the start", "$$a$$   b", "$$a$$ b"},
+		{"empty", " \n\t ", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cleaned := cleanText(tt.text)
+			if cleaned.text != tt.want {
+				t.Fatalf("cleanText(%q) = %q, want %q", tt.text, cleaned.text, tt.want)
+			}
+			// Every byte that was kept maps back to the same byte
+			for i := 0; i < len(cleaned.text); i++ {
+				source := cleaned.sourceOffset(i)
+				if cleaned.text[i] != ' ' && tt.text[source] != cleaned.text[i] {
+					t.Errorf("byte %d maps to %d: %q != %q", i, source, tt.text[source], cleaned.text[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizeLineBreaks(t *testing.T) {
+	text := "a\r\nb\rc\n"
+	normalized := normalizeLineBreaks(text)
+	if normalized.text != "a\nb\nc\n" {
+		t.Fatalf("normalizeLineBreaks(%q) = %q", text, normalized.text)
+	}
+
+	start, end := normalized.sourceSpan(2, 5)
+	if got := text[start:end]; got != "b\rc" {
+		t.Errorf("sourceSpan(2, 5) = %q, want %q", got, "b\rc")
+	}
+}
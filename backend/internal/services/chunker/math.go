@@ -0,0 +1,156 @@
+package chunker
+
+import (
+	"strings"
+)
+
+// maxInlineMathLength bounds an inline $...$ span, so a stray dollar sign
+// can't swallow a paragraph
+const maxInlineMathLength = 300
+
+// displayMathDelimiters are the opening and closing delimiters of display
+// and bracketed inline math
+var displayMathDelimiters = [][2]string{
+	{"$$", "$$"},
+	{`\[`, `\]`},
+	{`\(`, `\)`},
+}
+
+// mathSpans finds the LaTeX equations in text: $$...$$, \[...\], \(...\),
+// \begin{...}...\end{...} environments and inline $...$. Inline spans follow
+// Pandoc's rule, so prices such as "$5 and $10" aren't mistaken for math.
+func mathSpans(text string) []textSpan {
+	var spans []textSpan
+
+	for i := 0; i < len(text); {
+		rest := text[i:]
+
+		// Escaped dollar signs are literal
+		if strings.HasPrefix(rest, `\$`) {
+			i += 2
+			continue
+		}
+
+		if end := displayMathEnd(rest); end > 0 {
+			spans = append(spans, textSpan{Start: i, End: i + end})
+			i += end
+			continue
+		}
+
+		if strings.HasPrefix(rest, `\begin{`) {
+			if end := environmentEnd(rest); end > 0 {
+				spans = append(spans, textSpan{Start: i, End: i + end})
+				i += end
+				continue
+			}
+		}
+
+		if rest[0] == '$' {
+			if end := inlineMathEnd(rest); end > 0 {
+				spans = append(spans, textSpan{Start: i, End: i + end})
+				i += end
+				continue
+			}
+		}
+
+		i++
+	}
+
+	return spans
+}
+
+// displayMathEnd returns the length of a delimited span at the start of
+// text, or 0 when there is none
+func displayMathEnd(text string) int {
+	for _, delimiters := range displayMathDelimiters {
+		if !strings.HasPrefix(text, delimiters[0]) {
+			continue
+		}
+		if end := strings.Index(text[len(delimiters[0]):], delimiters[1]); end > 0 {
+			return len(delimiters[0]) + end + len(delimiters[1])
+		}
+		return 0
+	}
+	return 0
+}
+
+// environmentEnd returns the length of the \begin{name}...\end{name}
+// environment at the start of text, allowing nested environments of the
+// same name, or 0 when it isn't closed
+func environmentEnd(text string) int {
+	closing := strings.Index(text, "}")
+	if closing < 0 {
+		return 0
+	}
+	name := text[len(`\begin{`):closing]
+	begin, end := `\begin{`+name+"}", `\end{`+name+"}"
+
+	depth := 0
+	for i := 0; i < len(text); {
+		switch {
+		case strings.HasPrefix(text[i:], begin):
+			depth++
+			i += len(begin)
+		case strings.HasPrefix(text[i:], end):
+			depth--
+			i += len(end)
+			if depth == 0 {
+				return i
+			}
+		default:
+			i++
+		}
+	}
+	return 0
+}
+
+// inlineMathEnd returns the length of the $...$ span at the start of text,
+// or 0. The opening $ must be followed by a non-space, and the closing $
+// preceded by a non-space and not followed by a digit.
+func inlineMathEnd(text string) int {
+	if len(text) < 3 || text[1] == ' ' || text[1] == '\n' || text[1] == '$' {
+		return 0
+	}
+	for i := 2; i < len(text) && i <= maxInlineMathLength; i++ {
+		switch text[i] {
+		case '\n':
+			// Inline math doesn't span paragraphs
+			if strings.HasPrefix(text[i:], "\n\n") {
+				return 0
+			}
+		case '$':
+			if text[i-1] == '\\' {
+				continue
+			}
+			if text[i-1] == ' ' || (i+1 < len(text) && text[i+1] >= '0' && text[i+1] <= '9') {
+				return 0
+			}
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// insideSpan reports whether offset falls strictly inside one of spans,
+// which are sorted by position
+func insideSpan(spans []textSpan, offset int) bool {
+	for _, span := range spans {
+		if offset <= span.Start {
+			return false
+		}
+		if offset < span.End {
+			return true
+		}
+	}
+	return false
+}
+
+// markMath records in each chunk's metadata whether it holds equations
+func markMath(chunks []Chunk) {
+	for i := range chunks {
+		if count := len(mathSpans(chunks[i].Content)); count > 0 {
+			chunks[i].Metadata["has_math"] = true
+			chunks[i].Metadata["math_span_count"] = count
+		}
+	}
+}
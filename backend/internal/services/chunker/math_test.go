@@ -0,0 +1,81 @@
+package chunker
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMathSpans(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"display dollars", "Energy is $$E = mc^2$$ here.", []string{"$$E = mc^2$$"}},
+		{"brackets", `So \[a^2 + b^2 = c^2\] and \(x\) hold.`, []string{`\[a^2 + b^2 = c^2\]`, `\(x\)`}},
+		{"inline", "Let $x_1$ and $y$ be roots.", []string{"$x_1$", "$y$"}},
+		{"prices are not math", "Tickets cost $5 and $10 each.", nil},
+		{"escaped dollar", `It costs \$5, see $z$.`, []string{"$z$"}},
+		{"opening dollar before space", "A $ b$ c", nil},
+		{"closing dollar after space", "A $b $ c", nil},
+		{"no paragraph crossing", "A $b\n\nc$ d", nil},
+		{"multi-line inline", "A $b\nc$ d", []string{"$b\nc$"}},
+		{
+			"environment",
+			"Solve \\begin{align}x &= 1\\\\ y &= 2\\end{align} now.",
+			[]string{"\\begin{align}x &= 1\\\\ y &= 2\\end{align}"},
+		},
+		{
+			"nested environment",
+			"\\begin{array}\\begin{array}a\\end{array}\\end{array} b",
+			[]string{"\\begin{array}\\begin{array}a\\end{array}\\end{array}"},
+		},
+		{"unclosed environment", "\\begin{align}x = 1", nil},
+		{"unclosed display", "$$x = 1", nil},
+		{"punctuation inside", "Then $$a. B! c?$$ ends.", []string{"$$a. B! c?$$"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, span := range mathSpans(tt.text) {
+				got = append(got, tt.text[span.Start:span.End])
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mathSpans(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInsideSpan(t *testing.T) {
+	spans := []textSpan{{Start: 2, End: 5}, {Start: 10, End: 12}}
+	tests := []struct {
+		offset int
+		want   bool
+	}{
+		{0, false},
+		{2, false}, // the start is outside
+		{3, true},
+		{5, false}, // so is the end
+		{11, true},
+		{20, false},
+	}
+
+	for _, tt := range tests {
+		if got := insideSpan(spans, tt.offset); got != tt.want {
+			t.Errorf("insideSpan(%d) = %v, want %v", tt.offset, got, tt.want)
+		}
+	}
+}
+
+func TestSentencesKeepEquationsWhole(t *testing.T) {
+	text := "The identity $$a. b = c$$ holds everywhere. It is used often in the proof."
+	sentences := splitIntoSentences(text)
+	if len(sentences) != 2 {
+		t.Fatalf("got %d sentences, want 2: %+v", len(sentences), sentences)
+	}
+	if want := "The identity $$a. b = c$$ holds everywhere."; sentences[0].Text != want {
+		t.Errorf("first sentence = %q, want %q", sentences[0].Text, want)
+	}
+}
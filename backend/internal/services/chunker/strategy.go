@@ -0,0 +1,128 @@
+package chunker
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Strategy names
+const (
+	StrategySentence  = "sentence"
+	StrategyToken     = "token"
+	StrategyStructure = "structure"
+)
+
+// Strategy splits a piece of text into chunks
+type Strategy interface {
+	Name() string
+	Chunk(text string, metadata map[string]interface{}) ([]Chunk, error)
+}
+
+// sentenceStrategy is the original character-estimate sentence packer
+type sentenceStrategy struct {
+	client *Client
+}
+
+// Name returns the strategy name
+func (s *sentenceStrategy) Name() string {
+	return StrategySentence
+}
+
+// Chunk splits text using the client's sentence packer
+func (s *sentenceStrategy) Chunk(text string, metadata map[string]interface{}) ([]Chunk, error) {
+	return s.client.chunk(text, metadata)
+}
+
+// textSpan is a byte range of the text being chunked
+type textSpan struct {
+	Start int
+	End   int
+}
+
+// splitSpanByTokens splits an oversized span at word boundaries into
+// pieces of at most maxTokens, measured with tokens indexed over text
+func splitSpanByTokens(text string, span textSpan, tokens tokenIndex, maxTokens int) []textSpan {
+	var result []textSpan
+
+	// Equations are never broken, even when one alone exceeds maxTokens
+	math := mathSpans(text[span.Start:span.End])
+
+	start := span.Start
+	lastBreak := -1
+	for i := span.Start; i < span.End; i++ {
+		if text[i] != ' ' && text[i] != '\n' || insideSpan(math, i-span.Start) {
+			continue
+		}
+		if tokens.count(start, i) > maxTokens && lastBreak > start {
+			result = append(result, textSpan{Start: start, End: lastBreak})
+			start = lastBreak + 1
+		}
+		lastBreak = i
+	}
+
+	if tokens.count(start, span.End) > maxTokens && lastBreak > start {
+		result = append(result, textSpan{Start: start, End: lastBreak})
+		start = lastBreak + 1
+	}
+	if strings.TrimSpace(text[start:span.End]) != "" {
+		result = append(result, textSpan{Start: start, End: span.End})
+	}
+
+	return result
+}
+
+// strategyFor picks the strategy for a document from its file_type metadata
+func (c *Client) strategyFor(metadata map[string]interface{}) Strategy {
+	fileType, _ := metadata["file_type"].(string)
+	if name, ok := c.typeStrategies[strings.ToLower(fileType)]; ok {
+		if strategy, ok := c.strategies[name]; ok {
+			return strategy
+		}
+	}
+	return c.strategies[c.defaultStrategy]
+}
+
+// SetStrategyForType selects the strategy used for a file type such as ".pdf".
+// The file type "default" sets the fallback strategy.
+func (c *Client) SetStrategyForType(fileType, name string) error {
+	if _, ok := c.strategies[name]; !ok {
+		return fmt.Errorf("unknown chunking strategy: %s", name)
+	}
+
+	fileType = strings.ToLower(fileType)
+	if fileType == "default" {
+		c.defaultStrategy = name
+		return nil
+	}
+	if !strings.HasPrefix(fileType, ".") {
+		fileType = "." + fileType
+	}
+	c.typeStrategies[fileType] = name
+	return nil
+}
+
+// RegisterStrategy adds or replaces a named chunking strategy
+func (c *Client) RegisterStrategy(strategy Strategy) {
+	c.strategies[strategy.Name()] = strategy
+}
+
+// SetTokenizer sets the tokenizer used by the token and structure strategies
+func (c *Client) SetTokenizer(tokenizer Tokenizer) {
+	if tokenizer != nil {
+		c.tokenizer = tokenizer
+		c.registerDefaultStrategies()
+	}
+}
+
+// CountTokens counts tokens with the client's tokenizer
+func (c *Client) CountTokens(text string) int {
+	return c.tokenizer.CountTokens(text)
+}
+
+// registerDefaultStrategies (re)builds the built-in strategies from the
+// client's current size and tokenizer settings
+func (c *Client) registerDefaultStrategies() {
+	c.RegisterStrategy(&sentenceStrategy{client: c})
+	c.RegisterStrategy(NewTokenStrategy(c.tokenizer, c.chunkSize, c.overlapSize))
+	c.RegisterStrategy(NewStructureStrategy(c.tokenizer, c.chunkSize))
+}
@@ -0,0 +1,300 @@
+package chunker
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	markdownHeadingRegex = regexp.MustCompile(`^(#{1,6})\s+(.+)$`)
+	numberedHeadingRegex = regexp.MustCompile(`^(\d+(?:\.\d+)*)\.?\s+(\S.{0,100})$`)
+	listItemRegex        = regexp.MustCompile(`^(?:[-*•]|\d+[.)]|[a-zA-Z][.)])\s+`)
+)
+
+// block kinds recognised by the structure strategy
+const (
+	blockHeading = iota
+	blockParagraph
+	blockList
+)
+
+// block is a heading, paragraph or list found in the text
+type block struct {
+	kind  int
+	level int // Heading depth, 1 for top level
+	title string
+	items []textSpan // List items, or the single span of a paragraph/heading
+	span  textSpan
+}
+
+// StructureStrategy chunks along headings, paragraphs and lists so that a
+// chunk never starts mid-paragraph and always knows which section it is in
+type StructureStrategy struct {
+	tokenizer Tokenizer
+	chunkSize int
+}
+
+// NewStructureStrategy creates a structure-aware chunking strategy
+func NewStructureStrategy(tokenizer Tokenizer, chunkSize int) *StructureStrategy {
+	if tokenizer == nil {
+		tokenizer = EstimateTokenizer{}
+	}
+
+	return &StructureStrategy{
+		tokenizer: tokenizer,
+		chunkSize: chunkSize,
+	}
+}
+
+// Name returns the strategy name
+func (s *StructureStrategy) Name() string {
+	return StrategyStructure
+}
+
+// Chunk splits text into chunks that follow the document's structure
+func (s *StructureStrategy) Chunk(text string, metadata map[string]interface{}) ([]Chunk, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("text cannot be empty")
+	}
+
+	// Line breaks carry the structure, so only normalise them; offsets are
+	// mapped back to the original text
+	normalized := normalizeLineBreaks(text)
+	text = normalized.text
+
+	// Tokenize once; every span below is measured against this index
+	tokens := newTokenIndex(s.tokenizer, text)
+
+	var chunks []Chunk
+	// Sections cut from the middle of a document carry the headings they fall under
+	headingPath := stringSlice(metadata["heading_path"])
+	var pending []textSpan
+	onlyHeadings := true
+
+	flush := func() {
+		if len(pending) == 0 {
+			return
+		}
+		start, end := pending[0].Start, pending[len(pending)-1].End
+		content := text[start:end]
+		charStart, charEnd := normalized.sourceSpan(start, end)
+		sectionTitle := ""
+		if len(headingPath) > 0 {
+			sectionTitle = headingPath[len(headingPath)-1]
+		}
+		chunks = append(chunks, Chunk{
+			Content: content,
+			Ordinal: len(chunks),
+			Metadata: mergeMetadata(metadata, map[string]interface{}{
+				"chunk_strategy": StrategyStructure,
+				"tokenizer":      s.tokenizer.Name(),
+				"token_count":    tokens.count(start, end),
+				"heading_path":   append([]string(nil), headingPath...),
+				"section_title":  sectionTitle,
+				"char_start":     charStart,
+				"char_end":       charEnd,
+			}),
+		})
+		pending = nil
+		onlyHeadings = true
+	}
+
+	// add appends a span to the current chunk, flushing first if it would overflow
+	add := func(span textSpan) {
+		if len(pending) > 0 && tokens.count(pending[0].Start, span.End) > s.chunkSize {
+			flush()
+		}
+		pending = append(pending, span)
+	}
+
+	for _, b := range parseBlocks(text) {
+		switch b.kind {
+		case blockHeading:
+			// A new section starts a new chunk, unless the chunk so far is
+			// just the parent headings
+			if !onlyHeadings {
+				flush()
+			}
+			for len(headingPath) >= b.level {
+				headingPath = headingPath[:len(headingPath)-1]
+			}
+			for len(headingPath) < b.level-1 {
+				headingPath = append(headingPath, "")
+			}
+			headingPath = append(headingPath, b.title)
+			add(b.span)
+		default:
+			for _, unit := range s.units(text, tokens, b) {
+				add(unit)
+			}
+			onlyHeadings = false
+		}
+	}
+	flush()
+
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("no content found in text")
+	}
+
+	return chunks, nil
+}
+
+// units breaks a block into pieces that each fit within the chunk size,
+// keeping the block whole when it fits
+func (s *StructureStrategy) units(text string, tokens tokenIndex, b block) []textSpan {
+	if tokens.count(b.span.Start, b.span.End) <= s.chunkSize {
+		return []textSpan{b.span}
+	}
+
+	var parts []textSpan
+	if b.kind == blockList {
+		parts = b.items
+	} else {
+		for _, sentence := range splitIntoSentences(text[b.span.Start:b.span.End]) {
+			parts = append(parts, textSpan{Start: b.span.Start + sentence.Start, End: b.span.Start + sentence.End})
+		}
+		if len(parts) == 0 {
+			parts = []textSpan{b.span}
+		}
+	}
+
+	var units []textSpan
+	for _, part := range parts {
+		if tokens.count(part.Start, part.End) <= s.chunkSize {
+			units = append(units, part)
+			continue
+		}
+		units = append(units, splitSpanByTokens(text, part, tokens, s.chunkSize)...)
+	}
+	return units
+}
+
+// normalizeLineBreaks turns CRLF and lone CR line breaks into LF
+func normalizeLineBreaks(text string) mappedText {
+	var b mappedBuilder
+	for i := 0; i < len(text); i++ {
+		switch {
+		case text[i] != '\r':
+			b.write(text[i:i+1], i)
+		case i+1 < len(text) && text[i+1] == '\n':
+			// The LF that follows is written on the next iteration
+		default:
+			b.write("\n", i)
+		}
+	}
+	return b.mapped()
+}
+
+// parseBlocks groups lines into headings, lists and blank-line separated paragraphs
+func parseBlocks(text string) []block {
+	var blocks []block
+	var current *block
+
+	closeBlock := func() {
+		if current != nil {
+			blocks = append(blocks, *current)
+			current = nil
+		}
+	}
+
+	math := mathSpans(text)
+
+	offset := 0
+	for _, line := range strings.SplitAfter(text, "\n") {
+		lineStart := offset
+		offset += len(line)
+
+		trimmed := strings.TrimSpace(line)
+
+		// Lines of a multi-line equation, blank or not, continue its block
+		if current != nil && insideSpan(math, lineStart) {
+			if trimmed != "" {
+				current.span.End = lineStart + strings.Index(line, trimmed) + len(trimmed)
+				if current.kind == blockList {
+					current.items[len(current.items)-1].End = current.span.End
+				}
+			}
+			continue
+		}
+		if trimmed == "" {
+			closeBlock()
+			continue
+		}
+		start := lineStart + strings.Index(line, trimmed)
+		span := textSpan{Start: start, End: start + len(trimmed)}
+
+		if level, title, ok := headingLine(trimmed); ok {
+			closeBlock()
+			blocks = append(blocks, block{kind: blockHeading, level: level, title: title, span: span})
+			continue
+		}
+
+		if listItemRegex.MatchString(trimmed) {
+			if current == nil || current.kind != blockList {
+				closeBlock()
+				current = &block{kind: blockList, span: span}
+			}
+			current.items = append(current.items, span)
+			current.span.End = span.End
+			continue
+		}
+
+		// Continuation lines belong to the open list item or paragraph
+		if current == nil {
+			current = &block{kind: blockParagraph, span: span}
+			continue
+		}
+		current.span.End = span.End
+		if current.kind == blockList {
+			current.items[len(current.items)-1].End = span.End
+		}
+	}
+	closeBlock()
+
+	return blocks
+}
+
+// headingLine reports whether a line looks like a heading and returns its level and title
+func headingLine(line string) (int, string, bool) {
+	// Equations such as "$$F = MA$$" can look like all-caps headings
+	if strings.HasPrefix(line, "$") || strings.HasPrefix(line, `\`) {
+		return 0, "", false
+	}
+
+	if match := markdownHeadingRegex.FindStringSubmatch(line); match != nil {
+		return len(match[1]), strings.TrimSpace(match[2]), true
+	}
+
+	// Numbered section headings such as "2.3 Thermal Equilibrium"; sentence
+	// punctuation at the end means it is a numbered list item instead
+	if match := numberedHeadingRegex.FindStringSubmatch(line); match != nil && strings.Contains(match[1], ".") {
+		if !strings.ContainsAny(line[len(line)-1:], ".,;:!?") {
+			return strings.Count(strings.TrimSuffix(match[1], "."), ".") + 1, line, true
+		}
+	}
+
+	// Short all-caps lines such as "INTRODUCTION"
+	if len(line) <= 60 && strings.ToUpper(line) == line && strings.ToLower(line) != line && !listItemRegex.MatchString(line) {
+		return 1, line, true
+	}
+
+	return 0, "", false
+}
+
+// stringSlice reads a list of strings from metadata, which holds []string
+// when built in process and []interface{} once decoded from JSON
+func stringSlice(v interface{}) []string {
+	switch v := v.(type) {
+	case []string:
+		return append([]string(nil), v...)
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			s, _ := item.(string)
+			result = append(result, s)
+		}
+		return result
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package chunker
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHeadingLine(t *testing.T) {
+	tests := []struct {
+		line      string
+		wantLevel int
+		wantTitle string
+		wantOK    bool
+	}{
+		{"# Introduction", 1, "Introduction", true},
+		{"### Worked examples", 3, "Worked examples", true},
+		{"2.3 Thermal Equilibrium", 2, "2.3 Thermal Equilibrium", true},
+		{"1.2.4 Boundary layers", 3, "1.2.4 Boundary layers", true},
+		{"2.3 Heat flows from hot to cold.", 0, "", false},
+		{"1. First step", 0, "", false},
+		{"INTRODUCTION", 1, "INTRODUCTION", true},
+		{"$$F = MA$$", 0, "", false},
+		{"A. LIST ITEM", 0, "", false},
+		{"Plain sentence of text.", 0, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			level, title, ok := headingLine(tt.line)
+			if level != tt.wantLevel || title != tt.wantTitle || ok != tt.wantOK {
+				t.Errorf("headingLine(%q) = %d, %q, %v; want %d, %q, %v",
+					tt.line, level, title, ok, tt.wantLevel, tt.wantTitle, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestParseBlocks(t *testing.T) {
+	text := "# Units\n\nFirst paragraph\ncontinues here.\n\n- one\n- two\n  wrapped\n\nLast paragraph."
+	blocks := parseBlocks(text)
+
+	var kinds []int
+	var spans []string
+	for _, b := range blocks {
+		kinds = append(kinds, b.kind)
+		spans = append(spans, text[b.span.Start:b.span.End])
+	}
+
+	wantKinds := []int{blockHeading, blockParagraph, blockList, blockParagraph}
+	if !reflect.DeepEqual(kinds, wantKinds) {
+		t.Fatalf("kinds = %v, want %v (%q)", kinds, wantKinds, spans)
+	}
+	wantSpans := []string{"# Units", "First paragraph\ncontinues here.", "- one\n- two\n  wrapped", "Last paragraph."}
+	if !reflect.DeepEqual(spans, wantSpans) {
+		t.Errorf("spans = %q, want %q", spans, wantSpans)
+	}
+
+	list := blocks[2]
+	var items []string
+	for _, item := range list.items {
+		items = append(items, text[item.Start:item.End])
+	}
+	if want := []string{"- one", "- two\n  wrapped"}; !reflect.DeepEqual(items, want) {
+		t.Errorf("list items = %q, want %q", items, want)
+	}
+}
+
+func TestStructureStrategy(t *testing.T) {
+	text := strings.Join([]string{
+		"# Thermodynamics",
+		"",
+		"Energy is conserved in every process.",
+		"",
+		"## First law",
+		"",
+		"Heat added equals work done plus the change in internal energy.",
+		"",
+		"- closed systems",
+		"- open systems",
+		"",
+		"## Second law",
+		"",
+		"Entropy of an isolated system never decreases.",
+	}, "\n")
+
+	strategy := NewStructureStrategy(EstimateTokenizer{}, 1000)
+	chunks, err := strategy.Chunk(text, nil)
+	if err != nil {
+		t.Fatalf("Chunk() error = %v", err)
+	}
+
+	want := []struct {
+		path    []string
+		starts  string
+		content string
+	}{
+		{[]string{"Thermodynamics"}, "# Thermodynamics", "Energy is conserved"},
+		{[]string{"Thermodynamics", "First law"}, "## First law", "- open systems"},
+		{[]string{"Thermodynamics", "Second law"}, "## Second law", "never decreases."},
+	}
+	if len(chunks) != len(want) {
+		t.Fatalf("got %d chunks, want %d: %+v", len(chunks), len(want), chunks)
+	}
+	for i, w := range want {
+		chunk := chunks[i]
+		if got := chunk.Metadata["heading_path"].([]string); !reflect.DeepEqual(got, w.path) {
+			t.Errorf("chunk %d heading_path = %q, want %q", i, got, w.path)
+		}
+		if got := chunk.Metadata["section_title"]; got != w.path[len(w.path)-1] {
+			t.Errorf("chunk %d section_title = %q", i, got)
+		}
+		if !strings.HasPrefix(chunk.Content, w.starts) || !strings.Contains(chunk.Content, w.content) {
+			t.Errorf("chunk %d content = %q", i, chunk.Content)
+		}
+	}
+}
+
+func TestStructureStrategyCarriesSectionHeadings(t *testing.T) {
+	strategy := NewStructureStrategy(EstimateTokenizer{}, 1000)
+	chunks, err := strategy.Chunk("Text after a table.\n\n### Details\n\nMore text.", map[string]interface{}{
+		"heading_path": []interface{}{"Physics", "Heat"},
+	})
+	if err != nil {
+		t.Fatalf("Chunk() error = %v", err)
+	}
+	if len(chunks) != 2 {
+		t.Fatalf("got %d chunks, want 2", len(chunks))
+	}
+	if got := chunks[0].Metadata["heading_path"].([]string); !reflect.DeepEqual(got, []string{"Physics", "Heat"}) {
+		t.Errorf("first heading_path = %q", got)
+	}
+	if got := chunks[1].Metadata["heading_path"].([]string); !reflect.DeepEqual(got, []string{"Physics", "Heat", "Details"}) {
+		t.Errorf("second heading_path = %q", got)
+	}
+}
+
+func TestStructureStrategySplitsLargeBlocks(t *testing.T) {
+	var items []string
+	for i := 0; i < 20; i++ {
+		items = append(items, "- an item long enough to count for several tokens")
+	}
+	text := "# List\n\n" + strings.Join(items, "\n")
+
+	strategy := NewStructureStrategy(EstimateTokenizer{}, 40)
+	chunks, err := strategy.Chunk(text, nil)
+	if err != nil {
+		t.Fatalf("Chunk() error = %v", err)
+	}
+	if len(chunks) < 2 {
+		t.Fatalf("got %d chunks, want the list split", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if tokens := chunk.Metadata["token_count"].(int); tokens > 40 {
+			t.Errorf("chunk %d has %d tokens", i, tokens)
+		}
+		// Lists are split between items, never within one
+		for _, line := range strings.Split(chunk.Content, "\n") {
+			if line != "# List" && line != "" && line != items[0] {
+				t.Errorf("chunk %d has a partial line %q", i, line)
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package chunker
+
+import (
+	"strings"
+)
+
+// ChunkTypeTable is the chunk_type metadata of sections and chunks that
+// hold a Markdown table
+const ChunkTypeTable = "table"
+
+// isTable reports whether section metadata marks a table
+func isTable(metadata map[string]interface{}) bool {
+	chunkType, _ := metadata["chunk_type"].(string)
+	return chunkType == ChunkTypeTable
+}
+
+// chunkTable keeps a Markdown table in a single chunk whenever it fits.
+// Larger tables are split between rows, never within one, and every part
+// repeats the header row so it can be read on its own.
+func chunkTable(text string, metadata map[string]interface{}, tokenizer Tokenizer, chunkSize int) []Chunk {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+
+	var header []string
+	rows := lines
+	if len(lines) >= 2 && isTableSeparator(lines[1]) {
+		header, rows = lines[:2], lines[2:]
+	}
+
+	newChunk := func(content string, rowStart, rowCount int) Chunk {
+		return Chunk{
+			Content: content,
+			Metadata: mergeMetadata(metadata, map[string]interface{}{
+				"chunk_type":  ChunkTypeTable,
+				"tokenizer":   tokenizer.Name(),
+				"token_count": tokenizer.CountTokens(content),
+				"row_start":   rowStart,
+				"row_count":   rowCount,
+			}),
+		}
+	}
+
+	content := strings.Join(lines, "\n")
+	if tokenizer.CountTokens(content) <= chunkSize || len(rows) <= 1 {
+		return []Chunk{newChunk(content, 0, len(rows))}
+	}
+
+	var chunks []Chunk
+	for first := 0; first < len(rows); {
+		last := first + 1
+		for last < len(rows) && tokenizer.CountTokens(tableText(header, rows[first:last+1])) <= chunkSize {
+			last++
+		}
+		chunk := newChunk(tableText(header, rows[first:last]), first, last-first)
+		chunk.Ordinal = len(chunks)
+		chunks = append(chunks, chunk)
+		first = last
+	}
+	return chunks
+}
+
+// tableText joins a table's header and a run of its rows
+func tableText(header, rows []string) string {
+	return strings.Join(append(append([]string(nil), header...), rows...), "\n")
+}
+
+// isTableSeparator reports whether a line is a Markdown table's header
+// separator, such as "| --- | --- |"
+func isTableSeparator(line string) bool {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "|") || !strings.Contains(line, "---") {
+		return false
+	}
+	return strings.Trim(line, "|-: ") == ""
+}
@@ -0,0 +1,95 @@
+package chunker
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestChunkTable(t *testing.T) {
+	header := "| Course | Title |\n| --- | --- |"
+	var rows []string
+	for i := 0; i < 12; i++ {
+		rows = append(rows, fmt.Sprintf("| CSC %d | Introduction to topic %d |", 100+i, i))
+	}
+	table := header + "\n" + strings.Join(rows, "\n")
+
+	t.Run("fits in one chunk", func(t *testing.T) {
+		chunks := chunkTable(table+"\n", map[string]interface{}{"page": 2}, EstimateTokenizer{}, 1000)
+		if len(chunks) != 1 {
+			t.Fatalf("got %d chunks, want 1", len(chunks))
+		}
+		chunk := chunks[0]
+		if chunk.Content != table {
+			t.Errorf("content = %q", chunk.Content)
+		}
+		if chunk.Metadata["chunk_type"] != ChunkTypeTable || chunk.Metadata["page"] != 2 {
+			t.Errorf("metadata = %v", chunk.Metadata)
+		}
+		if chunk.Metadata["row_start"] != 0 || chunk.Metadata["row_count"] != len(rows) {
+			t.Errorf("rows %v+%v, want 0+%d", chunk.Metadata["row_start"], chunk.Metadata["row_count"], len(rows))
+		}
+	})
+
+	t.Run("split between rows", func(t *testing.T) {
+		chunks := chunkTable(table, nil, EstimateTokenizer{}, 60)
+		if len(chunks) < 2 {
+			t.Fatalf("got %d chunks, want the table split", len(chunks))
+		}
+
+		next := 0
+		for i, chunk := range chunks {
+			if chunk.Ordinal != i {
+				t.Errorf("chunk %d has ordinal %d", i, chunk.Ordinal)
+			}
+			// Every part can be read on its own
+			if !strings.HasPrefix(chunk.Content, header+"\n") {
+				t.Errorf("chunk %d doesn't repeat the header: %q", i, chunk.Content)
+			}
+			start := chunk.Metadata["row_start"].(int)
+			count := chunk.Metadata["row_count"].(int)
+			if start != next {
+				t.Errorf("chunk %d starts at row %d, want %d", i, start, next)
+			}
+			if want := header + "\n" + strings.Join(rows[start:start+count], "\n"); chunk.Content != want {
+				t.Errorf("chunk %d content = %q, want %q", i, chunk.Content, want)
+			}
+			next = start + count
+		}
+		if next != len(rows) {
+			t.Errorf("chunks cover %d rows, want %d", next, len(rows))
+		}
+	})
+
+	t.Run("oversized row is kept whole", func(t *testing.T) {
+		long := "| " + strings.Repeat("x", 400) + " |"
+		chunks := chunkTable(header+"\n"+long+"\n"+long, nil, EstimateTokenizer{}, 20)
+		if len(chunks) != 2 {
+			t.Fatalf("got %d chunks, want one per row", len(chunks))
+		}
+		for i, chunk := range chunks {
+			if !strings.HasSuffix(chunk.Content, long) {
+				t.Errorf("chunk %d cut the row: %q", i, chunk.Content)
+			}
+		}
+	})
+}
+
+func TestIsTableSeparator(t *testing.T) {
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{"| --- | --- |", true},
+		{"|:---|---:|", true},
+		{"  | --- |  ", true},
+		{"| a | b |", false},
+		{"---", false},
+		{"| - |", false},
+	}
+	for _, tt := range tests {
+		if got := isTableSeparator(tt.line); got != tt.want {
+			t.Errorf("isTableSeparator(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
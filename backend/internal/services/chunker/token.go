@@ -0,0 +1,107 @@
+package chunker
+
+import (
+	"fmt"
+)
+
+// TokenStrategy packs whole sentences into chunks using exact token counts
+// from the configured tokenizer, with a token-sized overlap between chunks
+type TokenStrategy struct {
+	tokenizer   Tokenizer
+	chunkSize   int
+	overlapSize int
+}
+
+// NewTokenStrategy creates a token-counting sentence packer
+func NewTokenStrategy(tokenizer Tokenizer, chunkSize, overlapSize int) *TokenStrategy {
+	if tokenizer == nil {
+		tokenizer = EstimateTokenizer{}
+	}
+
+	return &TokenStrategy{
+		tokenizer:   tokenizer,
+		chunkSize:   chunkSize,
+		overlapSize: overlapSize,
+	}
+}
+
+// Name returns the strategy name
+func (s *TokenStrategy) Name() string {
+	return StrategyToken
+}
+
+// Chunk splits text into overlapping chunks of at most chunkSize tokens
+func (s *TokenStrategy) Chunk(text string, metadata map[string]interface{}) ([]Chunk, error) {
+	if text == "" {
+		return nil, fmt.Errorf("text cannot be empty")
+	}
+
+	// Reuse the sentence splitter; chunk content is cut from the cleaned
+	// text so the original punctuation survives, and offsets are mapped
+	// back to the original text
+	cleaned := cleanText(text)
+	cleanedText := cleaned.text
+	sentences := splitIntoSentences(cleanedText)
+	if len(sentences) == 0 {
+		if cleanedText == "" {
+			return nil, fmt.Errorf("no sentences found in text")
+		}
+		sentences = []sentenceSpan{{Text: cleanedText, Start: 0, End: len(cleanedText)}}
+	}
+
+	// Tokenize once; every span below is measured against this index
+	tokens := newTokenIndex(s.tokenizer, cleanedText)
+
+	// Break up sentences that alone exceed the chunk size
+	var units []sentenceSpan
+	for _, sentence := range sentences {
+		if tokens.count(sentence.Start, sentence.End) <= s.chunkSize {
+			units = append(units, sentence)
+			continue
+		}
+		for _, part := range splitSpanByTokens(cleanedText, textSpan{Start: sentence.Start, End: sentence.End}, tokens, s.chunkSize) {
+			units = append(units, sentenceSpan{Text: cleanedText[part.Start:part.End], Start: part.Start, End: part.End})
+		}
+	}
+
+	var chunks []Chunk
+	first := 0
+	for first < len(units) {
+		// Grow the chunk one sentence at a time while it fits
+		last := first
+		for last+1 < len(units) {
+			if tokens.count(units[first].Start, units[last+1].End) > s.chunkSize {
+				break
+			}
+			last++
+		}
+
+		content := cleanedText[units[first].Start:units[last].End]
+		charStart, charEnd := cleaned.sourceSpan(units[first].Start, units[last].End)
+		chunks = append(chunks, Chunk{
+			Content: content,
+			Ordinal: len(chunks),
+			Metadata: mergeMetadata(metadata, map[string]interface{}{
+				"chunk_strategy": StrategyToken,
+				"tokenizer":      s.tokenizer.Name(),
+				"token_count":    tokens.count(units[first].Start, units[last].End),
+				"char_start":     charStart,
+				"char_end":       charEnd,
+			}),
+		})
+
+		if last == len(units)-1 {
+			break
+		}
+
+		// Step back over trailing sentences to form the overlap, always
+		// making progress by at least one sentence
+		next := last + 1
+		for next-1 > first && tokens.count(units[next-1].Start, units[last].End) <= s.overlapSize {
+			next--
+		}
+		first = next
+	}
+
+	return chunks, nil
+}
@@ -0,0 +1,140 @@
+package chunker
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTokenStrategy(t *testing.T) {
+	var sentences []string
+	for i := 0; i < 30; i++ {
+		sentences = append(sentences, fmt.Sprintf("Sentence %02d covers one small idea in detail.", i))
+	}
+	text := strings.Join(sentences, " ")
+
+	tests := []struct {
+		name        string
+		chunkSize   int
+		overlapSize int
+	}{
+		{"no overlap", 50, 0},
+		{"overlap", 50, 15},
+		{"one sentence per chunk", 12, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strategy := NewTokenStrategy(EstimateTokenizer{}, tt.chunkSize, tt.overlapSize)
+			chunks, err := strategy.Chunk(text, map[string]interface{}{"file_type": ".pdf"})
+			if err != nil {
+				t.Fatalf("Chunk() error = %v", err)
+			}
+			if len(chunks) < 2 {
+				t.Fatalf("got %d chunks, want several", len(chunks))
+			}
+
+			previousEnd := 0
+			for i, chunk := range chunks {
+				if chunk.Ordinal != i {
+					t.Errorf("chunk %d has ordinal %d", i, chunk.Ordinal)
+				}
+				if tokens := chunk.Metadata["token_count"].(int); tokens > tt.chunkSize {
+					t.Errorf("chunk %d has %d tokens, limit %d", i, tokens, tt.chunkSize)
+				}
+				if chunk.Metadata["file_type"] != ".pdf" {
+					t.Errorf("chunk %d lost the document metadata", i)
+				}
+				if !strings.HasPrefix(chunk.Content, "Sentence ") || !strings.HasSuffix(chunk.Content, ".") {
+					t.Errorf("chunk %d doesn't hold whole sentences: %q", i, chunk.Content)
+				}
+
+				start := chunk.Metadata["char_start"].(int)
+				end := chunk.Metadata["char_end"].(int)
+				if i > 0 {
+					overlaps := start < previousEnd
+					if overlaps != (tt.overlapSize > 0) {
+						t.Errorf("chunk %d starts at %d, previous ended at %d", i, start, previousEnd)
+					}
+				}
+				previousEnd = end
+			}
+
+			if first := chunks[0].Metadata["char_start"].(int); first != 0 {
+				t.Errorf("first chunk starts at %d", first)
+			}
+			if previousEnd != len(text) {
+				t.Errorf("last chunk ends at %d, text is %d bytes", previousEnd, len(text))
+			}
+		})
+	}
+}
+
+func TestTokenStrategySplitsLongSentences(t *testing.T) {
+	text := strings.Repeat("word ", 200) + "end."
+	strategy := NewTokenStrategy(EstimateTokenizer{}, 40, 0)
+
+	chunks, err := strategy.Chunk(text, nil)
+	if err != nil {
+		t.Fatalf("Chunk() error = %v", err)
+	}
+	if len(chunks) < 5 {
+		t.Fatalf("got %d chunks, want the sentence split up", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if tokens := chunk.Metadata["token_count"].(int); tokens > 40 {
+			t.Errorf("chunk %d has %d tokens", i, tokens)
+		}
+		if strings.HasPrefix(chunk.Content, " ") || strings.HasSuffix(chunk.Content, " ") {
+			t.Errorf("chunk %d was split mid-word: %q", i, chunk.Content)
+		}
+	}
+}
+
+func TestTokenStrategyEmptyText(t *testing.T) {
+	strategy := NewTokenStrategy(nil, 100, 10)
+	if _, err := strategy.Chunk("", nil); err == nil {
+		t.Error("Chunk(\"\") succeeded")
+	}
+	if _, err := strategy.Chunk(" \n\t", nil); err == nil {
+		t.Error("Chunk(whitespace) succeeded")
+	}
+}
+
+func TestSplitSpanByTokens(t *testing.T) {
+	text := "aaaa bbbb $$c c c c$$ dddd eeee"
+	tokens := newTokenIndex(EstimateTokenizer{}, text)
+
+	parts := splitSpanByTokens(text, textSpan{Start: 0, End: len(text)}, tokens, 3)
+
+	var got []string
+	for _, part := range parts {
+		got = append(got, text[part.Start:part.End])
+	}
+	want := []string{"aaaa bbbb", "$$c c c c$$", "dddd eeee"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("splitSpanByTokens() = %q, want %q", got, want)
+	}
+}
+
+func TestTokenIndex(t *testing.T) {
+	text := "abcdefghij"
+	tokens := newTokenIndex(EstimateTokenizer{}, text)
+
+	tests := []struct {
+		start, end int
+		want       int
+	}{
+		{0, 10, 3},
+		{0, 4, 1},
+		{0, 5, 1},
+		{4, 8, 1},
+		{2, 9, 2},
+		{5, 5, 0},
+	}
+	for _, tt := range tests {
+		if got := tokens.count(tt.start, tt.end); got != tt.want {
+			t.Errorf("count(%d, %d) = %d, want %d", tt.start, tt.end, got, tt.want)
+		}
+	}
+}
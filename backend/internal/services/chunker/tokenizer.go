@@ -0,0 +1,101 @@
+package chunker
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/eliben/go-sentencepiece"
+)
+
+// Tokenizer counts tokens the way the embedding model will
+type Tokenizer interface {
+	Name() string
+	CountTokens(text string) int
+	// TokenEnds returns the byte offset in text at which each token ends,
+	// so a text can be tokenized once and any slice of it measured
+	TokenEnds(text string) []int
+}
+
+// tokenIndex counts the tokens in slices of a text that was tokenized once.
+// A slice is charged for the tokens ending inside it, which matches
+// tokenizing the slice alone up to the tokens straddling its edges.
+type tokenIndex struct {
+	ends []int
+}
+
+// newTokenIndex tokenizes text once
+func newTokenIndex(tokenizer Tokenizer, text string) tokenIndex {
+	return tokenIndex{ends: tokenizer.TokenEnds(text)}
+}
+
+// count returns the number of tokens in text[start:end]
+func (t tokenIndex) count(start, end int) int {
+	return sort.SearchInts(t.ends, end+1) - sort.SearchInts(t.ends, start+1)
+}
+
+// EstimateTokenizer approximates token counts as one token per four bytes.
+// It is used when no tokenizer model has been configured.
+type EstimateTokenizer struct{}
+
+// Name returns the tokenizer name
+func (EstimateTokenizer) Name() string {
+	return "estimate"
+}
+
+// CountTokens estimates the number of tokens in text
+func (EstimateTokenizer) CountTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// TokenEnds splits text into four-byte tokens
+func (EstimateTokenizer) TokenEnds(text string) []int {
+	ends := make([]int, 0, (len(text)+3)/4)
+	for end := 4; end < len(text)+4; end += 4 {
+		ends = append(ends, min(end, len(text)))
+	}
+	return ends
+}
+
+// SentencePieceTokenizer counts tokens with a SentencePiece model, such as
+// the Gemma tokenizer.model shared by the Gemini embedding models
+type SentencePieceTokenizer struct {
+	processor *sentencepiece.Processor
+}
+
+// NewSentencePieceTokenizer loads a SentencePiece model file
+func NewSentencePieceTokenizer(modelPath string) (*SentencePieceTokenizer, error) {
+	processor, err := sentencepiece.NewProcessorFromPath(modelPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tokenizer model: %w", err)
+	}
+	return &SentencePieceTokenizer{processor: processor}, nil
+}
+
+// Name returns the tokenizer name
+func (t *SentencePieceTokenizer) Name() string {
+	return "sentencepiece"
+}
+
+// CountTokens returns the exact number of tokens in text
+func (t *SentencePieceTokenizer) CountTokens(text string) int {
+	return len(t.processor.Encode(text))
+}
+
+// TokenEnds returns where each token ends in text. Pieces spell spaces as
+// "▁", and byte fallback pieces such as "<0x0A>" stand for a single byte.
+func (t *SentencePieceTokenizer) TokenEnds(text string) []int {
+	tokens := t.processor.Encode(text)
+	ends := make([]int, 0, len(tokens))
+
+	offset := 0
+	for _, token := range tokens {
+		if len(token.Text) == 6 && strings.HasPrefix(token.Text, "<0x") && strings.HasSuffix(token.Text, ">") {
+			offset++
+		} else {
+			offset += len(strings.ReplaceAll(token.Text, "▁", " "))
+		}
+		ends = append(ends, min(offset, len(text)))
+	}
+	return ends
+}
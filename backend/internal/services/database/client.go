@@ -105,6 +105,37 @@ func (c *Client) GetUserBySupabaseID(supabaseID string) (*models.User, error) {
 	return user, nil
 }
 
+// GetUserRole returns the role a user chose during onboarding, or "" if
+// they haven't completed it
+func (c *Client) GetUserRole(userID uuid.UUID) (string, error) {
+	var role string
+	err := c.db.QueryRow("SELECT role FROM onboarding WHERE user_id = $1", userID).Scan(&role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get user role: %w", err)
+	}
+	return role, nil
+}
+
+// GetUserPlan returns the subscription plan of a user, "free" by default
+func (c *Client) GetUserPlan(userID uuid.UUID) (string, error) {
+	var plan sql.NullString
+	err := c.db.QueryRow("SELECT plan FROM users WHERE id = $1", userID).Scan(&plan)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", fmt.Errorf("user not found")
+		}
+		return "", fmt.Errorf("failed to get user plan: %w", err)
+	}
+
+	if !plan.Valid || plan.String == "" {
+		return "free", nil
+	}
+	return plan.String, nil
+}
+
 // GetUserByID retrieves a user by their ID
 func (c *Client) GetUserByID(userID uuid.UUID) (*models.User, error) {
 	logger := utils.GetLogger()
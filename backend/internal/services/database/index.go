@@ -0,0 +1,511 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kinyichukwu/edu-pro-backend/internal/models"
+	"github.com/kinyichukwu/edu-pro-backend/internal/utils"
+	"go.uber.org/zap"
+)
+
+// Vector index methods
+const (
+	IndexMethodHNSW    = "hnsw"
+	IndexMethodIVFFlat = "ivfflat"
+	IndexMethodNone    = "none"
+)
+
+// vectorIndexLockKey is the advisory lock held while an instance builds or
+// drops vector indexes, so only one instance does it at a time
+const vectorIndexLockKey = 724_301_001
+
+// ivfflatMinRows is the chunk count below which no IVFFlat index is built:
+// its lists are trained on the rows present at build time, and a sequential
+// scan is fast enough for small tables anyway
+const ivfflatMinRows = 10_000
+
+// indexDimensionSQL extracts the dimension from a partial vector index's
+// predicate, or 0 for an index on the bare column
+const indexDimensionSQL = `COALESCE(substring(pg_get_expr(i.indpred, i.indrelid) from 'embedding_dimension = (\d+)')::int, 0)`
+
+// VectorIndexConfig describes the ANN index kept on chunks.embedding and how
+// queries against it are tuned. The column holds vectors of any dimension, so
+// the index is a partial expression index over the chunks of one dimension.
+type VectorIndexConfig struct {
+	Method    string // hnsw, ivfflat or none
+	Dimension int    // Dimension of the embeddings being searched
+	// HNSW build parameters
+	HNSWM              int
+	HNSWEfConstruction int
+	// IVFFlatLists is the number of IVFFlat lists; 0 sizes them from the
+	// row count and rebuilds the index as the table grows
+	IVFFlatLists int
+	// Query-time parameters. ef_search is raised to at least the query's
+	// limit; 0 probes uses the square root of the list count.
+	EfSearch int
+	Probes   int
+	// CheckInterval is how often the index is checked and, for auto-sized
+	// IVFFlat, rebuilt; zero only checks it at startup
+	CheckInterval time.Duration
+}
+
+// vectorSearchParams are the per-query settings for the index currently in
+// use, derived from the valid index found on chunks.embedding
+type vectorSearchParams struct {
+	method    string
+	efSearch  int
+	probes    int
+	iterative bool // pgvector 0.8+ iterative index scans
+}
+
+// vectorIndex is an ANN index found on chunks.embedding
+type vectorIndex struct {
+	name      string
+	method    string
+	dimension int
+	options   map[string]int
+	valid     bool
+}
+
+// VectorIndexManager creates the configured ANN index on chunk embeddings,
+// replaces indexes built with other settings, and keeps the client's
+// per-query search parameters in line with the index in use. Indexes are
+// built concurrently so searches and ingestion carry on meanwhile.
+type VectorIndexManager struct {
+	client *PgxClient
+	cfg    VectorIndexConfig
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewVectorIndexManager creates a vector index manager for the client
+func NewVectorIndexManager(client *PgxClient, cfg VectorIndexConfig) *VectorIndexManager {
+	client.indexConfig = cfg
+	return &VectorIndexManager{
+		client: client,
+		cfg:    cfg,
+	}
+}
+
+// Start ensures the index in the background, as a first build over a large
+// table can take a long time, then re-checks it every CheckInterval
+func (m *VectorIndexManager) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		logger := utils.GetLogger()
+
+		for {
+			if err := m.EnsureIndex(ctx); err != nil && ctx.Err() == nil {
+				logger.Error("Vector index maintenance failed", zap.Error(err))
+			}
+			if m.cfg.CheckInterval <= 0 {
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(m.cfg.CheckInterval):
+			}
+		}
+	}()
+
+	utils.GetLogger().Info("Vector index manager started",
+		zap.String("method", m.cfg.Method),
+		zap.Duration("interval", m.cfg.CheckInterval),
+	)
+}
+
+// Stop stops the manager. A build in progress is cancelled and its invalid
+// index is dropped on the next check.
+func (m *VectorIndexManager) Stop() {
+	if m.cancel == nil {
+		return
+	}
+	m.cancel()
+	m.wg.Wait()
+}
+
+// EnsureIndex builds the configured index if it is missing or was built with
+// other settings, drops any other ANN index on chunks.embedding once it is
+// valid, and refreshes the client's search parameters. If another instance
+// holds the maintenance lock only the search parameters are refreshed.
+func (m *VectorIndexManager) EnsureIndex(ctx context.Context) error {
+	logger := utils.GetLogger()
+
+	conn, err := m.client.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	var locked bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", vectorIndexLockKey).Scan(&locked); err != nil {
+		return fmt.Errorf("failed to take vector index lock: %w", err)
+	}
+	if locked {
+		defer conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", vectorIndexLockKey)
+
+		if err := m.maintain(ctx, conn.Conn()); err != nil {
+			return err
+		}
+	} else {
+		logger.Info("Vector index maintenance running on another instance")
+	}
+
+	return m.client.refreshVectorSearch(ctx)
+}
+
+// maintain builds and drops indexes; the caller holds the advisory lock
+func (m *VectorIndexManager) maintain(ctx context.Context, conn *pgx.Conn) error {
+	logger := utils.GetLogger()
+
+	if m.cfg.Method == IndexMethodNone {
+		return nil
+	}
+
+	existing, err := m.client.listVectorIndexes(ctx)
+	if err != nil {
+		return err
+	}
+
+	options, err := m.desiredOptions(ctx, existing)
+	if err != nil {
+		return err
+	}
+	if options == nil {
+		logger.Info("Too few chunks for an IVFFlat index, skipping", zap.Int("min_rows", ivfflatMinRows))
+		return nil
+	}
+	name := vectorIndexName(m.cfg.Method, m.cfg.Dimension, options)
+
+	current := false
+	for _, index := range existing {
+		if index.name == name && index.valid {
+			current = true
+		}
+	}
+
+	if !current {
+		// An interrupted concurrent build leaves an invalid index behind
+		if _, err := conn.Exec(ctx, "DROP INDEX CONCURRENTLY IF EXISTS "+pgx.Identifier{name}.Sanitize()); err != nil {
+			return fmt.Errorf("failed to drop invalid index %s: %w", name, err)
+		}
+
+		logger.Info("Building vector index", zap.String("index", name))
+		start := time.Now()
+
+		var with []string
+		for _, key := range sortedKeys(options) {
+			with = append(with, fmt.Sprintf("%s = %d", key, options[key]))
+		}
+		sql := fmt.Sprintf("CREATE INDEX CONCURRENTLY %s ON chunks USING %s ((%s) vector_cosine_ops) WITH (%s) WHERE embedding_dimension = %d",
+			pgx.Identifier{name}.Sanitize(), m.cfg.Method, embeddingExpr("embedding", m.cfg.Dimension), strings.Join(with, ", "), m.cfg.Dimension)
+		if _, err := conn.Exec(ctx, sql); err != nil {
+			return fmt.Errorf("failed to build vector index %s: %w", name, err)
+		}
+
+		logger.Info("Vector index built",
+			zap.String("index", name),
+			zap.Duration("duration", time.Since(start)),
+		)
+	}
+
+	// The new index is valid, so the ones it replaces can go
+	for _, index := range existing {
+		if index.name == name {
+			continue
+		}
+		if _, err := conn.Exec(ctx, "DROP INDEX CONCURRENTLY IF EXISTS "+pgx.Identifier{index.name}.Sanitize()); err != nil {
+			return fmt.Errorf("failed to drop vector index %s: %w", index.name, err)
+		}
+		logger.Info("Vector index dropped", zap.String("index", index.name))
+	}
+
+	return nil
+}
+
+// desiredOptions returns the build parameters for the configured method.
+// Auto-sized IVFFlat keeps its current list count unless the table has grown
+// or shrunk enough to be worth a rebuild, and returns nil while the table is
+// too small to train lists on.
+func (m *VectorIndexManager) desiredOptions(ctx context.Context, existing []vectorIndex) (map[string]int, error) {
+	if m.cfg.Method == IndexMethodHNSW {
+		return map[string]int{"m": m.cfg.HNSWM, "ef_construction": m.cfg.HNSWEfConstruction}, nil
+	}
+	if m.cfg.IVFFlatLists > 0 {
+		return map[string]int{"lists": m.cfg.IVFFlatLists}, nil
+	}
+
+	var rows int64
+	if err := m.client.pool.QueryRow(ctx, "SELECT count(*) FROM chunks WHERE embedding IS NOT NULL AND embedding_dimension = $1", m.cfg.Dimension).Scan(&rows); err != nil {
+		return nil, fmt.Errorf("failed to count embedded chunks: %w", err)
+	}
+	if rows < ivfflatMinRows {
+		return nil, nil
+	}
+
+	lists := recommendedLists(rows)
+	for _, index := range existing {
+		current := index.options["lists"]
+		if index.method == IndexMethodIVFFlat && index.dimension == m.cfg.Dimension && index.valid && current > 0 && lists < current*2 && lists*2 > current {
+			lists = current
+		}
+	}
+	return map[string]int{"lists": lists}, nil
+}
+
+// recommendedLists follows the pgvector guidance of rows/1000 lists up to a
+// million rows and sqrt(rows) beyond
+func recommendedLists(rows int64) int {
+	if rows <= 1_000_000 {
+		return max(int(rows/1000), 10)
+	}
+	return int(math.Sqrt(float64(rows)))
+}
+
+// vectorIndexName names an index after its method, dimension and
+// parameters, so a change of settings builds a new index alongside the old one
+func vectorIndexName(method string, dimension int, options map[string]int) string {
+	name := fmt.Sprintf("idx_chunks_embedding_%s_dim%d", method, dimension)
+	for _, key := range sortedKeys(options) {
+		name += fmt.Sprintf("_%s%d", strings.ReplaceAll(key, "_", ""), options[key])
+	}
+	return name
+}
+
+func sortedKeys(options map[string]int) []string {
+	keys := make([]string, 0, len(options))
+	for key := range options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// listVectorIndexes returns the ANN indexes on chunks, valid or not
+func (c *PgxClient) listVectorIndexes(ctx context.Context) ([]vectorIndex, error) {
+	rows, err := c.pool.Query(ctx, `
+		SELECT ic.relname, am.amname, `+indexDimensionSQL+`, COALESCE(ic.reloptions, '{}'), i.indisvalid
+		FROM pg_index i
+		JOIN pg_class ic ON ic.oid = i.indexrelid
+		JOIN pg_am am ON am.oid = ic.relam
+		WHERE i.indrelid = 'chunks'::regclass AND am.amname IN ('hnsw', 'ivfflat')
+		ORDER BY ic.relname
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list vector indexes: %w", err)
+	}
+	defer rows.Close()
+
+	var indexes []vectorIndex
+	for rows.Next() {
+		var index vectorIndex
+		var reloptions []string
+		if err := rows.Scan(&index.name, &index.method, &index.dimension, &reloptions, &index.valid); err != nil {
+			return nil, fmt.Errorf("failed to scan vector index: %w", err)
+		}
+		index.options = make(map[string]int)
+		for _, option := range reloptions {
+			key, value, ok := strings.Cut(option, "=")
+			if !ok {
+				continue
+			}
+			if n, err := strconv.Atoi(value); err == nil {
+				index.options[key] = n
+			}
+		}
+		indexes = append(indexes, index)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating vector indexes: %w", err)
+	}
+
+	return indexes, nil
+}
+
+// refreshVectorSearch derives the per-query search parameters from the valid
+// index in use, preferring one of the configured method. Only an index over
+// the configured dimension can serve queries.
+func (c *PgxClient) refreshVectorSearch(ctx context.Context) error {
+	indexes, err := c.listVectorIndexes(ctx)
+	if err != nil {
+		return err
+	}
+
+	var inUse *vectorIndex
+	for i := range indexes {
+		if !indexes[i].valid || indexes[i].dimension != c.indexConfig.Dimension {
+			continue
+		}
+		if inUse == nil || indexes[i].method == c.indexConfig.Method {
+			inUse = &indexes[i]
+		}
+	}
+
+	var params vectorSearchParams
+	if inUse != nil {
+		var extVersion string
+		if err := c.pool.QueryRow(ctx, "SELECT extversion FROM pg_extension WHERE extname = 'vector'").Scan(&extVersion); err != nil {
+			return fmt.Errorf("failed to read pgvector version: %w", err)
+		}
+
+		params = vectorSearchParams{
+			method:    inUse.method,
+			efSearch:  c.indexConfig.EfSearch,
+			probes:    c.indexConfig.Probes,
+			iterative: versionAtLeast(extVersion, 0, 8),
+		}
+		if params.probes <= 0 {
+			params.probes = max(int(math.Sqrt(float64(inUse.options["lists"]))), 1)
+		}
+	}
+
+	c.searchMu.Lock()
+	c.searchParams = params
+	c.searchMu.Unlock()
+
+	return nil
+}
+
+// versionAtLeast reports whether a "major.minor.patch" version is at least
+// major.minor
+func versionAtLeast(version string, major, minor int) bool {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+	gotMajor, err1 := strconv.Atoi(parts[0])
+	gotMinor, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	return gotMajor > major || (gotMajor == major && gotMinor >= minor)
+}
+
+// beginVectorSearch starts a read-only transaction with the index's search
+// parameters set for a nearest-neighbour query returning limit rows. HNSW
+// only looks at ef_search candidates, so it is raised to at least the limit;
+// with pgvector 0.8+ the scan continues past them when the user and model
+// filters discard candidates, rather than returning too few rows.
+func (c *PgxClient) beginVectorSearch(ctx context.Context, limit int) (pgx.Tx, error) {
+	c.searchMu.RLock()
+	params := c.searchParams
+	c.searchMu.RUnlock()
+
+	tx, err := c.pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin vector search: %w", err)
+	}
+
+	settings := map[string]string{}
+	switch params.method {
+	case IndexMethodHNSW:
+		settings["hnsw.ef_search"] = strconv.Itoa(max(params.efSearch, limit))
+		if params.iterative {
+			settings["hnsw.iterative_scan"] = "strict_order"
+		}
+	case IndexMethodIVFFlat:
+		settings["ivfflat.probes"] = strconv.Itoa(params.probes)
+	}
+
+	for name, value := range settings {
+		if _, err := tx.Exec(ctx, "SELECT set_config($1, $2, true)", name, value); err != nil {
+			tx.Rollback(ctx)
+			return nil, fmt.Errorf("failed to set %s: %w", name, err)
+		}
+	}
+
+	return tx, nil
+}
+
+// GetVectorIndexHealth reports the state of the ANN indexes on chunk
+// embeddings, including any build in progress
+func (c *PgxClient) GetVectorIndexHealth(ctx context.Context) (*models.VectorIndexHealth, error) {
+	health := &models.VectorIndexHealth{
+		Method:    c.indexConfig.Method,
+		Dimension: c.indexConfig.Dimension,
+	}
+
+	c.searchMu.RLock()
+	params := c.searchParams
+	c.searchMu.RUnlock()
+	switch params.method {
+	case IndexMethodHNSW:
+		health.EfSearch = params.efSearch
+	case IndexMethodIVFFlat:
+		health.Probes = params.probes
+	}
+
+	if err := c.pool.QueryRow(ctx, `
+		SELECT GREATEST(reltuples, 0)::bigint FROM pg_class WHERE oid = 'chunks'::regclass
+	`).Scan(&health.EstimatedChunks); err != nil {
+		return nil, fmt.Errorf("failed to estimate chunk count: %w", err)
+	}
+
+	rows, err := c.pool.Query(ctx, `
+		SELECT ic.relname, am.amname, `+indexDimensionSQL+`, i.indisvalid, pg_relation_size(ic.oid),
+			COALESCE(s.idx_scan, 0), COALESCE(ic.reloptions, '{}')
+		FROM pg_index i
+		JOIN pg_class ic ON ic.oid = i.indexrelid
+		JOIN pg_am am ON am.oid = ic.relam
+		LEFT JOIN pg_stat_user_indexes s ON s.indexrelid = i.indexrelid
+		WHERE i.indrelid = 'chunks'::regclass AND am.amname IN ('hnsw', 'ivfflat')
+		ORDER BY ic.relname
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vector index health: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var index models.VectorIndexStatus
+		if err := rows.Scan(&index.Name, &index.Method, &index.Dimension, &index.Valid, &index.SizeBytes, &index.Scans, &index.Options); err != nil {
+			return nil, fmt.Errorf("failed to scan vector index health: %w", err)
+		}
+		health.Indexes = append(health.Indexes, index)
+		if index.Valid && index.Method == params.method && index.Dimension == c.indexConfig.Dimension {
+			health.Ready = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating vector index health: %w", err)
+	}
+
+	// A concurrent build shows up here until it finishes
+	var phase string
+	var done, total int64
+	err = c.pool.QueryRow(ctx, `
+		SELECT p.phase,
+			CASE WHEN p.blocks_total > 0 THEN p.blocks_done ELSE p.tuples_done END,
+			CASE WHEN p.blocks_total > 0 THEN p.blocks_total ELSE p.tuples_total END
+		FROM pg_stat_progress_create_index p
+		WHERE p.relid = 'chunks'::regclass
+		LIMIT 1
+	`).Scan(&phase, &done, &total)
+	switch {
+	case err == nil:
+		health.Building = true
+		health.BuildPhase = phase
+		if total > 0 {
+			health.BuildProgress = float64(done) / float64(total)
+		}
+	case err != pgx.ErrNoRows:
+		return nil, fmt.Errorf("failed to read index build progress: %w", err)
+	}
+
+	return health, nil
+}
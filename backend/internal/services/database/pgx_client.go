@@ -2,8 +2,11 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -14,9 +17,29 @@ import (
 	"go.uber.org/zap"
 )
 
+var (
+	// ErrChunkQuotaExceeded is returned when storing a document's chunks
+	// would take its owner over their chunk quota
+	ErrChunkQuotaExceeded = errors.New("chunk quota exceeded")
+	// ErrEmbeddingQuotaExceeded is returned when embedding would take a user
+	// over their monthly embedding token quota
+	ErrEmbeddingQuotaExceeded = errors.New("monthly embedding token quota exceeded")
+	// ErrDocumentQuotaExceeded is returned when adding a document would take
+	// its owner over their document quota
+	ErrDocumentQuotaExceeded = errors.New("document quota exceeded")
+	// ErrStorageQuotaExceeded is returned when adding a document would take
+	// its owner over their storage quota
+	ErrStorageQuotaExceeded = errors.New("storage quota exceeded")
+)
+
 // PgxClient represents the pgx database client for vector operations
 type PgxClient struct {
 	pool *pgxpool.Pool
+
+	// Vector index settings, set by the VectorIndexManager
+	indexConfig  VectorIndexConfig
+	searchMu     sync.RWMutex
+	searchParams vectorSearchParams
 }
 
 // NewPgxClient creates a new pgx client for vector operations
@@ -67,11 +90,14 @@ func (c *PgxClient) GetPool() *pgxpool.Pool {
 	return c.pool
 }
 
-// SearchSimilarChunks performs vector similarity search
-func (c *PgxClient) SearchSimilarChunks(ctx context.Context, embedding []float32, userID string, limit int) ([]ChunkResult, error) {
+// SearchSimilarChunks performs vector similarity search over chunks embedded
+// with the given model version
+func (c *PgxClient) SearchSimilarChunks(ctx context.Context, embedding []float32, version EmbeddingVersion, userID string, limit int) ([]ChunkResult, error) {
 	logger := utils.GetLogger()
 
-	query := `
+	// The dimension is inlined rather than bound, so the planner can match
+	// the partial index built for it
+	query := fmt.Sprintf(`
 		SELECT 
 			c.id,
 			c.document_id,
@@ -80,18 +106,27 @@ func (c *PgxClient) SearchSimilarChunks(ctx context.Context, embedding []float32
 			c.metadata,
 			d.title,
 			d.source_url,
-			c.embedding <=> $1 as distance
+			%[1]s <=> $1 as distance
 		FROM chunks c
 		JOIN documents d ON c.document_id = d.id
 		WHERE d.user_id = $2
-		ORDER BY c.embedding <=> $1
+			AND c.embedding_model = $4 AND c.embedding_version = $5
+			AND c.embedding_dimension = %[2]d
+		ORDER BY %[1]s <=> $1
 		LIMIT $3
-	`
+	`, embeddingExpr("c.embedding", version.Dimension), version.Dimension)
 
 	// Convert embedding to pgvector format
 	vec := pgvector.NewVector(embedding)
 
-	rows, err := c.pool.Query(ctx, query, vec, userID, limit)
+	tx, err := c.beginVectorSearch(ctx, limit)
+	if err != nil {
+		logger.Error("Failed to search similar chunks", zap.Error(err))
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, query, vec, userID, limit, version.Model, version.Version)
 	if err != nil {
 		logger.Error("Failed to search similar chunks", zap.Error(err))
 		return nil, fmt.Errorf("failed to search similar chunks: %w", err)
@@ -131,31 +166,40 @@ func (c *PgxClient) SearchSimilarChunks(ctx context.Context, embedding []float32
 	return results, nil
 }
 
-// InsertChunks inserts multiple chunks with embeddings in a batch
-func (c *PgxClient) InsertChunks(ctx context.Context, chunks []ChunkInsert) error {
+// ReplaceDocumentChunks atomically replaces all chunks of a document, so a
+// retried ingestion never leaves duplicate chunks from an earlier attempt. It
+// returns ErrChunkQuotaExceeded, storing nothing, if the owner would end up
+// with more than chunkLimit chunks; a nil limit is unlimited.
+func (c *PgxClient) ReplaceDocumentChunks(ctx context.Context, documentID string, chunks []ChunkInsert, chunkLimit *int64) error {
 	logger := utils.GetLogger()
 
-	if len(chunks) == 0 {
-		return nil
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "DELETE FROM chunks WHERE document_id = $1", documentID); err != nil {
+		logger.Error("Failed to delete existing chunks", zap.Error(err), zap.String("document_id", documentID))
+		return fmt.Errorf("failed to delete existing chunks: %w", err)
 	}
 
 	query := `
-		INSERT INTO chunks (document_id, ordinal, content, embedding, metadata)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO chunks (document_id, ordinal, content, embedding, metadata, embedding_model, embedding_version, embedding_dimension)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 	`
 
 	batch := &pgx.Batch{}
 	for _, chunk := range chunks {
 		vec := pgvector.NewVector(chunk.Embedding)
-		batch.Queue(query, chunk.DocumentID, chunk.Ordinal, chunk.Content, vec, chunk.Metadata)
+		batch.Queue(query, chunk.DocumentID, chunk.Ordinal, chunk.Content, vec, chunk.Metadata,
+			chunk.EmbeddingModel, chunk.EmbeddingVersion, len(chunk.Embedding))
 	}
 
-	results := c.pool.SendBatch(ctx, batch)
-	defer results.Close()
-
+	results := tx.SendBatch(ctx, batch)
 	for i := 0; i < len(chunks); i++ {
-		_, err := results.Exec()
-		if err != nil {
+		if _, err := results.Exec(); err != nil {
+			results.Close()
 			logger.Error("Failed to insert chunk",
 				zap.Error(err),
 				zap.Int("chunk_index", i),
@@ -163,25 +207,92 @@ func (c *PgxClient) InsertChunks(ctx context.Context, chunks []ChunkInsert) erro
 			return fmt.Errorf("failed to insert chunk %d: %w", i, err)
 		}
 	}
+	if err := results.Close(); err != nil {
+		return fmt.Errorf("failed to insert chunks: %w", err)
+	}
+
+	if err := enforceChunkQuota(ctx, tx, documentID, chunkLimit); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit chunks: %w", err)
+	}
 
-	logger.Info("Chunks inserted successfully", zap.Int("count", len(chunks)))
+	logger.Info("Document chunks replaced successfully",
+		zap.String("document_id", documentID),
+		zap.Int("count", len(chunks)),
+	)
 	return nil
 }
 
+// CopyDocumentChunks atomically replaces the chunks of a document with copies
+// of another document's chunks embedded with the given model version,
+// embeddings included. The source may belong to another user, so the
+// per-document metadata it carries is replaced with the given filename and
+// its file type. It returns the number of chunks copied, or
+// ErrChunkQuotaExceeded as ReplaceDocumentChunks does.
+func (c *PgxClient) CopyDocumentChunks(ctx context.Context, sourceDocumentID, documentID, filename string, version EmbeddingVersion, chunkLimit *int64) (int64, error) {
+	logger := utils.GetLogger()
+
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "DELETE FROM chunks WHERE document_id = $1", documentID); err != nil {
+		logger.Error("Failed to delete existing chunks", zap.Error(err), zap.String("document_id", documentID))
+		return 0, fmt.Errorf("failed to delete existing chunks: %w", err)
+	}
+
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO chunks (document_id, ordinal, content, embedding, metadata, embedding_model, embedding_version, embedding_dimension)
+		SELECT $1, ordinal, content, embedding,
+			metadata || jsonb_build_object('filename', $5::text, 'file_type', $6::text),
+			embedding_model, embedding_version, embedding_dimension
+		FROM chunks
+		WHERE document_id = $2 AND embedding_model = $3 AND embedding_version = $4
+	`, documentID, sourceDocumentID, version.Model, version.Version, filename, strings.ToLower(filepath.Ext(filename)))
+	if err != nil {
+		logger.Error("Failed to copy chunks",
+			zap.Error(err),
+			zap.String("source_document_id", sourceDocumentID),
+			zap.String("document_id", documentID),
+		)
+		return 0, fmt.Errorf("failed to copy chunks: %w", err)
+	}
+
+	if err := enforceChunkQuota(ctx, tx, documentID, chunkLimit); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit chunks: %w", err)
+	}
+
+	logger.Info("Document chunks copied successfully",
+		zap.String("source_document_id", sourceDocumentID),
+		zap.String("document_id", documentID),
+		zap.Int64("count", tag.RowsAffected()),
+	)
+	return tag.RowsAffected(), nil
+}
+
 // SearchSimilarChunksInDocuments performs vector similarity search within specific documents
-func (c *PgxClient) SearchSimilarChunksInDocuments(ctx context.Context, embedding []float32, userID string, documentIDs []string, limit int) ([]ChunkResult, error) {
+func (c *PgxClient) SearchSimilarChunksInDocuments(ctx context.Context, embedding []float32, version EmbeddingVersion, userID string, documentIDs []string, limit int) ([]ChunkResult, error) {
 	logger := utils.GetLogger()
 
 	if len(documentIDs) == 0 {
-		return c.SearchSimilarChunks(ctx, embedding, userID, limit)
+		return c.SearchSimilarChunks(ctx, embedding, version, userID, limit)
 	}
 
 	// Build placeholders for document IDs
 	placeholders := make([]string, len(documentIDs))
-	args := []interface{}{pgvector.NewVector(embedding), userID}
+	args := []interface{}{pgvector.NewVector(embedding), userID, version.Model, version.Version}
 	
 	for i, docID := range documentIDs {
-		placeholders[i] = fmt.Sprintf("$%d", i+3)
+		placeholders[i] = fmt.Sprintf("$%d", i+5)
 		args = append(args, docID)
 	}
 
@@ -194,15 +305,24 @@ func (c *PgxClient) SearchSimilarChunksInDocuments(ctx context.Context, embeddin
 			c.metadata,
 			d.title,
 			d.source_url,
-			c.embedding <=> $1 as distance
+			%[1]s <=> $1 as distance
 		FROM chunks c
 		JOIN documents d ON c.document_id = d.id
-		WHERE d.user_id = $2 AND d.id IN (%s)
-		ORDER BY c.embedding <=> $1
-		LIMIT %d
-	`, strings.Join(placeholders, ","), limit)
+		WHERE d.user_id = $2 AND d.id IN (%[3]s)
+			AND c.embedding_model = $3 AND c.embedding_version = $4
+			AND c.embedding_dimension = %[2]d
+		ORDER BY %[1]s <=> $1
+		LIMIT %[4]d
+	`, embeddingExpr("c.embedding", version.Dimension), version.Dimension, strings.Join(placeholders, ","), limit)
+
+	tx, err := c.beginVectorSearch(ctx, limit)
+	if err != nil {
+		logger.Error("Failed to search similar chunks in documents", zap.Error(err))
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
 
-	rows, err := c.pool.Query(ctx, query, args...)
+	rows, err := tx.Query(ctx, query, args...)
 	if err != nil {
 		logger.Error("Failed to search similar chunks in documents", zap.Error(err))
 		return nil, fmt.Errorf("failed to search similar chunks in documents: %w", err)
@@ -243,6 +363,357 @@ func (c *PgxClient) SearchSimilarChunksInDocuments(ctx context.Context, embeddin
 	return results, nil
 }
 
+// ListStaleChunks returns the chunks of a document that were not embedded with
+// the given model version, ordered by ordinal. Embeddings are not loaded.
+func (c *PgxClient) ListStaleChunks(ctx context.Context, documentID string, version EmbeddingVersion) ([]ChunkEmbedding, error) {
+	rows, err := c.pool.Query(ctx, `
+		SELECT id, content
+		FROM chunks
+		WHERE document_id = $1
+			AND (embedding_model IS DISTINCT FROM $2 OR embedding_version IS DISTINCT FROM $3)
+		ORDER BY ordinal
+	`, documentID, version.Model, version.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stale chunks: %w", err)
+	}
+	defer rows.Close()
+
+	var chunks []ChunkEmbedding
+	for rows.Next() {
+		var chunk ChunkEmbedding
+		if err := rows.Scan(&chunk.ID, &chunk.Content); err != nil {
+			return nil, fmt.Errorf("failed to scan chunk: %w", err)
+		}
+		chunks = append(chunks, chunk)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating chunks: %w", err)
+	}
+
+	return chunks, nil
+}
+
+// UpdateChunkEmbeddings sets the embeddings of existing chunks to vectors from
+// the given model version in one transaction. Chunks are updated in place, so
+// ones already current and ones without a new vector are left untouched.
+func (c *PgxClient) UpdateChunkEmbeddings(ctx context.Context, chunks []ChunkEmbedding, version EmbeddingVersion) (int64, error) {
+	if len(chunks) == 0 {
+		return 0, nil
+	}
+
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	batch := &pgx.Batch{}
+	for _, chunk := range chunks {
+		batch.Queue(`
+			UPDATE chunks
+			SET embedding = $2, embedding_model = $3, embedding_version = $4, embedding_dimension = $5
+			WHERE id = $1
+		`, chunk.ID, pgvector.NewVector(chunk.Embedding), version.Model, version.Version, len(chunk.Embedding))
+	}
+
+	results := tx.SendBatch(ctx, batch)
+	var updated int64
+	for i := range chunks {
+		tag, err := results.Exec()
+		if err != nil {
+			results.Close()
+			return 0, fmt.Errorf("failed to update chunk %s: %w", chunks[i].ID, err)
+		}
+		updated += tag.RowsAffected()
+	}
+	if err := results.Close(); err != nil {
+		return 0, fmt.Errorf("failed to update chunk embeddings: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit chunk embeddings: %w", err)
+	}
+	return updated, nil
+}
+
+// ListDocumentsNeedingReembed returns the user's completed documents that
+// still have chunks from another model version and no re-embedding job
+// already waiting or running
+func (c *PgxClient) ListDocumentsNeedingReembed(ctx context.Context, userID string, version EmbeddingVersion, jobKind string) ([]string, error) {
+	rows, err := c.pool.Query(ctx, `
+		SELECT d.id
+		FROM documents d
+		WHERE d.user_id = $1
+			AND d.processing_status = 'completed'
+			AND EXISTS (
+				SELECT 1 FROM chunks c
+				WHERE c.document_id = d.id
+					AND (c.embedding_model IS DISTINCT FROM $2 OR c.embedding_version IS DISTINCT FROM $3)
+			)
+			AND NOT EXISTS (
+				SELECT 1 FROM ingestion_jobs j
+				WHERE j.document_id = d.id AND j.kind = $4 AND j.status IN ('queued', 'running')
+			)
+		ORDER BY d.created_at
+	`, userID, version.Model, version.Version, jobKind)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents needing re-embedding: %w", err)
+	}
+	defer rows.Close()
+
+	var documentIDs []string
+	for rows.Next() {
+		var documentID string
+		if err := rows.Scan(&documentID); err != nil {
+			return nil, fmt.Errorf("failed to scan document id: %w", err)
+		}
+		documentIDs = append(documentIDs, documentID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating documents: %w", err)
+	}
+
+	return documentIDs, nil
+}
+
+// GetReindexProgress reports how much of a user's corpus has been embedded
+// with the given model version, and the state of its re-embedding jobs
+func (c *PgxClient) GetReindexProgress(ctx context.Context, userID string, version EmbeddingVersion, jobKind string) (*ReindexProgress, error) {
+	progress := &ReindexProgress{}
+	err := c.pool.QueryRow(ctx, `
+		SELECT
+			COUNT(*),
+			COUNT(*) FILTER (WHERE NOT EXISTS (
+				SELECT 1 FROM chunks c
+				WHERE c.document_id = d.id
+					AND (c.embedding_model IS DISTINCT FROM $2 OR c.embedding_version IS DISTINCT FROM $3)
+			)),
+			(SELECT COUNT(*) FROM ingestion_jobs j JOIN documents jd ON j.document_id = jd.id
+				WHERE jd.user_id = $1 AND j.kind = $4 AND j.status = 'queued'),
+			(SELECT COUNT(*) FROM ingestion_jobs j JOIN documents jd ON j.document_id = jd.id
+				WHERE jd.user_id = $1 AND j.kind = $4 AND j.status = 'running'),
+			(SELECT COUNT(DISTINCT j.document_id) FROM ingestion_jobs j JOIN documents jd ON j.document_id = jd.id
+				WHERE jd.user_id = $1 AND j.kind = $4 AND j.status = 'dead'
+					AND EXISTS (
+						SELECT 1 FROM chunks c
+						WHERE c.document_id = j.document_id
+							AND (c.embedding_model IS DISTINCT FROM $2 OR c.embedding_version IS DISTINCT FROM $3)
+					))
+		FROM documents d
+		WHERE d.user_id = $1 AND d.processing_status = 'completed'
+	`, userID, version.Model, version.Version, jobKind).Scan(
+		&progress.TotalDocuments,
+		&progress.CurrentDocuments,
+		&progress.QueuedJobs,
+		&progress.RunningJobs,
+		&progress.FailedJobs,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reindex progress: %w", err)
+	}
+
+	return progress, nil
+}
+
+// ReferencedStoragePaths reports which of the given storage paths belong to
+// a document
+func (c *PgxClient) ReferencedStoragePaths(ctx context.Context, paths []string) (map[string]bool, error) {
+	rows, err := c.pool.Query(ctx, `
+		SELECT storage_path
+		FROM documents
+		WHERE storage_path = ANY($1)
+	`, paths)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up storage paths: %w", err)
+	}
+	defer rows.Close()
+
+	referenced := make(map[string]bool, len(paths))
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, fmt.Errorf("failed to scan storage path: %w", err)
+		}
+		referenced[path] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating storage paths: %w", err)
+	}
+
+	return referenced, nil
+}
+
+// GetUserUsage returns what a user has stored and the embedding tokens used
+// in the current calendar month (UTC)
+func (c *PgxClient) GetUserUsage(ctx context.Context, userID string) (*UserUsage, error) {
+	usage := &UserUsage{}
+	err := c.pool.QueryRow(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM documents WHERE user_id = $1),
+			(SELECT COALESCE(SUM(size), 0) FROM documents WHERE user_id = $1),
+			(SELECT COUNT(*) FROM chunks ch JOIN documents d ON d.id = ch.document_id WHERE d.user_id = $1),
+			(SELECT COALESCE(SUM(tokens), 0) FROM embedding_usage
+			 WHERE user_id = $1 AND month = date_trunc('month', NOW() AT TIME ZONE 'UTC')::date)
+	`, userID).Scan(&usage.Documents, &usage.StorageBytes, &usage.Chunks, &usage.EmbeddingTokens)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user usage: %w", err)
+	}
+	return usage, nil
+}
+
+// InsertDocument records a new document, queued for processing, unless it
+// would take its owner over documentLimit documents or storageLimit bytes,
+// in which case it returns ErrDocumentQuotaExceeded or
+// ErrStorageQuotaExceeded and stores nothing. The owner's row is locked
+// while usage is counted, so concurrent uploads for one user are checked one
+// after another. Nil limits are unlimited.
+func (c *PgxClient) InsertDocument(ctx context.Context, doc DocumentInsert, documentLimit, storageLimit *int64) error {
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if documentLimit != nil || storageLimit != nil {
+		if _, err := tx.Exec(ctx, "SELECT id FROM users WHERE id = $1 FOR NO KEY UPDATE", doc.UserID); err != nil {
+			return fmt.Errorf("failed to lock document owner: %w", err)
+		}
+
+		var documents, storage int64
+		err := tx.QueryRow(ctx, `
+			SELECT COUNT(*), COALESCE(SUM(size), 0)
+			FROM documents
+			WHERE user_id = $1
+		`, doc.UserID).Scan(&documents, &storage)
+		if err != nil {
+			return fmt.Errorf("failed to count documents: %w", err)
+		}
+
+		if documentLimit != nil && documents+1 > *documentLimit {
+			return ErrDocumentQuotaExceeded
+		}
+		if storageLimit != nil && storage+doc.Size > *storageLimit {
+			return ErrStorageQuotaExceeded
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO documents (id, user_id, title, source_url, storage_path, mime_type, size, checksum, batch_id, processing_status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, '')::uuid, 'queued')
+	`, doc.ID, doc.UserID, doc.Title, doc.SourceURL, doc.StoragePath, doc.MimeType, doc.Size, doc.Checksum, doc.BatchID)
+	if err != nil {
+		return fmt.Errorf("failed to insert document: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit document: %w", err)
+	}
+	return nil
+}
+
+// enforceChunkQuota returns ErrChunkQuotaExceeded if, counting the chunks
+// written so far in tx, the document's owner has more than limit chunks. The
+// owner's row is locked first, so concurrent ingestions for one user check
+// their totals one after another and cannot both slip under the limit.
+func enforceChunkQuota(ctx context.Context, tx pgx.Tx, documentID string, limit *int64) error {
+	if limit == nil {
+		return nil
+	}
+
+	var userID string
+	err := tx.QueryRow(ctx, `
+		SELECT u.id
+		FROM users u
+		JOIN documents d ON d.user_id = u.id
+		WHERE d.id = $1
+		FOR NO KEY UPDATE OF u
+	`, documentID).Scan(&userID)
+	if err != nil {
+		return fmt.Errorf("failed to lock document owner: %w", err)
+	}
+
+	var chunks int64
+	err = tx.QueryRow(ctx, `
+		SELECT COUNT(*)
+		FROM chunks ch
+		JOIN documents d ON d.id = ch.document_id
+		WHERE d.user_id = $1
+	`, userID).Scan(&chunks)
+	if err != nil {
+		return fmt.Errorf("failed to count chunks: %w", err)
+	}
+
+	if chunks > *limit {
+		return ErrChunkQuotaExceeded
+	}
+	return nil
+}
+
+// ReserveEmbeddingTokens adds tokens about to be embedded for a document to
+// its owner's usage for the current month, unless that would exceed limit,
+// in which case it returns ErrEmbeddingQuotaExceeded and records nothing. The
+// check and the increment are one statement, so concurrent jobs cannot both
+// pass it. A nil limit is unlimited.
+func (c *PgxClient) ReserveEmbeddingTokens(ctx context.Context, documentID string, tokens int, limit *int64) error {
+	if tokens <= 0 {
+		return nil
+	}
+
+	var total int64
+	err := c.pool.QueryRow(ctx, `
+		INSERT INTO embedding_usage (user_id, month, tokens)
+		SELECT user_id, date_trunc('month', NOW() AT TIME ZONE 'UTC')::date, $2
+		FROM documents
+		WHERE id = $1 AND ($3::bigint IS NULL OR $2 <= $3)
+		ON CONFLICT (user_id, month) DO UPDATE
+		SET tokens = embedding_usage.tokens + EXCLUDED.tokens, updated_at = NOW()
+		WHERE $3::bigint IS NULL OR embedding_usage.tokens + EXCLUDED.tokens <= $3
+		RETURNING tokens
+	`, documentID, tokens, limit).Scan(&total)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrEmbeddingQuotaExceeded
+	}
+	if err != nil {
+		return fmt.Errorf("failed to reserve embedding tokens: %w", err)
+	}
+	return nil
+}
+
+// ReleaseEmbeddingTokens returns tokens reserved for a document that were
+// not embedded after all
+func (c *PgxClient) ReleaseEmbeddingTokens(ctx context.Context, documentID string, tokens int) error {
+	if tokens <= 0 {
+		return nil
+	}
+	_, err := c.pool.Exec(ctx, `
+		UPDATE embedding_usage
+		SET tokens = GREATEST(tokens - $2, 0), updated_at = NOW()
+		WHERE user_id = (SELECT user_id FROM documents WHERE id = $1)
+			AND month = date_trunc('month', NOW() AT TIME ZONE 'UTC')::date
+	`, documentID, tokens)
+	if err != nil {
+		return fmt.Errorf("failed to release embedding tokens: %w", err)
+	}
+	return nil
+}
+
+// UserUsage is a user's resource consumption
+type UserUsage struct {
+	Documents       int64
+	StorageBytes    int64
+	Chunks          int64
+	EmbeddingTokens int64 // this calendar month
+}
+
+// ReindexProgress represents the re-embedding state of a user's corpus
+type ReindexProgress struct {
+	TotalDocuments   int `json:"total_documents"`
+	CurrentDocuments int `json:"current_documents"` // Fully embedded with the active model
+	QueuedJobs       int `json:"queued_jobs"`
+	RunningJobs      int `json:"running_jobs"`
+	FailedJobs       int `json:"failed_jobs"` // Documents whose re-embedding was dead-lettered
+}
+
 // ChunkResult represents a search result
 type ChunkResult struct {
 	ID            string      `json:"id"`
@@ -253,13 +724,77 @@ type ChunkResult struct {
 	DocumentTitle string      `json:"document_title"`
 	SourceURL     *string     `json:"source_url"`
 	Distance      float64     `json:"distance"`
+	// Set by keyword and hybrid searches
+	KeywordRank float64 `json:"keyword_rank,omitempty"`
+	Score       float64 `json:"score,omitempty"`
+	// Set when the reranking stage scored the chunk
+	RerankScore *float64 `json:"rerank_score,omitempty"`
+	// Set for a keyword match in a document not yet re-embedded with the
+	// active model; its distance is not meaningful
+	Stale bool `json:"stale,omitempty"`
+}
+
+// DocumentInsert represents data for inserting a document
+type DocumentInsert struct {
+	ID          string
+	UserID      string
+	Title       string
+	SourceURL   string
+	StoragePath string
+	MimeType    string
+	Size        int64
+	Checksum    string
+	BatchID     string // empty when the document is not part of a batch
 }
 
 // ChunkInsert represents data for inserting a chunk
 type ChunkInsert struct {
-	DocumentID string      `json:"document_id"`
-	Ordinal    int         `json:"ordinal"`
-	Content    string      `json:"content"`
-	Embedding  []float32   `json:"embedding"`
-	Metadata   interface{} `json:"metadata"`
+	DocumentID       string      `json:"document_id"`
+	Ordinal          int         `json:"ordinal"`
+	Content          string      `json:"content"`
+	Embedding        []float32   `json:"embedding"`
+	Metadata         interface{} `json:"metadata"`
+	EmbeddingModel   string      `json:"embedding_model"`
+	EmbeddingVersion string      `json:"embedding_version"`
+}
+
+// ChunkEmbedding is a stored chunk's text and a new embedding for it
+type ChunkEmbedding struct {
+	ID        string
+	Content   string
+	Embedding []float32
+}
+
+// EmbeddingVersion identifies the model that produced a set of vectors.
+// Vectors from different versions are not comparable and are never searched
+// together.
+type EmbeddingVersion struct {
+	Model     string `json:"model"`
+	Version   string `json:"version"`
+	Dimension int    `json:"dimension"`
+}
+
+// embeddingExpr casts an embedding column to a fixed dimension. The column
+// holds vectors of any dimension, and ANN indexes are built on this
+// expression, so queries must use it for the planner to match them.
+func embeddingExpr(column string, dimension int) string {
+	return fmt.Sprintf("%s::vector(%d)", column, dimension)
+}
+
+// CheckEmbeddingDimension verifies that chunks.embedding can hold vectors of
+// the given dimension: either it was migrated to an unconstrained vector
+// column or its fixed width matches
+func (c *PgxClient) CheckEmbeddingDimension(ctx context.Context, dimension int) error {
+	var typmod int
+	err := c.pool.QueryRow(ctx, `
+		SELECT atttypmod FROM pg_attribute
+		WHERE attrelid = 'chunks'::regclass AND attname = 'embedding'
+	`).Scan(&typmod)
+	if err != nil {
+		return fmt.Errorf("failed to read chunks.embedding type: %w", err)
+	}
+	if typmod != -1 && typmod != dimension {
+		return fmt.Errorf("chunks.embedding is vector(%d) but EMBEDDING_DIMENSION is %d; run migration.sql", typmod, dimension)
+	}
+	return nil
 }
@@ -73,7 +73,8 @@ CREATE TABLE IF NOT EXISTS chunks (
     document_id UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
     ordinal INT NOT NULL,
     content TEXT NOT NULL,
-    embedding vector(768),
+    -- Any dimension; ANN indexes are built per dimension (EMBEDDING_DIMENSION)
+    embedding vector,
     metadata JSONB,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
@@ -98,7 +99,7 @@ CREATE TABLE IF NOT EXISTS chat_messages (
 -- RAG Indexes
 CREATE INDEX IF NOT EXISTS idx_documents_user_id ON documents(user_id);
 CREATE INDEX IF NOT EXISTS idx_chunks_document_id ON chunks(document_id);
-CREATE INDEX IF NOT EXISTS idx_chunks_embedding_cosine ON chunks USING ivfflat (embedding vector_cosine_ops) WITH (lists=100);
+-- The ANN index on chunks.embedding is built and maintained by the API (VECTOR_INDEX_METHOD)
 CREATE INDEX IF NOT EXISTS idx_chats_user_id_created_at ON chats(user_id, created_at DESC);
 CREATE INDEX IF NOT EXISTS idx_chat_messages_chat_id_created_at ON chat_messages(chat_id, created_at DESC);
 
@@ -0,0 +1,319 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/kinyichukwu/edu-pro-backend/internal/utils"
+	"github.com/pgvector/pgvector-go"
+	"go.uber.org/zap"
+)
+
+// rrfK is the reciprocal rank fusion constant; larger values flatten the
+// advantage of the top ranks
+const rrfK = 60
+
+// searchCandidateFactor is how many more candidates than requested each
+// ranking contributes to a hybrid search before fusion
+const searchCandidateFactor = 4
+
+// HybridSearch describes a search over a user's chunks that combines vector
+// similarity with Postgres full-text search
+type HybridSearch struct {
+	Query       string
+	Embedding   []float32 // may be nil for a pure keyword search
+	Version     EmbeddingVersion
+	UserID      string
+	DocumentIDs []string // optional; all of the user's documents when empty
+	Limit       int
+	// KeywordWeight weighs the keyword ranking against the vector ranking:
+	// 0 is a pure vector search, 1 a pure keyword search
+	KeywordWeight float64
+	// MaxDistance drops results further than this cosine distance from the
+	// query embedding; 0 keeps everything. Only applies with an embedding.
+	MaxDistance float64
+}
+
+// HybridSearchChunks runs the vector and keyword searches a search's
+// weighting calls for and merges their rankings with weighted reciprocal
+// rank fusion
+func (c *PgxClient) HybridSearchChunks(ctx context.Context, search HybridSearch) ([]ChunkResult, error) {
+	logger := utils.GetLogger()
+
+	switch {
+	case search.KeywordWeight <= 0:
+		results, err := c.SearchSimilarChunksInDocuments(ctx, search.Embedding, search.Version, search.UserID, search.DocumentIDs, search.Limit)
+		if err != nil {
+			return nil, err
+		}
+		results = search.withinDistance(results)
+
+		// Documents not yet re-embedded with the active model have no
+		// comparable vectors, so they can only be found by keyword
+		keywordResults, err := c.SearchKeywordChunks(ctx, search.Query, nil, search.Version, search.UserID, search.DocumentIDs, search.Limit)
+		if err != nil {
+			return nil, err
+		}
+		stale := keywordResults[:0]
+		for _, result := range keywordResults {
+			if result.Stale {
+				stale = append(stale, result)
+			}
+		}
+		if len(stale) == 0 {
+			return results, nil
+		}
+		return fuseRankings([][]ChunkResult{results, stale}, []float64{1, 1}, search.Limit), nil
+	case search.KeywordWeight >= 1:
+		results, err := c.SearchKeywordChunks(ctx, search.Query, search.Embedding, search.Version, search.UserID, search.DocumentIDs, search.Limit)
+		if err != nil {
+			return nil, err
+		}
+		return search.withinDistance(results), nil
+	}
+
+	candidates := search.Limit * searchCandidateFactor
+	vectorResults, err := c.SearchSimilarChunksInDocuments(ctx, search.Embedding, search.Version, search.UserID, search.DocumentIDs, candidates)
+	if err != nil {
+		return nil, err
+	}
+	keywordResults, err := c.SearchKeywordChunks(ctx, search.Query, search.Embedding, search.Version, search.UserID, search.DocumentIDs, candidates)
+	if err != nil {
+		return nil, err
+	}
+	vectorResults = search.withinDistance(vectorResults)
+	keywordResults = search.withinDistance(keywordResults)
+
+	results := fuseRankings(
+		[][]ChunkResult{vectorResults, keywordResults},
+		[]float64{1 - search.KeywordWeight, search.KeywordWeight},
+		search.Limit,
+	)
+
+	logger.Info("Hybrid chunk search completed",
+		zap.Int("vector_results", len(vectorResults)),
+		zap.Int("keyword_results", len(keywordResults)),
+		zap.Int("results_count", len(results)),
+		zap.Float64("keyword_weight", search.KeywordWeight),
+		zap.String("user_id", search.UserID),
+	)
+
+	return results, nil
+}
+
+// withinDistance drops results further from the query than the search's
+// MaxDistance. A keyword match is held to the same bar, as a single shared
+// common word is not enough to make a chunk relevant. Stale results have no
+// distance to judge and are kept.
+func (search HybridSearch) withinDistance(results []ChunkResult) []ChunkResult {
+	if search.MaxDistance <= 0 || search.Embedding == nil {
+		return results
+	}
+
+	kept := results[:0]
+	for _, result := range results {
+		if result.Stale || result.Distance <= search.MaxDistance {
+			kept = append(kept, result)
+		}
+	}
+	return kept
+}
+
+// GetChunkEmbeddings loads the embeddings of the given chunks, keyed by chunk
+// id. Chunks without an embedding from the given version are left out, as
+// their vectors are not comparable with the rest.
+func (c *PgxClient) GetChunkEmbeddings(ctx context.Context, chunkIDs []string, version EmbeddingVersion) (map[string][]float32, error) {
+	embeddings := make(map[string][]float32, len(chunkIDs))
+	if len(chunkIDs) == 0 {
+		return embeddings, nil
+	}
+
+	rows, err := c.pool.Query(ctx, `
+		SELECT id, embedding::text
+		FROM chunks
+		WHERE id = ANY($1::uuid[]) AND embedding IS NOT NULL
+			AND embedding_model = $2 AND embedding_version = $3
+	`, chunkIDs, version.Model, version.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chunk embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var embedding pgvector.Vector
+		if err := rows.Scan(&id, &embedding); err != nil {
+			return nil, fmt.Errorf("failed to scan chunk embedding: %w", err)
+		}
+		embeddings[id] = embedding.Slice()
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating chunk embeddings: %w", err)
+	}
+
+	return embeddings, nil
+}
+
+// SearchKeywordChunks ranks chunks by full-text match against the query.
+// Any of the query's terms may match, so a course code or rare term finds
+// its chunks even when the rest of the question doesn't appear in them.
+// When an embedding is given, each result's vector distance is filled in.
+// Documents with no chunks embedded with the given version yet, such as
+// while a re-index to a new model runs, are searched by keyword alone and
+// their results marked stale.
+func (c *PgxClient) SearchKeywordChunks(ctx context.Context, query string, embedding []float32, version EmbeddingVersion, userID string, documentIDs []string, limit int) ([]ChunkResult, error) {
+	logger := utils.GetLogger()
+
+	terms := keywordQuery(query)
+	if terms == "" {
+		return nil, nil
+	}
+	args := []interface{}{terms, userID, version.Model, version.Version, limit}
+
+	current := fmt.Sprintf("c.embedding_model = $3 AND c.embedding_version = $4 AND c.embedding_dimension = %d", version.Dimension)
+	distance := "0::float8"
+	if embedding != nil {
+		args = append(args, pgvector.NewVector(embedding))
+		distance = fmt.Sprintf("CASE WHEN %s THEN %s <=> $%d ELSE 0 END", current, embeddingExpr("c.embedding", version.Dimension), len(args))
+	}
+
+	documentFilter := ""
+	if len(documentIDs) > 0 {
+		args = append(args, documentIDs)
+		documentFilter = fmt.Sprintf("AND d.id = ANY($%d::uuid[])", len(args))
+	}
+
+	// The query's words are ORed together, so chunks are ranked by how well
+	// they match rather than dropped for a missing word
+	sql := fmt.Sprintf(`
+		WITH q AS (
+			SELECT to_tsquery('english', $1) AS query
+		)
+		SELECT
+			c.id,
+			c.document_id,
+			c.ordinal,
+			c.content,
+			c.metadata,
+			d.title,
+			d.source_url,
+			%[2]s as distance,
+			ts_rank_cd(c.content_tsv, q.query) as keyword_rank,
+			NOT (%[1]s) as stale
+		FROM chunks c
+		JOIN documents d ON c.document_id = d.id
+		CROSS JOIN q
+		WHERE d.user_id = $2
+			AND c.content_tsv @@ q.query
+			AND ((%[1]s) OR NOT EXISTS (
+				SELECT 1 FROM chunks cur
+				WHERE cur.document_id = c.document_id
+					AND cur.embedding_model = $3 AND cur.embedding_version = $4
+			))
+			%[3]s
+		ORDER BY keyword_rank DESC, c.ordinal
+		LIMIT $5
+	`, current, distance, documentFilter)
+
+	rows, err := c.pool.Query(ctx, sql, args...)
+	if err != nil {
+		logger.Error("Failed to search keyword chunks", zap.Error(err))
+		return nil, fmt.Errorf("failed to search keyword chunks: %w", err)
+	}
+	defer rows.Close()
+
+	var results []ChunkResult
+	for rows.Next() {
+		var result ChunkResult
+		err := rows.Scan(
+			&result.ID,
+			&result.DocumentID,
+			&result.Ordinal,
+			&result.Content,
+			&result.Metadata,
+			&result.DocumentTitle,
+			&result.SourceURL,
+			&result.Distance,
+			&result.KeywordRank,
+			&result.Stale,
+		)
+		if err != nil {
+			logger.Error("Failed to scan chunk result", zap.Error(err))
+			continue
+		}
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Error("Error iterating chunk results", zap.Error(err))
+		return nil, fmt.Errorf("error iterating chunk results: %w", err)
+	}
+
+	logger.Info("Keyword chunks search completed",
+		zap.Int("results_count", len(results)),
+		zap.String("user_id", userID),
+	)
+
+	return results, nil
+}
+
+// keywordQuery turns a question into a to_tsquery expression that ORs its
+// words together. Only letters and digits are kept, so punctuation and
+// backslashes can't break the tsquery syntax; to_tsquery then stems the
+// words and drops stopwords.
+func keywordQuery(query string) string {
+	words := strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, " | ")
+}
+
+// MergeSearches fuses the results of several searches for the same question,
+// such as paraphrases of it, with equally weighted reciprocal rank fusion
+func MergeSearches(searches [][]ChunkResult, limit int) []ChunkResult {
+	if len(searches) == 1 {
+		return searches[0]
+	}
+
+	weights := make([]float64, len(searches))
+	for i := range weights {
+		weights[i] = 1
+	}
+	return fuseRankings(searches, weights, limit)
+}
+
+// fuseRankings merges rankings with weighted reciprocal rank fusion: a chunk
+// scores weight/(rrfK+rank) in each ranking it appears in, summed. Chunks
+// found by both searches keep the vector result's distance and the keyword
+// result's rank.
+func fuseRankings(rankings [][]ChunkResult, weights []float64, limit int) []ChunkResult {
+	var fused []ChunkResult
+	index := make(map[string]int)
+
+	for r, ranking := range rankings {
+		for rank, result := range ranking {
+			score := weights[r] / float64(rrfK+rank+1)
+			i, ok := index[result.ID]
+			if !ok {
+				index[result.ID] = len(fused)
+				result.Score = score
+				fused = append(fused, result)
+				continue
+			}
+			fused[i].Score += score
+			if result.KeywordRank > fused[i].KeywordRank {
+				fused[i].KeywordRank = result.KeywordRank
+			}
+		}
+	}
+
+	// Stable, so ties keep the order of the earlier rankings
+	sort.SliceStable(fused, func(i, j int) bool { return fused[i].Score > fused[j].Score })
+	if len(fused) > limit {
+		fused = fused[:limit]
+	}
+	return fused
+}
@@ -0,0 +1,135 @@
+package database
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+func TestKeywordQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"words", "What is CSC 201 about?", "What | is | CSC | 201 | about"},
+		{"tsquery operators", "heat & (work | !entropy) <-> temp:*", "heat | work | entropy | temp"},
+		{"backslashes", `C:\Users\notes \\ escape\'s`, "C | Users | notes | escape | s"},
+		{"quotes", `"isn't" it 'quoted'`, "isn | t | it | quoted"},
+		{"hyphenated", "heat-transfer coefficients", "heat | transfer | coefficients"},
+		{"accented", "théorème de Gödel", "théorème | de | Gödel"},
+		{"punctuation only", `?!\\ -- '' ::`, ""},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := keywordQuery(tt.query)
+			if got != tt.want {
+				t.Errorf("keywordQuery(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+
+			// Nothing but words and the OR operator may reach to_tsquery
+			for _, word := range strings.Split(got, " | ") {
+				for _, r := range word {
+					if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+						t.Errorf("keywordQuery(%q) kept %q", tt.query, r)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestFuseRankings(t *testing.T) {
+	vector := []ChunkResult{
+		{ID: "a", Distance: 0.1},
+		{ID: "b", Distance: 0.2},
+		{ID: "c", Distance: 0.3},
+	}
+	keyword := []ChunkResult{
+		{ID: "c", KeywordRank: 0.9},
+		{ID: "d", KeywordRank: 0.5},
+		{ID: "a", KeywordRank: 0.1},
+	}
+
+	tests := []struct {
+		name    string
+		weights []float64
+		limit   int
+		want    []string
+	}{
+		{"equal weights favour chunks both searches found", []float64{0.5, 0.5}, 10, []string{"a", "c", "b", "d"}},
+		{"vector only", []float64{1, 0}, 10, []string{"a", "b", "c", "d"}},
+		{"keyword heavy", []float64{0.2, 0.8}, 10, []string{"c", "a", "d", "b"}},
+		{"limit", []float64{0.5, 0.5}, 2, []string{"a", "c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fused := fuseRankings([][]ChunkResult{vector, keyword}, tt.weights, tt.limit)
+			var ids []string
+			for _, result := range fused {
+				ids = append(ids, result.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("fuseRankings() = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestFuseRankingsMergesFields(t *testing.T) {
+	vector := []ChunkResult{{ID: "a", Distance: 0.1}}
+	keyword := []ChunkResult{{ID: "a", KeywordRank: 0.7}}
+
+	fused := fuseRankings([][]ChunkResult{vector, keyword}, []float64{0.5, 0.5}, 10)
+	if len(fused) != 1 {
+		t.Fatalf("got %d results, want 1", len(fused))
+	}
+	if fused[0].Distance != 0.1 || fused[0].KeywordRank != 0.7 {
+		t.Errorf("merged result = %+v", fused[0])
+	}
+	if want := 1 / float64(rrfK+1); math.Abs(fused[0].Score-want) > 1e-12 {
+		t.Errorf("score = %v, want %v", fused[0].Score, want)
+	}
+
+	// Unweighted fusion of nothing is empty, not a panic
+	if got := fuseRankings(nil, nil, 10); len(got) != 0 {
+		t.Errorf("fuseRankings(nil) = %v", got)
+	}
+}
+
+func TestWithinDistance(t *testing.T) {
+	results := func() []ChunkResult {
+		return []ChunkResult{
+			{ID: "near", Distance: 0.2},
+			{ID: "far", Distance: 0.8},
+			{ID: "stale", Distance: 0.9, Stale: true},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		search HybridSearch
+		want   []string
+	}{
+		{"no limit", HybridSearch{Embedding: []float32{1}}, []string{"near", "far", "stale"}},
+		{"limit", HybridSearch{Embedding: []float32{1}, MaxDistance: 0.5}, []string{"near", "stale"}},
+		{"keyword search ignores distance", HybridSearch{MaxDistance: 0.5}, []string{"near", "far", "stale"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ids []string
+			for _, result := range tt.search.withinDistance(results()) {
+				ids = append(ids, result.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("withinDistance() = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package embeddings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/googleapis/gax-go/v2/apierror"
+	"github.com/kinyichukwu/edu-pro-backend/internal/utils"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+)
+
+// ProgressFunc is called after each batch completes with the number of texts
+// embedded so far and the total number of texts
+type ProgressFunc func(completed, total int)
+
+// batchEmbedFunc embeds one batch of non-empty texts, returning one vector per text
+type batchEmbedFunc func(ctx context.Context, texts []string) ([][]float32, error)
+
+// batchRunner splits texts into batches and embeds them on a bounded worker
+// pool, rate limited by a token bucket and retrying transient failures
+type batchRunner struct {
+	batchSize   int
+	concurrency int
+	limiter     *rate.Limiter
+	maxRetries  int
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+}
+
+// newBatchRunner creates a batch runner. requestsPerMinute limits the number
+// of batch requests sent to the provider.
+func newBatchRunner(batchSize, concurrency, requestsPerMinute int) *batchRunner {
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	limit := rate.Inf
+	if requestsPerMinute > 0 {
+		limit = rate.Limit(float64(requestsPerMinute) / 60)
+	}
+
+	return &batchRunner{
+		batchSize:   batchSize,
+		concurrency: concurrency,
+		limiter:     rate.NewLimiter(limit, concurrency),
+		maxRetries:  5,
+		baseBackoff: time.Second,
+		maxBackoff:  time.Minute,
+	}
+}
+
+// run embeds texts in order. Empty texts are skipped and get a nil embedding.
+func (r *batchRunner) run(ctx context.Context, texts []string, embed batchEmbedFunc, progress ProgressFunc) ([][]float32, error) {
+	logger := utils.GetLogger()
+
+	// Only non-empty texts are sent; remember where each came from
+	var indexes []int
+	for i, text := range texts {
+		if text == "" {
+			logger.Warn("Skipping empty text in batch", zap.Int("index", i))
+			continue
+		}
+		indexes = append(indexes, i)
+	}
+
+	embeddings := make([][]float32, len(texts))
+	if len(indexes) == 0 {
+		return embeddings, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	batches := make(chan []int)
+	var (
+		mu        sync.Mutex
+		completed int
+		firstErr  error
+		wg        sync.WaitGroup
+	)
+
+	for w := 0; w < r.concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				batchTexts := make([]string, len(batch))
+				for i, index := range batch {
+					batchTexts[i] = texts[index]
+				}
+
+				vectors, err := r.embedWithRetry(ctx, batchTexts, embed)
+				if err == nil && len(vectors) != len(batch) {
+					err = fmt.Errorf("expected %d embeddings, got %d", len(batch), len(vectors))
+				}
+
+				mu.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+						cancel()
+					}
+					mu.Unlock()
+					continue
+				}
+				for i, index := range batch {
+					embeddings[index] = vectors[i]
+				}
+				completed += len(batch)
+				done := completed
+				mu.Unlock()
+
+				if progress != nil {
+					progress(done, len(indexes))
+				}
+			}
+		}()
+	}
+
+	for start := 0; start < len(indexes); start += r.batchSize {
+		end := start + r.batchSize
+		if end > len(indexes) {
+			end = len(indexes)
+		}
+		select {
+		case batches <- indexes[start:end]:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(batches)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return embeddings, nil
+}
+
+// embedWithRetry sends one batch, waiting on the rate limiter before every
+// attempt and backing off exponentially with jitter on retryable errors
+func (r *batchRunner) embedWithRetry(ctx context.Context, texts []string, embed batchEmbedFunc) ([][]float32, error) {
+	logger := utils.GetLogger()
+
+	for attempt := 0; ; attempt++ {
+		if err := r.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		vectors, err := embed(ctx, texts)
+		if err == nil {
+			return vectors, nil
+		}
+		if attempt >= r.maxRetries || !isRetryable(err) {
+			return nil, err
+		}
+
+		delay := r.backoff(attempt)
+		logger.Warn("Embedding batch failed, retrying",
+			zap.Error(err),
+			zap.Int("attempt", attempt+1),
+			zap.Int("batch_size", len(texts)),
+			zap.Duration("delay", delay),
+		)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// backoff returns the delay before the next attempt using exponential
+// backoff with full jitter
+func (r *batchRunner) backoff(attempt int) time.Duration {
+	delay := r.baseBackoff << attempt
+	if delay <= 0 || delay > r.maxBackoff {
+		delay = r.maxBackoff
+	}
+	return time.Duration(rand.Int63n(int64(delay)) + 1)
+}
+
+// isRetryable reports whether an embedding error is transient: rate limiting
+// (429) or a server-side failure (5xx)
+func isRetryable(err error) bool {
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		return isRetryableStatus(statusErr.StatusCode)
+	}
+
+	var apiErr *apierror.APIError
+	if errors.As(err, &apiErr) {
+		if code := apiErr.HTTPCode(); code > 0 {
+			return isRetryableStatus(code)
+		}
+		if status := apiErr.GRPCStatus(); status != nil {
+			switch status.Code() {
+			case codes.ResourceExhausted, codes.Unavailable, codes.Internal, codes.DeadlineExceeded, codes.Aborted:
+				return true
+			}
+		}
+		return false
+	}
+
+	var googleErr *googleapi.Error
+	if errors.As(err, &googleErr) {
+		return isRetryableStatus(googleErr.Code)
+	}
+
+	return false
+}
+
+// isRetryableStatus reports whether an HTTP status is worth retrying
+func isRetryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
+
+// httpStatusError is returned by HTTP-based providers for non-2xx responses
+type httpStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("embedding request failed with status %d: %s", e.StatusCode, e.Body)
+}
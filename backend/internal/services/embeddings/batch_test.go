@@ -0,0 +1,222 @@
+package embeddings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+// testRunner returns a batch runner that backs off for at most a millisecond
+func testRunner(batchSize, concurrency int) *batchRunner {
+	r := newBatchRunner(batchSize, concurrency, 0)
+	r.baseBackoff = time.Millisecond
+	r.maxBackoff = time.Millisecond
+	return r
+}
+
+// indexEmbed embeds each text, which holds its own index, as a one-element vector
+func indexEmbed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		index, err := strconv.Atoi(text)
+		if err != nil {
+			return nil, err
+		}
+		vectors[i] = []float32{float32(index)}
+	}
+	return vectors, nil
+}
+
+func TestBatchRunnerKeepsOrder(t *testing.T) {
+	texts := make([]string, 25)
+	for i := range texts {
+		if i%7 != 3 {
+			texts[i] = strconv.Itoa(i)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		batchSize   int
+		concurrency int
+	}{
+		{"sequential", 4, 1},
+		{"concurrent", 4, 3},
+		{"one batch", 100, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu       sync.Mutex
+				reports  []int
+				maxBatch int
+			)
+			embed := func(ctx context.Context, batch []string) ([][]float32, error) {
+				mu.Lock()
+				if len(batch) > maxBatch {
+					maxBatch = len(batch)
+				}
+				mu.Unlock()
+				return indexEmbed(ctx, batch)
+			}
+			progress := func(completed, total int) {
+				mu.Lock()
+				defer mu.Unlock()
+				if total != 21 {
+					t.Errorf("progress total = %d, want 21 non-empty texts", total)
+				}
+				reports = append(reports, completed)
+			}
+
+			embeddings, err := testRunner(tt.batchSize, tt.concurrency).run(context.Background(), texts, embed, progress)
+			if err != nil {
+				t.Fatalf("run() error = %v", err)
+			}
+			if len(embeddings) != len(texts) {
+				t.Fatalf("got %d embeddings for %d texts", len(embeddings), len(texts))
+			}
+			for i, embedding := range embeddings {
+				if texts[i] == "" {
+					if embedding != nil {
+						t.Errorf("empty text %d got embedding %v", i, embedding)
+					}
+					continue
+				}
+				if len(embedding) != 1 || embedding[0] != float32(i) {
+					t.Errorf("text %d got embedding %v", i, embedding)
+				}
+			}
+
+			if maxBatch > tt.batchSize {
+				t.Errorf("sent a batch of %d, limit %d", maxBatch, tt.batchSize)
+			}
+			if len(reports) == 0 || reports[len(reports)-1] != 21 {
+				t.Errorf("progress reports = %v, want to finish at 21", reports)
+			}
+		})
+	}
+}
+
+func TestBatchRunnerOnlyEmptyTexts(t *testing.T) {
+	embed := func(ctx context.Context, texts []string) ([][]float32, error) {
+		t.Error("embedded a batch of empty texts")
+		return nil, nil
+	}
+	embeddings, err := testRunner(10, 1).run(context.Background(), []string{"", ""}, embed, nil)
+	if err != nil || len(embeddings) != 2 || embeddings[0] != nil || embeddings[1] != nil {
+		t.Errorf("run() = %v, %v", embeddings, err)
+	}
+}
+
+func TestBatchRunnerRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int
+		err       error
+		wantCalls int32
+		wantErr   bool
+	}{
+		{"rate limited then succeeds", 2, &httpStatusError{StatusCode: 429}, 3, false},
+		{"server error then succeeds", 1, &googleapi.Error{Code: 503}, 2, false},
+		{"bad request is not retried", 1, &httpStatusError{StatusCode: 400}, 1, true},
+		{"plain error is not retried", 1, errors.New("malformed response"), 1, true},
+		{"gives up after max retries", 100, &httpStatusError{StatusCode: 500}, 6, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			embed := func(ctx context.Context, texts []string) ([][]float32, error) {
+				if atomic.AddInt32(&calls, 1) <= int32(tt.failures) {
+					return nil, tt.err
+				}
+				return indexEmbed(ctx, texts)
+			}
+
+			_, err := testRunner(10, 1).run(context.Background(), []string{"1", "2"}, embed, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("embed called %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestBatchRunnerStopsOnError(t *testing.T) {
+	texts := make([]string, 50)
+	for i := range texts {
+		texts[i] = strconv.Itoa(i)
+	}
+
+	var calls int32
+	embed := func(ctx context.Context, batch []string) ([][]float32, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, fmt.Errorf("provider rejected the request")
+	}
+
+	embeddings, err := testRunner(5, 1).run(context.Background(), texts, embed, nil)
+	if err == nil || embeddings != nil {
+		t.Fatalf("run() = %v, %v; want an error", embeddings, err)
+	}
+	if calls > 2 {
+		t.Errorf("kept sending batches after a failure: %d calls", calls)
+	}
+}
+
+func TestBatchRunnerCountMismatch(t *testing.T) {
+	embed := func(ctx context.Context, texts []string) ([][]float32, error) {
+		return [][]float32{{1}}, nil
+	}
+	if _, err := testRunner(10, 1).run(context.Background(), []string{"a", "b"}, embed, nil); err == nil {
+		t.Error("run() accepted one embedding for two texts")
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"429", &httpStatusError{StatusCode: 429}, true},
+		{"500", &httpStatusError{StatusCode: 500}, true},
+		{"401", &httpStatusError{StatusCode: 401}, false},
+		{"wrapped 503", fmt.Errorf("batch 2: %w", &httpStatusError{StatusCode: 503}), true},
+		{"googleapi 429", &googleapi.Error{Code: 429}, true},
+		{"googleapi 404", &googleapi.Error{Code: 404}, false},
+		{"cancelled", context.Canceled, false},
+		{"plain", errors.New("boom"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(tt.err); got != tt.want {
+				t.Errorf("isRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	r := newBatchRunner(10, 1, 0)
+	for attempt := 0; attempt < 70; attempt++ {
+		delay := r.backoff(attempt)
+		limit := r.baseBackoff << attempt
+		if limit <= 0 || limit > r.maxBackoff {
+			limit = r.maxBackoff
+		}
+		if delay <= 0 || delay > limit {
+			t.Errorf("backoff(%d) = %v, want within (0, %v]", attempt, delay, limit)
+		}
+	}
+}
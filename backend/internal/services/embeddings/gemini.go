@@ -6,27 +6,49 @@ import (
 	"time"
 
 	"github.com/google/generative-ai-go/genai"
+	"github.com/kinyichukwu/edu-pro-backend/internal/config"
 	"github.com/kinyichukwu/edu-pro-backend/internal/utils"
 	"go.uber.org/zap"
 	"google.golang.org/api/option"
 )
 
-// Client represents the embeddings client
-type Client struct {
-	apiKey string
+// GeminiClient generates embeddings with the Gemini embedding models
+type GeminiClient struct {
+	genai  *genai.Client
 	model  string
+	runner *batchRunner
 }
 
-// NewClient creates a new embeddings client
-func NewClient(apiKey string) *Client {
-	return &Client{
-		apiKey: apiKey,
-		model:  "models/embedding-001",
+// NewGeminiClient creates a Gemini embeddings client. The underlying Gemini
+// client is shared by all requests and must be released with Close.
+func NewGeminiClient(cfg *config.Config) (*GeminiClient, error) {
+	logger := utils.GetLogger()
+
+	genaiClient, err := genai.NewClient(context.Background(), option.WithAPIKey(cfg.GeminiAPIKey))
+	if err != nil {
+		logger.Error("Failed to create Gemini client", zap.Error(err))
+		return nil, fmt.Errorf("failed to create Gemini client: %w", err)
+	}
+
+	model := cfg.EmbeddingModel
+	if model == "" {
+		model = "models/embedding-001"
 	}
+
+	return &GeminiClient{
+		genai:  genaiClient,
+		model:  model,
+		runner: newBatchRunner(cfg.EmbeddingBatchSize, cfg.EmbeddingConcurrency, cfg.EmbeddingRequestsPerMinute),
+	}, nil
+}
+
+// Close releases the underlying Gemini client
+func (c *GeminiClient) Close() error {
+	return c.genai.Close()
 }
 
 // GenerateEmbedding generates an embedding for the given text
-func (c *Client) GenerateEmbedding(text string) ([]float32, error) {
+func (c *GeminiClient) GenerateEmbedding(text string) ([]float32, error) {
 	logger := utils.GetLogger()
 
 	if text == "" {
@@ -36,15 +58,8 @@ func (c *Client) GenerateEmbedding(text string) ([]float32, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	client, err := genai.NewClient(ctx, option.WithAPIKey(c.apiKey))
-	if err != nil {
-		logger.Error("Failed to create Gemini client", zap.Error(err))
-		return nil, fmt.Errorf("failed to create Gemini client: %w", err)
-	}
-	defer client.Close()
-
 	// Get the embedding model
-	model := client.EmbeddingModel(c.model)
+	model := c.genai.EmbeddingModel(c.model)
 
 	// Generate embedding
 	resp, err := model.EmbedContent(ctx, genai.Text(text))
@@ -70,71 +85,24 @@ func (c *Client) GenerateEmbedding(text string) ([]float32, error) {
 	return resp.Embedding.Values, nil
 }
 
-// GenerateEmbeddings generates embeddings for multiple texts in batch
-func (c *Client) GenerateEmbeddings(texts []string) ([][]float32, error) {
+// GenerateEmbeddings generates embeddings for multiple texts using the batch
+// embedding API. Batches are sent concurrently within the configured rate
+// limit, and rate-limit and server errors are retried with backoff. Empty
+// texts get a nil embedding. progress may be nil.
+func (c *GeminiClient) GenerateEmbeddings(ctx context.Context, texts []string, progress ProgressFunc) ([][]float32, error) {
 	logger := utils.GetLogger()
 
 	if len(texts) == 0 {
 		return nil, fmt.Errorf("texts cannot be empty")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-	defer cancel()
-
-	client, err := genai.NewClient(ctx, option.WithAPIKey(c.apiKey))
+	embeddings, err := c.runner.run(ctx, texts, c.embedBatch, progress)
 	if err != nil {
-		logger.Error("Failed to create Gemini client", zap.Error(err))
-		return nil, fmt.Errorf("failed to create Gemini client: %w", err)
-	}
-	defer client.Close()
-
-	// Get the embedding model
-	model := client.EmbeddingModel(c.model)
-
-	var embeddings [][]float32
-
-	// Process texts in batches to avoid rate limits
-	batchSize := 10
-	for i := 0; i < len(texts); i += batchSize {
-		end := i + batchSize
-		if end > len(texts) {
-			end = len(texts)
-		}
-
-		batch := texts[i:end]
-		logger.Debug("Processing embedding batch",
-			zap.Int("batch_start", i),
-			zap.Int("batch_size", len(batch)),
+		logger.Error("Failed to generate batch embeddings",
+			zap.Error(err),
+			zap.Int("total_texts", len(texts)),
 		)
-
-		for _, text := range batch {
-			if text == "" {
-				logger.Warn("Skipping empty text in batch")
-				embeddings = append(embeddings, nil)
-				continue
-			}
-
-			resp, err := model.EmbedContent(ctx, genai.Text(text))
-			if err != nil {
-				logger.Error("Failed to generate embedding in batch",
-					zap.Error(err),
-					zap.String("text_preview", truncateText(text, 100)),
-				)
-				return nil, fmt.Errorf("failed to generate embedding for text: %w", err)
-			}
-
-			if resp.Embedding == nil || len(resp.Embedding.Values) == 0 {
-				logger.Error("Empty embedding response in batch")
-				return nil, fmt.Errorf("empty embedding response for text")
-			}
-
-			embeddings = append(embeddings, resp.Embedding.Values)
-		}
-
-		// Add a small delay between batches to respect rate limits
-		if end < len(texts) {
-			time.Sleep(100 * time.Millisecond)
-		}
+		return nil, fmt.Errorf("failed to generate embeddings: %w", err)
 	}
 
 	logger.Info("Batch embeddings generated successfully",
@@ -145,35 +113,48 @@ func (c *Client) GenerateEmbeddings(texts []string) ([][]float32, error) {
 	return embeddings, nil
 }
 
-// IsHealthy checks if the embeddings service is available
-func (c *Client) IsHealthy() bool {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// embedBatch sends a single BatchEmbedContents request
+func (c *GeminiClient) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	model := c.genai.EmbeddingModel(c.model)
+
+	batch := model.NewBatch()
+	for _, text := range texts {
+		batch.AddContent(genai.Text(text))
+	}
 
-	client, err := genai.NewClient(ctx, option.WithAPIKey(c.apiKey))
+	resp, err := model.BatchEmbedContents(ctx, batch)
 	if err != nil {
-		return false
+		return nil, err
 	}
-	defer client.Close()
 
-	// Try a simple embedding request
-	model := client.EmbeddingModel(c.model)
-	_, err = model.EmbedContent(ctx, genai.Text("test"))
+	embeddings := make([][]float32, len(resp.Embeddings))
+	for i, embedding := range resp.Embeddings {
+		if embedding == nil || len(embedding.Values) == 0 {
+			return nil, fmt.Errorf("empty embedding response for text %d", i)
+		}
+		embeddings[i] = embedding.Values
+	}
+	return embeddings, nil
+}
 
-	return err == nil
+// Provider returns the provider name
+func (c *GeminiClient) Provider() string {
+	return ProviderGemini
 }
 
-// truncateText truncates text to a specified length for logging
-func truncateText(text string, maxLen int) string {
-	if len(text) <= maxLen {
-		return text
-	}
-	return text[:maxLen] + "..."
+// Model returns the embedding model name
+func (c *GeminiClient) Model() string {
+	return c.model
 }
 
-// EmbeddingResult represents the result of an embedding operation
-type EmbeddingResult struct {
-	Text      string    `json:"text"`
-	Embedding []float32 `json:"embedding"`
-	Dimension int       `json:"dimension"`
+// IsHealthy checks if the embeddings service is available
+func (c *GeminiClient) IsHealthy() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Try a simple embedding request
+	model := c.genai.EmbeddingModel(c.model)
+	_, err := model.EmbedContent(ctx, genai.Text("test"))
+
+	return err == nil
 }
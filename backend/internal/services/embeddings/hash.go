@@ -0,0 +1,124 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// HashEmbedder produces deterministic embeddings by hashing words and word
+// pairs into a fixed number of dimensions. Texts that share vocabulary end up
+// close together, which is enough for offline development and tests without
+// any API key or network access.
+type HashEmbedder struct {
+	model     string
+	dimension int
+}
+
+// NewHashEmbedder creates a hashing embedder. The dimension must match
+// EMBEDDING_DIMENSION, 768 by default.
+func NewHashEmbedder(model string, dimension int) *HashEmbedder {
+	if model == "" {
+		model = "hash-v1"
+	}
+	if dimension <= 0 {
+		dimension = 768
+	}
+
+	return &HashEmbedder{
+		model:     model,
+		dimension: dimension,
+	}
+}
+
+// Provider returns the provider name
+func (e *HashEmbedder) Provider() string {
+	return ProviderHash
+}
+
+// Model returns the embedding model name
+func (e *HashEmbedder) Model() string {
+	return e.model
+}
+
+// GenerateEmbedding generates an embedding for the given text
+func (e *HashEmbedder) GenerateEmbedding(text string) ([]float32, error) {
+	if text == "" {
+		return nil, fmt.Errorf("text cannot be empty")
+	}
+	return e.embed(text), nil
+}
+
+// GenerateEmbeddings generates embeddings for multiple texts. Empty texts get
+// a nil embedding. progress may be nil.
+func (e *HashEmbedder) GenerateEmbeddings(ctx context.Context, texts []string, progress ProgressFunc) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, fmt.Errorf("texts cannot be empty")
+	}
+
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if text != "" {
+			embeddings[i] = e.embed(text)
+		}
+	}
+
+	if progress != nil {
+		progress(len(texts), len(texts))
+	}
+	return embeddings, nil
+}
+
+// IsHealthy always reports true as no external service is involved
+func (e *HashEmbedder) IsHealthy() bool {
+	return true
+}
+
+// embed hashes each word and adjacent word pair into a signed bucket and
+// L2-normalises the result so cosine distance behaves
+func (e *HashEmbedder) embed(text string) []float32 {
+	vector := make([]float32, e.dimension)
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for i, word := range words {
+		e.add(vector, word, 1)
+		if i > 0 {
+			e.add(vector, words[i-1]+" "+word, 0.5)
+		}
+	}
+
+	var norm float64
+	for _, value := range vector {
+		norm += float64(value) * float64(value)
+	}
+	if norm == 0 {
+		return vector
+	}
+
+	scale := float32(1 / math.Sqrt(norm))
+	for i := range vector {
+		vector[i] *= scale
+	}
+	return vector
+}
+
+// add adds weight to the bucket a feature hashes to, with a hash-derived sign
+// so that collisions tend to cancel out
+func (e *HashEmbedder) add(vector []float32, feature string, weight float32) {
+	hasher := fnv.New64a()
+	hasher.Write([]byte(feature))
+	sum := hasher.Sum64()
+
+	if sum>>63 == 1 {
+		weight = -weight
+	}
+	vector[sum%uint64(e.dimension)] += weight
+}
@@ -0,0 +1,172 @@
+package embeddings
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kinyichukwu/edu-pro-backend/internal/config"
+	"github.com/kinyichukwu/edu-pro-backend/internal/utils"
+	"go.uber.org/zap"
+)
+
+// OpenAIClient generates embeddings with any OpenAI-compatible /embeddings
+// endpoint, such as OpenAI itself or a self-hosted model server
+type OpenAIClient struct {
+	httpClient *http.Client
+	baseURL    string
+	apiKey     string
+	model      string
+	runner     *batchRunner
+}
+
+// NewOpenAIClient creates an OpenAI-compatible embeddings client
+func NewOpenAIClient(cfg *config.Config) (*OpenAIClient, error) {
+	if cfg.EmbeddingBaseURL == "" {
+		return nil, fmt.Errorf("EMBEDDING_BASE_URL is required for the openai embedding provider")
+	}
+	if cfg.EmbeddingModel == "" {
+		return nil, fmt.Errorf("EMBEDDING_MODEL is required for the openai embedding provider")
+	}
+
+	return &OpenAIClient{
+		httpClient: &http.Client{Timeout: 2 * time.Minute},
+		baseURL:    strings.TrimRight(cfg.EmbeddingBaseURL, "/"),
+		apiKey:     cfg.EmbeddingAPIKey,
+		model:      cfg.EmbeddingModel,
+		runner:     newBatchRunner(cfg.EmbeddingBatchSize, cfg.EmbeddingConcurrency, cfg.EmbeddingRequestsPerMinute),
+	}, nil
+}
+
+// Provider returns the provider name
+func (c *OpenAIClient) Provider() string {
+	return ProviderOpenAI
+}
+
+// Model returns the embedding model name
+func (c *OpenAIClient) Model() string {
+	return c.model
+}
+
+// GenerateEmbedding generates an embedding for the given text
+func (c *OpenAIClient) GenerateEmbedding(text string) ([]float32, error) {
+	logger := utils.GetLogger()
+
+	if text == "" {
+		return nil, fmt.Errorf("text cannot be empty")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	embeddings, err := c.embedBatch(ctx, []string{text})
+	if err != nil {
+		logger.Error("Failed to generate embedding",
+			zap.Error(err),
+			zap.String("text_preview", truncateText(text, 100)),
+		)
+		return nil, fmt.Errorf("failed to generate embedding: %w", err)
+	}
+
+	return embeddings[0], nil
+}
+
+// GenerateEmbeddings generates embeddings for multiple texts in concurrent,
+// rate-limited batches. Empty texts get a nil embedding. progress may be nil.
+func (c *OpenAIClient) GenerateEmbeddings(ctx context.Context, texts []string, progress ProgressFunc) ([][]float32, error) {
+	logger := utils.GetLogger()
+
+	if len(texts) == 0 {
+		return nil, fmt.Errorf("texts cannot be empty")
+	}
+
+	embeddings, err := c.runner.run(ctx, texts, c.embedBatch, progress)
+	if err != nil {
+		logger.Error("Failed to generate batch embeddings",
+			zap.Error(err),
+			zap.Int("total_texts", len(texts)),
+		)
+		return nil, fmt.Errorf("failed to generate embeddings: %w", err)
+	}
+
+	logger.Info("Batch embeddings generated successfully",
+		zap.Int("total_texts", len(texts)),
+		zap.Int("total_embeddings", len(embeddings)),
+	)
+
+	return embeddings, nil
+}
+
+// openAIEmbeddingRequest is the body of a POST /embeddings request
+type openAIEmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// openAIEmbeddingResponse is the body of a POST /embeddings response
+type openAIEmbeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// embedBatch sends a single /embeddings request
+func (c *OpenAIClient) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(openAIEmbeddingRequest{Model: c.model, Input: texts})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode embedding request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embedding request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("embedding request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, &httpStatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(respBody))}
+	}
+
+	var result openAIEmbeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode embedding response: %w", err)
+	}
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(result.Data))
+	}
+
+	// Responses carry an index per input and are not guaranteed to be ordered
+	embeddings := make([][]float32, len(texts))
+	for _, item := range result.Data {
+		if item.Index < 0 || item.Index >= len(texts) || len(item.Embedding) == 0 {
+			return nil, fmt.Errorf("invalid embedding at index %d", item.Index)
+		}
+		embeddings[item.Index] = item.Embedding
+	}
+	return embeddings, nil
+}
+
+// IsHealthy checks if the embeddings endpoint is available
+func (c *OpenAIClient) IsHealthy() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := c.embedBatch(ctx, []string{"test"})
+	return err == nil
+}
@@ -0,0 +1,60 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kinyichukwu/edu-pro-backend/internal/config"
+)
+
+// Embedding provider names
+const (
+	ProviderGemini = "gemini"
+	ProviderOpenAI = "openai"
+	ProviderHash   = "hash"
+)
+
+// Embedder defines the embeddings service interface
+type Embedder interface {
+	Provider() string
+	Model() string
+	GenerateEmbedding(text string) ([]float32, error)
+	GenerateEmbeddings(ctx context.Context, texts []string, progress ProgressFunc) ([][]float32, error)
+	IsHealthy() bool
+}
+
+// NewEmbedder creates the embedding provider selected in the configuration
+func NewEmbedder(cfg *config.Config) (Embedder, error) {
+	switch strings.ToLower(cfg.EmbeddingProvider) {
+	case "", ProviderGemini:
+		return NewGeminiClient(cfg)
+	case ProviderOpenAI:
+		return NewOpenAIClient(cfg)
+	case ProviderHash:
+		return NewHashEmbedder(cfg.EmbeddingModel, cfg.EmbeddingDimension), nil
+	default:
+		return nil, fmt.Errorf("unknown embedding provider: %s", cfg.EmbeddingProvider)
+	}
+}
+
+// ModelID identifies an embedder's model across providers, e.g.
+// "gemini/models/embedding-001"
+func ModelID(embedder Embedder) string {
+	return embedder.Provider() + "/" + embedder.Model()
+}
+
+// truncateText truncates text to a specified length for logging
+func truncateText(text string, maxLen int) string {
+	if len(text) <= maxLen {
+		return text
+	}
+	return text[:maxLen] + "..."
+}
+
+// EmbeddingResult represents the result of an embedding operation
+type EmbeddingResult struct {
+	Text      string    `json:"text"`
+	Embedding []float32 `json:"embedding"`
+	Dimension int       `json:"dimension"`
+}
@@ -0,0 +1,443 @@
+package extract
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// headingStyleRegex matches Word heading style names such as "heading 1"
+var headingStyleRegex = regexp.MustCompile(`^heading\s*(\d)$`)
+
+// docxParagraph represents a single block of text in a DOCX body
+type docxParagraph struct {
+	Text         string
+	HeadingLevel int // 0 for body text
+	Equations    int
+}
+
+// docxTable represents a table as rows of cell text
+type docxTable struct {
+	Rows [][]string
+}
+
+// docxBlock is either a paragraph or a table, kept in document order
+type docxBlock struct {
+	Paragraph *docxParagraph
+	Table     *docxTable
+}
+
+// extractFromDOCX extracts text from DOCX files. Tables are written as
+// Markdown and equations as LaTeX; when there are tables, the body is also
+// returned as sections with each table in its own.
+func (c *Client) extractFromDOCX(reader io.Reader) (string, map[string]interface{}, []Section, error) {
+	// zip requires random access, so work from a file rather than memory
+	file, cleanup, err := fileFromReader(reader, "edupro-*.docx")
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to read DOCX content: %w", err)
+	}
+	defer cleanup()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to read DOCX content: %w", err)
+	}
+
+	archive, err := zip.NewReader(file, info.Size())
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to open DOCX: %w", err)
+	}
+
+	files := make(map[string]*zip.File)
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+
+	documentFile, ok := files["word/document.xml"]
+	if !ok {
+		return "", nil, nil, fmt.Errorf("invalid DOCX: missing word/document.xml")
+	}
+
+	// Styles are optional; they map style IDs to names so localized or
+	// custom heading styles are still recognised
+	styleNames := map[string]string{}
+	if stylesFile, ok := files["word/styles.xml"]; ok {
+		styleNames, err = c.parseDOCXStyles(stylesFile)
+		if err != nil {
+			return "", nil, nil, err
+		}
+	}
+
+	blocks, err := c.parseDOCXBody(documentFile, styleNames)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	var footnotes []docxFootnote
+	if footnotesFile, ok := files["word/footnotes.xml"]; ok {
+		footnotes, err = c.parseDOCXFootnotes(footnotesFile)
+		if err != nil {
+			return "", nil, nil, err
+		}
+	}
+
+	var textBuilder strings.Builder
+	paragraphCount, headingCount, tableCount, equationCount := 0, 0, 0, 0
+
+	// Tables split the body into sections so each can be chunked whole. The
+	// heading path is carried into every section so the chunks cut from the
+	// text after a table still know which part of the document they are in.
+	var sections []Section
+	var headingPath []string
+	sectionStart := 0
+	sectionMetadata := headingMetadata(nil)
+	closeSection := func() {
+		if text := textBuilder.String()[sectionStart:]; strings.TrimSpace(text) != "" {
+			sections = append(sections, Section{Text: text, Metadata: sectionMetadata, Offset: sectionStart})
+		}
+		sectionStart = textBuilder.Len()
+		sectionMetadata = headingMetadata(headingPath)
+	}
+
+	for _, block := range blocks {
+		switch {
+		case block.Paragraph != nil:
+			p := block.Paragraph
+			equationCount += p.Equations
+			if p.HeadingLevel > 0 {
+				headingCount++
+				textBuilder.WriteString(strings.Repeat("#", p.HeadingLevel))
+				textBuilder.WriteString(" ")
+				headingPath = appendHeading(headingPath, p.HeadingLevel, p.Text)
+			} else {
+				paragraphCount++
+			}
+			textBuilder.WriteString(p.Text)
+			textBuilder.WriteString("\n\n")
+		case block.Table != nil:
+			tableCount++
+			closeSection()
+			table := markdownTable(block.Table.Rows)
+			sections = append(sections, Section{
+				Text:     table,
+				Metadata: tableMetadata(headingMetadata(headingPath), block.Table.Rows),
+				Offset:   textBuilder.Len(),
+			})
+			textBuilder.WriteString(table)
+			textBuilder.WriteString("\n\n")
+			sectionStart = textBuilder.Len()
+			sectionMetadata = headingMetadata(headingPath)
+		}
+	}
+
+	if len(footnotes) > 0 {
+		textBuilder.WriteString("Footnotes\n")
+		for _, note := range footnotes {
+			textBuilder.WriteString(fmt.Sprintf("[^%s]: %s\n", note.ID, note.Text))
+		}
+	}
+
+	// Documents without tables are chunked as a whole
+	if tableCount > 0 {
+		closeSection()
+	} else {
+		sections = nil
+	}
+
+	metadata := map[string]interface{}{
+		"format":          "DOCX",
+		"paragraph_count": paragraphCount,
+		"heading_count":   headingCount,
+		"table_count":     tableCount,
+		"footnote_count":  len(footnotes),
+		"equation_count":  equationCount,
+	}
+
+	return textBuilder.String(), metadata, sections, nil
+}
+
+// appendHeading updates a heading path for a heading at the given level
+func appendHeading(path []string, level int, title string) []string {
+	for len(path) >= level {
+		path = path[:len(path)-1]
+	}
+	for len(path) < level-1 {
+		path = append(path, "")
+	}
+	return append(path, title)
+}
+
+// headingMetadata returns section metadata locating it under a heading path
+func headingMetadata(path []string) map[string]interface{} {
+	metadata := map[string]interface{}{}
+	if len(path) > 0 {
+		metadata["heading_path"] = append([]string(nil), path...)
+		metadata["section_title"] = path[len(path)-1]
+	}
+	return metadata
+}
+
+// parseDOCXStyles returns a map of style ID to lower-cased style name
+func (c *Client) parseDOCXStyles(f *zip.File) (map[string]string, error) {
+	rc, err := c.openZipPart(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DOCX styles: %w", err)
+	}
+	defer rc.Close()
+
+	var styles struct {
+		Styles []struct {
+			ID   string `xml:"styleId,attr"`
+			Name struct {
+				Val string `xml:"val,attr"`
+			} `xml:"name"`
+		} `xml:"style"`
+	}
+	if err := xml.NewDecoder(rc).Decode(&styles); err != nil {
+		return nil, fmt.Errorf("failed to parse DOCX styles: %w", err)
+	}
+
+	names := make(map[string]string, len(styles.Styles))
+	for _, s := range styles.Styles {
+		names[s.ID] = strings.ToLower(s.Name.Val)
+	}
+	return names, nil
+}
+
+// headingLevel returns the heading level for a paragraph style, or 0
+func headingLevel(styleID string, styleNames map[string]string) int {
+	name := styleNames[styleID]
+	if name == "" {
+		name = strings.ToLower(styleID)
+	}
+	if name == "title" {
+		return 1
+	}
+	if m := headingStyleRegex.FindStringSubmatch(name); m != nil {
+		level, _ := strconv.Atoi(m[1])
+		return level
+	}
+	return 0
+}
+
+// parseDOCXBody walks word/document.xml and returns its paragraphs and tables in order
+func (c *Client) parseDOCXBody(f *zip.File, styleNames map[string]string) ([]docxBlock, error) {
+	rc, err := c.openZipPart(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DOCX body: %w", err)
+	}
+	defer rc.Close()
+
+	decoder := xml.NewDecoder(rc)
+
+	var blocks []docxBlock
+	inText := false
+
+	// Paragraphs can be nested, as in text boxes and content controls, so
+	// each open paragraph collects its own text; a nested paragraph becomes
+	// a block of its own rather than clearing its parent's text
+	type openParagraph struct {
+		text      strings.Builder
+		styleID   string
+		equations int
+	}
+	var paragraphs []*openParagraph
+	current := func() *openParagraph {
+		if len(paragraphs) == 0 {
+			// Text outside any paragraph is dropped
+			return &openParagraph{}
+		}
+		return paragraphs[len(paragraphs)-1]
+	}
+
+	// Tables can be nested; only the outermost table becomes a block and
+	// nested table text is flattened into the enclosing cell
+	var table *docxTable
+	var row []string
+	var cell strings.Builder
+	tableDepth := 0
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse DOCX body: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			// Equations are converted to LaTeX as a whole
+			if t.Name.Space == ommlNamespace && (t.Name.Local == "oMath" || t.Name.Local == "oMathPara") {
+				var equation ommlNode
+				if err := decoder.DecodeElement(&equation, &t); err != nil {
+					return nil, fmt.Errorf("failed to parse DOCX equation: %w", err)
+				}
+				p := current()
+				p.text.WriteString(ommlEquation(&equation))
+				p.equations++
+				continue
+			}
+			switch t.Name.Local {
+			case "tbl":
+				tableDepth++
+				if tableDepth == 1 {
+					table = &docxTable{}
+				}
+			case "tr":
+				if tableDepth == 1 {
+					row = nil
+				}
+			case "tc":
+				if tableDepth == 1 {
+					cell.Reset()
+				}
+			case "p":
+				paragraphs = append(paragraphs, &openParagraph{})
+			case "pStyle":
+				current().styleID = attrValue(t, "val")
+			case "t":
+				inText = true
+			case "tab":
+				current().text.WriteString("\t")
+			case "br", "cr":
+				current().text.WriteString("\n")
+			case "footnoteReference":
+				current().text.WriteString(fmt.Sprintf("[^%s]", attrValue(t, "id")))
+			}
+		case xml.CharData:
+			if inText {
+				current().text.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				if len(paragraphs) == 0 {
+					continue
+				}
+				p := paragraphs[len(paragraphs)-1]
+				paragraphs = paragraphs[:len(paragraphs)-1]
+				text := strings.TrimSpace(p.text.String())
+				if text == "" {
+					continue
+				}
+				if tableDepth > 0 {
+					if cell.Len() > 0 {
+						cell.WriteString(" ")
+					}
+					cell.WriteString(text)
+					continue
+				}
+				blocks = append(blocks, docxBlock{Paragraph: &docxParagraph{
+					Text:         text,
+					HeadingLevel: headingLevel(p.styleID, styleNames),
+					Equations:    p.equations,
+				}})
+			case "tc":
+				if tableDepth == 1 {
+					row = append(row, strings.TrimSpace(cell.String()))
+				}
+			case "tr":
+				if tableDepth == 1 && len(row) > 0 {
+					table.Rows = append(table.Rows, row)
+				}
+			case "tbl":
+				if tableDepth == 1 && table != nil && len(table.Rows) > 0 {
+					blocks = append(blocks, docxBlock{Table: table})
+				}
+				tableDepth--
+			}
+		}
+	}
+
+	return blocks, nil
+}
+
+// docxFootnote represents a footnote body
+type docxFootnote struct {
+	ID   string
+	Text string
+}
+
+// parseDOCXFootnotes extracts footnote text from word/footnotes.xml
+func (c *Client) parseDOCXFootnotes(f *zip.File) ([]docxFootnote, error) {
+	rc, err := c.openZipPart(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DOCX footnotes: %w", err)
+	}
+	defer rc.Close()
+
+	decoder := xml.NewDecoder(rc)
+
+	var footnotes []docxFootnote
+	var current *docxFootnote
+	var text strings.Builder
+	inText := false
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse DOCX footnotes: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "footnote":
+				// Separator footnotes are layout artefacts, not content
+				noteType := attrValue(t, "type")
+				if noteType == "separator" || noteType == "continuationSeparator" || noteType == "continuationNotice" {
+					current = nil
+					continue
+				}
+				current = &docxFootnote{ID: attrValue(t, "id")}
+				text.Reset()
+			case "t":
+				inText = true
+			}
+		case xml.CharData:
+			if inText && current != nil {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				if current != nil {
+					text.WriteString(" ")
+				}
+			case "footnote":
+				if current != nil {
+					current.Text = strings.Join(strings.Fields(text.String()), " ")
+					if current.Text != "" {
+						footnotes = append(footnotes, *current)
+					}
+					current = nil
+				}
+			}
+		}
+	}
+
+	return footnotes, nil
+}
+
+// attrValue returns the value of an attribute by local name
+func attrValue(el xml.StartElement, name string) string {
+	for _, attr := range el.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,224 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// zipPackage builds an Office package from part names and contents
+func zipPackage(t *testing.T, parts map[string]string) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	w := zip.NewWriter(&b)
+	for name, content := range parts {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+const wordNamespace = `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"`
+
+// docxBody wraps body XML in a word/document.xml part
+func docxBody(body string) string {
+	return `<?xml version="1.0"?><w:document ` + wordNamespace + `><w:body>` + body + `</w:body></w:document>`
+}
+
+// wordParagraph is a paragraph with an optional style
+func wordParagraph(style, text string) string {
+	props := ""
+	if style != "" {
+		props = `<w:pPr><w:pStyle w:val="` + style + `"/></w:pPr>`
+	}
+	return `<w:p>` + props + `<w:r><w:t>` + text + `</w:t></w:r></w:p>`
+}
+
+func TestExtractDOCX(t *testing.T) {
+	styles := `<w:styles ` + wordNamespace + `>
+		<w:style w:styleId="Kop1"><w:name w:val="heading 1"/></w:style>
+		<w:style w:styleId="Heading2"><w:name w:val="heading 2"/></w:style>
+	</w:styles>`
+	footnotes := `<w:footnotes ` + wordNamespace + `>
+		<w:footnote w:type="separator" w:id="-1"><w:p><w:r><w:t>---</w:t></w:r></w:p></w:footnote>
+		<w:footnote w:id="1"><w:p><w:r><w:t>See chapter 4.</w:t></w:r></w:p></w:footnote>
+	</w:footnotes>`
+	body := wordParagraph("Kop1", "Thermodynamics") +
+		`<w:p><w:r><w:t>Energy is conserved</w:t></w:r><w:r><w:footnoteReference w:id="1"/></w:r><w:r><w:t>.</w:t></w:r></w:p>` +
+		wordParagraph("Heading2", "First law") +
+		wordParagraph("", "Heat equals work.")
+
+	document := zipPackage(t, map[string]string{
+		"word/document.xml":  docxBody(body),
+		"word/styles.xml":    styles,
+		"word/footnotes.xml": footnotes,
+	})
+
+	text, metadata, sections, err := NewClient().extractFromDOCX(bytes.NewReader(document))
+	if err != nil {
+		t.Fatalf("extractFromDOCX() error = %v", err)
+	}
+
+	want := "# Thermodynamics\n\nEnergy is conserved[^1].\n\n## First law\n\nHeat equals work.\n\nFootnotes\n[^1]: See chapter 4.\n"
+	if text != want {
+		t.Errorf("text =\n%q\nwant\n%q", text, want)
+	}
+	if sections != nil {
+		t.Errorf("got %d sections for a document without tables", len(sections))
+	}
+	for key, want := range map[string]int{"heading_count": 2, "paragraph_count": 2, "footnote_count": 1, "table_count": 0} {
+		if metadata[key] != want {
+			t.Errorf("%s = %v, want %d", key, metadata[key], want)
+		}
+	}
+}
+
+func TestExtractDOCXTables(t *testing.T) {
+	table := `<w:tbl>
+		<w:tr><w:tc>` + wordParagraph("", "Course") + `</w:tc><w:tc>` + wordParagraph("", "Credits") + `</w:tc></w:tr>
+		<w:tr><w:tc>` + wordParagraph("", "CSC 201") + `</w:tc><w:tc>` + wordParagraph("", "3") + `</w:tc></w:tr>
+	</w:tbl>`
+	body := wordParagraph("Heading1", "Courses") + wordParagraph("", "Before the table.") + table + wordParagraph("", "After the table.")
+	document := zipPackage(t, map[string]string{"word/document.xml": docxBody(body)})
+
+	text, _, sections, err := NewClient().extractFromDOCX(bytes.NewReader(document))
+	if err != nil {
+		t.Fatalf("extractFromDOCX() error = %v", err)
+	}
+	if len(sections) != 3 {
+		t.Fatalf("got %d sections, want 3: %+v", len(sections), sections)
+	}
+
+	wantTable := "| Course | Credits |\n| --- | --- |\n| CSC 201 | 3 |"
+	if sections[1].Text != wantTable || sections[1].Metadata["chunk_type"] != ChunkTypeTable {
+		t.Errorf("table section = %q %v", sections[1].Text, sections[1].Metadata)
+	}
+	if got := sections[2].Metadata["heading_path"]; !reflect.DeepEqual(got, []string{"Courses"}) {
+		t.Errorf("section after the table has heading_path %v", got)
+	}
+
+	// Each section's offset locates its text in the document text
+	for i, section := range sections {
+		if !strings.HasPrefix(text[section.Offset:], section.Text) {
+			t.Errorf("section %d offset %d doesn't locate %q", i, section.Offset, section.Text)
+		}
+	}
+}
+
+func TestParseDOCXBodyNestedParagraphs(t *testing.T) {
+	// A text box inside a paragraph holds paragraphs of its own
+	body := `<w:p>
+		<w:r><w:t>Outer text before</w:t></w:r>
+		<w:r><w:txbxContent>
+			<w:p><w:r><w:t>Inside the text box</w:t></w:r></w:p>
+		</w:txbxContent></w:r>
+		<w:r><w:t> and after.</w:t></w:r>
+	</w:p>`
+	document := zipPackage(t, map[string]string{"word/document.xml": docxBody(body)})
+
+	text, _, _, err := NewClient().extractFromDOCX(bytes.NewReader(document))
+	if err != nil {
+		t.Fatalf("extractFromDOCX() error = %v", err)
+	}
+	for _, want := range []string{"Inside the text box", "Outer text before and after."} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text %q is missing %q", text, want)
+		}
+	}
+}
+
+func TestExtractDOCXPartLimits(t *testing.T) {
+	body := docxBody(wordParagraph("", strings.Repeat("a", 64*1024)))
+	document := zipPackage(t, map[string]string{"word/document.xml": body})
+
+	tests := []struct {
+		name    string
+		limits  Limits
+		wantErr bool
+	}{
+		{"within limits", Limits{MaxZipPartSize: 1 << 20, MaxZipPartRatio: 1000}, false},
+		{"too large", Limits{MaxZipPartSize: 16 * 1024}, true},
+		{"compresses too well", Limits{MaxZipPartRatio: 10}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient()
+			client.SetLimits(tt.limits)
+			_, _, _, err := client.extractFromDOCX(bytes.NewReader(document))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("extractFromDOCX() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrUnsafeDocument) {
+				t.Errorf("error %v is not ErrUnsafeDocument", err)
+			}
+		})
+	}
+}
+
+func TestLimitedPartRejectsUnderstatedSize(t *testing.T) {
+	// The declared size can't be trusted, so reading stops at the limit
+	part := &limitedPart{ReadCloser: nopCloser{strings.NewReader(strings.Repeat("x", 100))}, name: "word/document.xml", remaining: 50}
+	buf := make([]byte, 30)
+	var err error
+	read := 0
+	for err == nil {
+		var n int
+		n, err = part.Read(buf)
+		read += n
+	}
+	if !errors.Is(err, ErrUnsafeDocument) {
+		t.Fatalf("error = %v, want ErrUnsafeDocument", err)
+	}
+	if read > 50 {
+		t.Errorf("read %d bytes past the limit of 50", read)
+	}
+}
+
+type nopCloser struct{ *strings.Reader }
+
+func (nopCloser) Close() error { return nil }
+
+func TestHeadingLevel(t *testing.T) {
+	styles := map[string]string{"Kop2": "heading 2", "Titel": "title"}
+	tests := []struct {
+		style string
+		want  int
+	}{
+		{"Kop2", 2},
+		{"Titel", 1},
+		{"Heading3", 3}, // unnamed styles fall back to the style ID
+		{"heading 4", 4},
+		{"Normal", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got := headingLevel(tt.style, styles); got != tt.want {
+			t.Errorf("headingLevel(%q) = %d, want %d", tt.style, got, tt.want)
+		}
+	}
+}
+
+func TestAppendHeading(t *testing.T) {
+	var path []string
+	path = appendHeading(path, 1, "Physics")
+	path = appendHeading(path, 3, "Details")
+	if want := []string{"Physics", "", "Details"}; !reflect.DeepEqual(path, want) {
+		t.Errorf("path = %q, want %q", path, want)
+	}
+	path = appendHeading(path, 2, "Heat")
+	if want := []string{"Physics", "Heat"}; !reflect.DeepEqual(path, want) {
+		t.Errorf("path = %q, want %q", path, want)
+	}
+}
@@ -1,41 +1,95 @@
 package extract
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/kinyichukwu/edu-pro-backend/internal/utils"
 	"github.com/ledongthuc/pdf"
 	"go.uber.org/zap"
 )
 
+// minPageTextLength is the amount of text below which a PDF page is
+// treated as image-only and sent to OCR
+const minPageTextLength = 20
+
 // Client represents the text extraction client
-type Client struct{}
+type Client struct {
+	ocr        OCR
+	ocrTimeout time.Duration
+	limits     Limits
+}
 
 // NewClient creates a new extraction client
 func NewClient() *Client {
-	return &Client{}
+	return &Client{
+		ocrTimeout: 2 * time.Minute,
+	}
+}
+
+// SetOCR sets the OCR engine used for image-only PDF pages. OCR is disabled when nil.
+func (c *Client) SetOCR(ocr OCR) {
+	c.ocr = ocr
+}
+
+// SetLimits sets the safety limits applied to each document
+func (c *Client) SetLimits(limits Limits) {
+	c.limits = limits
+}
+
+// ExtractText extracts text from various file formats. When a timeout or
+// memory limit is set, extraction runs in a separate worker process so a
+// crafted document can't stall or exhaust the server. Cancelling ctx kills
+// the worker.
+func (c *Client) ExtractText(ctx context.Context, reader io.Reader, filename string) (*ExtractionResult, error) {
+	if c.limits.Timeout <= 0 && c.limits.MemoryLimit <= 0 {
+		return c.extractText(reader, filename)
+	}
+
+	file, cleanup, err := fileFromReader(reader, "edupro-extract-*"+strings.ToLower(filepath.Ext(filename)))
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	result, err := c.extractIsolated(ctx, file, filename)
+	if err != nil {
+		utils.GetLogger().Error("Failed to extract text",
+			zap.Error(err),
+			zap.String("filename", filename),
+		)
+		return nil, err
+	}
+	return result, nil
 }
 
-// ExtractText extracts text from various file formats
-func (c *Client) ExtractText(reader io.Reader, filename string) (*ExtractionResult, error) {
+// extractText extracts text in the current process
+func (c *Client) extractText(reader io.Reader, filename string) (*ExtractionResult, error) {
 	logger := utils.GetLogger()
 
 	ext := strings.ToLower(filepath.Ext(filename))
 
 	var text string
 	var metadata map[string]interface{}
+	var sections []Section
 	var err error
 
 	switch ext {
 	case ".pdf":
-		text, metadata, err = c.extractFromPDF(reader)
+		text, metadata, sections, err = c.extractFromPDF(reader)
 	case ".docx":
-		text, metadata, err = c.extractFromDOCX(reader)
+		text, metadata, sections, err = c.extractFromDOCX(reader)
+	case ".pptx":
+		text, metadata, sections, err = c.extractFromPPTX(reader)
 	case ".txt":
 		text, metadata, err = c.extractFromTXT(reader)
+	case ".html", ".htm":
+		text, metadata, err = c.extractFromHTML(reader)
 	default:
 		return nil, fmt.Errorf("unsupported file type: %s", ext)
 	}
@@ -55,6 +109,15 @@ func (c *Client) ExtractText(reader io.Reader, filename string) (*ExtractionResu
 		return nil, fmt.Errorf("no text content found in file")
 	}
 
+	// Clean sections the same way and drop any left empty
+	var cleanedSections []Section
+	for _, section := range sections {
+		section.Text = c.cleanExtractedText(section.Text)
+		if section.Text != "" {
+			cleanedSections = append(cleanedSections, section)
+		}
+	}
+
 	// Add basic metadata
 	if metadata == nil {
 		metadata = make(map[string]interface{})
@@ -67,6 +130,7 @@ func (c *Client) ExtractText(reader io.Reader, filename string) (*ExtractionResu
 	result := &ExtractionResult{
 		Text:     text,
 		Metadata: metadata,
+		Sections: cleanedSections,
 	}
 
 	logger.Info("Text extracted successfully",
@@ -79,22 +143,42 @@ func (c *Client) ExtractText(reader io.Reader, filename string) (*ExtractionResu
 	return result, nil
 }
 
-// extractFromPDF extracts text from PDF files
-func (c *Client) extractFromPDF(reader io.Reader) (string, map[string]interface{}, error) {
-	// Read all content into memory (required by pdf library)
-	content, err := io.ReadAll(reader)
+// extractFromPDF extracts text from PDF files. Each page becomes its own
+// section so chunks can be traced back to a page number, and each table on
+// a page becomes a further section of its own. Equation lines are marked as
+// LaTeX.
+func (c *Client) extractFromPDF(reader io.Reader) (text string, metadata map[string]interface{}, sections []Section, err error) {
+	// The pdf library panics on malformed files
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("malformed PDF: %v", r)
+		}
+	}()
+
+	// The pdf library needs random access; large textbooks are read from
+	// disk rather than held in memory
+	file, cleanup, err := fileFromReader(reader, "edupro-*.pdf")
 	if err != nil {
-		return "", nil, fmt.Errorf("failed to read PDF content: %w", err)
+		return "", nil, nil, fmt.Errorf("failed to read PDF content: %w", err)
 	}
+	defer cleanup()
 
-	// Open PDF from bytes
-	pdfReader, err := pdf.NewReader(strings.NewReader(string(content)), int64(len(content)))
+	info, err := file.Stat()
 	if err != nil {
-		return "", nil, fmt.Errorf("failed to open PDF: %w", err)
+		return "", nil, nil, fmt.Errorf("failed to read PDF content: %w", err)
+	}
+
+	pdfReader, err := pdf.NewReader(file, info.Size())
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to open PDF: %w", err)
+	}
+	if err := c.checkPDFStructure(pdfReader); err != nil {
+		return "", nil, nil, err
 	}
 
 	var textBuilder strings.Builder
 	pageCount := pdfReader.NumPage()
+	ocrPageCount, tableCount, equationCount := 0, 0, 0
 
 	// Extract text from each page
 	for i := 1; i <= pageCount; i++ {
@@ -102,42 +186,135 @@ func (c *Client) extractFromPDF(reader io.Reader) (string, map[string]interface{
 		if page.V.IsNull() {
 			continue
 		}
+		if err := c.checkPageStreams(page, i); err != nil {
+			return "", nil, nil, err
+		}
 
 		pageText, err := page.GetPlainText(nil)
 		if err != nil {
-			// Log error but continue with other pages
+			// Log error but continue; OCR may still recover the page
 			utils.GetLogger().Warn("Failed to extract text from PDF page",
 				zap.Int("page", i),
 				zap.Error(err),
 			)
+			pageText = ""
+		}
+
+		pageMetadata := map[string]interface{}{
+			"page": i,
+			"ocr":  false,
+		}
+
+		// Scanned pages have no text layer; fall back to OCR
+		if len(strings.TrimSpace(pageText)) < minPageTextLength && c.ocr != nil {
+			ocrResult, err := c.recognizePage(file.Name(), i)
+			if err != nil {
+				utils.GetLogger().Warn("OCR failed for PDF page",
+					zap.Int("page", i),
+					zap.Error(err),
+				)
+			} else if strings.TrimSpace(ocrResult.Text) != "" {
+				pageText = ocrResult.Text
+				pageMetadata["ocr"] = true
+				pageMetadata["ocr_confidence"] = ocrResult.Confidence
+				ocrPageCount++
+			}
+		}
+
+		if strings.TrimSpace(pageText) == "" {
 			continue
 		}
 
+		// Tables come out of the plain text jumbled; pages with tables are
+		// rebuilt from their layout with each table as its own section
+		if pageMetadata["ocr"] == false {
+			if segments := pageSegments(page); segments != nil {
+				// The page's text is its segments one after another
+				pageStart := textBuilder.Len()
+				for _, segment := range segments {
+					text, equations := markEquations(segment.Text)
+					equationCount += equations
+					section := Section{Text: text, Metadata: pageMetadata}
+					if segment.Table != nil {
+						section = Section{Text: markdownTable(segment.Table), Metadata: tableMetadata(pageMetadata, segment.Table)}
+						tableCount++
+					}
+					section.Offset = textBuilder.Len() - pageStart
+					textBuilder.WriteString(section.Text)
+					textBuilder.WriteString("\n")
+					sections = append(sections, section)
+				}
+				continue
+			}
+		}
+
+		pageText, equations := markEquations(pageText)
+		equationCount += equations
+
 		textBuilder.WriteString(pageText)
 		textBuilder.WriteString("\n")
+
+		sections = append(sections, Section{
+			Text:     pageText,
+			Metadata: pageMetadata,
+		})
 	}
 
-	metadata := map[string]interface{}{
-		"page_count": pageCount,
-		"format":     "PDF",
+	metadata = map[string]interface{}{
+		"page_count":     pageCount,
+		"format":         "PDF",
+		"ocr_page_count": ocrPageCount,
+		"table_count":    tableCount,
+		"equation_count": equationCount,
 	}
 
-	return textBuilder.String(), metadata, nil
+	return textBuilder.String(), metadata, sections, nil
+}
+
+// recognizePage runs OCR on a single PDF page under the OCR timeout
+func (c *Client) recognizePage(pdfPath string, page int) (*OCRResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.ocrTimeout)
+	defer cancel()
+
+	return c.ocr.RecognizePDFPage(ctx, pdfPath, page)
 }
 
-// extractFromDOCX extracts text from DOCX files
-func (c *Client) extractFromDOCX(reader io.Reader) (string, map[string]interface{}, error) {
-	// For now, return an error as DOCX extraction is complex
-	// In production, you would use a proper DOCX library or external service
-	return "", nil, fmt.Errorf("DOCX extraction not implemented - please convert to PDF or TXT")
+// fileFromReader returns the reader as a file for random access. Readers that
+// are already files are used in place; anything else is spooled to a
+// temporary file, which the returned cleanup function removes.
+func fileFromReader(reader io.Reader, pattern string) (*os.File, func(), error) {
+	if file, ok := reader.(*os.File); ok {
+		return file, func() {}, nil
+	}
+
+	file, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	cleanup := func() {
+		file.Close()
+		os.Remove(file.Name())
+	}
+
+	if _, err := io.Copy(file, reader); err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to write temp file: %w", err)
+	}
+	return file, cleanup, nil
 }
 
 // extractFromTXT extracts text from plain text files
 func (c *Client) extractFromTXT(reader io.Reader) (string, map[string]interface{}, error) {
+	if max := c.limits.MaxTextBytes; max > 0 {
+		reader = io.LimitReader(reader, max+1)
+	}
 	content, err := io.ReadAll(reader)
 	if err != nil {
 		return "", nil, fmt.Errorf("failed to read TXT content: %w", err)
 	}
+	if max := c.limits.MaxTextBytes; max > 0 && int64(len(content)) > max {
+		return "", nil, fmt.Errorf("%w: text file is larger than %dMB", ErrUnsafeDocument, max/(1024*1024))
+	}
 
 	text := string(content)
 	lineCount := len(strings.Split(text, "\n"))
@@ -186,9 +363,12 @@ func (c *Client) cleanExtractedText(text string) string {
 func (c *Client) IsSupported(filename string) bool {
 	ext := strings.ToLower(filepath.Ext(filename))
 	supportedTypes := map[string]bool{
-		".pdf": true,
-		".txt": true,
-		// ".docx": true, // Disabled for now - complex extraction
+		".pdf":  true,
+		".txt":  true,
+		".docx": true,
+		".pptx": true,
+		".html": true,
+		".htm":  true,
 	}
 	return supportedTypes[ext]
 }
@@ -197,4 +377,16 @@ func (c *Client) IsSupported(filename string) bool {
 type ExtractionResult struct {
 	Text     string                 `json:"text"`
 	Metadata map[string]interface{} `json:"metadata"`
+	Sections []Section              `json:"sections,omitempty"`
+}
+
+// Section represents a located part of a document, such as a page or a
+// slide. Its metadata (e.g. the page number) is carried into the chunks cut
+// from it.
+type Section struct {
+	Text     string                 `json:"text"`
+	Metadata map[string]interface{} `json:"metadata"`
+	// Offset is where Text starts in the extracted text of its page or
+	// slide, or of the document when it has neither
+	Offset int `json:"offset,omitempty"`
 }
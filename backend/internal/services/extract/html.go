@@ -0,0 +1,282 @@
+package extract
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// htmlSkippedElements hold page furniture or non-text content
+var htmlSkippedElements = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Svg:      true,
+	atom.Iframe:   true,
+	atom.Form:     true,
+	atom.Button:   true,
+	atom.Nav:      true,
+	atom.Aside:    true,
+}
+
+// htmlPageChrome elements are site headers and footers, except inside an
+// article or main element where they hold the article's own title or notes
+var htmlPageChrome = map[atom.Atom]bool{
+	atom.Header: true,
+	atom.Footer: true,
+}
+
+// htmlBlockElements start a new line in the extracted text
+var htmlBlockElements = map[atom.Atom]bool{
+	atom.P:          true,
+	atom.Div:        true,
+	atom.Section:    true,
+	atom.Article:    true,
+	atom.Main:       true,
+	atom.Blockquote: true,
+	atom.Pre:        true,
+	atom.Ul:         true,
+	atom.Ol:         true,
+	atom.Dl:         true,
+	atom.Dt:         true,
+	atom.Dd:         true,
+	atom.Table:      true,
+	atom.Figure:     true,
+	atom.Figcaption: true,
+	atom.Hr:         true,
+}
+
+// htmlHeadingLevels maps heading elements to their level
+var htmlHeadingLevels = map[atom.Atom]int{
+	atom.H1: 1,
+	atom.H2: 2,
+	atom.H3: 3,
+	atom.H4: 4,
+	atom.H5: 5,
+	atom.H6: 6,
+}
+
+// extractFromHTML converts a web page to plain text. Headings are written as
+// markdown headings so structure-aware chunking can follow them, and
+// navigation, scripts and other page furniture are dropped.
+func (c *Client) extractFromHTML(reader io.Reader) (string, map[string]interface{}, error) {
+	doc, err := html.Parse(reader)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	w := &htmlTextWriter{}
+	body := findHTMLElement(doc, atom.Main)
+	if body == nil {
+		body = findHTMLElement(doc, atom.Body)
+	}
+	if body == nil {
+		body = doc
+	}
+	w.walk(body)
+
+	metadata := map[string]interface{}{
+		"format":        "HTML",
+		"heading_count": w.headingCount,
+	}
+	if title := findHTMLElement(doc, atom.Title); title != nil {
+		if text := collapseSpaces(nodeText(title)); text != "" {
+			metadata["title"] = text
+		}
+	}
+
+	return w.text.String(), metadata, nil
+}
+
+// htmlTextWriter accumulates the text of an HTML tree
+type htmlTextWriter struct {
+	text         strings.Builder
+	line         strings.Builder
+	headingCount int
+	contentDepth int // number of enclosing article or main elements
+}
+
+// walk writes a node and its children
+func (w *htmlTextWriter) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		w.line.WriteString(n.Data)
+		return
+	case html.ElementNode:
+		// Equations are kept as LaTeX when the page carries their source
+		if n.DataAtom == atom.Math || isTeXScript(n) {
+			w.line.WriteString(" " + htmlMath(n) + " ")
+			return
+		}
+		if htmlSkippedElements[n.DataAtom] || (htmlPageChrome[n.DataAtom] && w.contentDepth == 0) || isRenderedMath(n) {
+			return
+		}
+		if n.DataAtom == atom.Article || n.DataAtom == atom.Main {
+			w.contentDepth++
+			defer func() { w.contentDepth-- }()
+		}
+
+		if level, ok := htmlHeadingLevels[n.DataAtom]; ok {
+			w.flush()
+			if text := collapseSpaces(nodeText(n)); text != "" {
+				w.headingCount++
+				w.text.WriteString(strings.Repeat("#", level) + " " + text + "\n\n")
+			}
+			return
+		}
+
+		switch n.DataAtom {
+		case atom.Br:
+			w.flush()
+			return
+		case atom.Tr:
+			w.flush()
+		case atom.Td, atom.Th:
+			if w.line.Len() > 0 {
+				w.line.WriteString(" | ")
+			}
+		case atom.Img:
+			// Alt text often carries the meaning of diagrams
+			for _, attr := range n.Attr {
+				if attr.Key == "alt" && strings.TrimSpace(attr.Val) != "" {
+					w.line.WriteString(" " + attr.Val + " ")
+				}
+			}
+		}
+	}
+
+	block := n.Type == html.ElementNode && (htmlBlockElements[n.DataAtom] || n.DataAtom == atom.Li)
+	if block {
+		w.flush()
+		if n.DataAtom == atom.Li {
+			w.line.WriteString("- ")
+		}
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		w.walk(child)
+	}
+	if block {
+		w.flush()
+		if n.DataAtom != atom.Li {
+			w.text.WriteString("\n")
+		}
+	}
+}
+
+// flush ends the current line
+func (w *htmlTextWriter) flush() {
+	line := collapseSpaces(w.line.String())
+	w.line.Reset()
+	if line == "" || line == "-" {
+		return
+	}
+	w.text.WriteString(line)
+	w.text.WriteString("\n")
+}
+
+// findHTMLElement returns the first element of the given type
+func findHTMLElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if found := findHTMLElement(child, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// nodeText returns the concatenated text below a node
+func nodeText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && htmlSkippedElements[child.DataAtom] {
+			continue
+		}
+		b.WriteString(nodeText(child))
+	}
+	return b.String()
+}
+
+// collapseSpaces trims a string and collapses runs of whitespace
+func collapseSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// htmlAttr returns the value of an element's attribute, or ""
+func htmlAttr(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// isTeXScript reports whether an element is a MathJax script holding TeX
+// source, such as <script type="math/tex; mode=display">
+func isTeXScript(n *html.Node) bool {
+	return n.DataAtom == atom.Script && strings.HasPrefix(htmlAttr(n, "type"), "math/tex")
+}
+
+// isRenderedMath reports whether an element is the visual rendering of an
+// equation whose source is given elsewhere, so its text would be a garbled
+// duplicate
+func isRenderedMath(n *html.Node) bool {
+	for _, class := range strings.Fields(htmlAttr(n, "class")) {
+		switch class {
+		case "katex-html", "MathJax", "MathJax_Display", "MathJax_Preview", "MathJax_CHTML", "MathJax_SVG":
+			return true
+		}
+	}
+	return false
+}
+
+// htmlMath returns an equation as delimited LaTeX: from a TeX script, or
+// from a MathML element's TeX annotation or alt text. MathML without
+// either is written as its plain text.
+func htmlMath(n *html.Node) string {
+	if isTeXScript(n) {
+		tex := collapseSpaces(nodeText(n))
+		if strings.Contains(htmlAttr(n, "type"), "mode=display") {
+			return "$$" + tex + "$$"
+		}
+		return "$" + tex + "$"
+	}
+
+	tex := ""
+	if annotation := findTeXAnnotation(n); annotation != nil {
+		tex = collapseSpaces(nodeText(annotation))
+	}
+	if tex == "" {
+		tex = collapseSpaces(htmlAttr(n, "alttext"))
+	}
+	if tex == "" {
+		return collapseSpaces(nodeText(n))
+	}
+	if htmlAttr(n, "display") == "block" {
+		return "$$" + tex + "$$"
+	}
+	return "$" + tex + "$"
+}
+
+// findTeXAnnotation returns the TeX annotation of a MathML element
+func findTeXAnnotation(n *html.Node) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == atom.Annotation && htmlAttr(n, "encoding") == "application/x-tex" {
+		return n
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if found := findTeXAnnotation(child); found != nil {
+			return found
+		}
+	}
+	return nil
+}
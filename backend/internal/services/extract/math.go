@@ -0,0 +1,316 @@
+package extract
+
+import (
+	"encoding/xml"
+	"strings"
+	"unicode"
+)
+
+// ommlNamespace is the namespace of Office Math Markup in DOCX files
+const ommlNamespace = "http://schemas.openxmlformats.org/officeDocument/2006/math"
+
+// latexSymbols maps Unicode math symbols to LaTeX
+var latexSymbols = map[rune]string{
+	// Greek letters
+	'α': `\alpha`, 'β': `\beta`, 'γ': `\gamma`, 'δ': `\delta`, 'ε': `\epsilon`,
+	'ζ': `\zeta`, 'η': `\eta`, 'θ': `\theta`, 'ι': `\iota`, 'κ': `\kappa`,
+	'λ': `\lambda`, 'μ': `\mu`, 'ν': `\nu`, 'ξ': `\xi`, 'π': `\pi`,
+	'ρ': `\rho`, 'σ': `\sigma`, 'τ': `\tau`, 'υ': `\upsilon`, 'φ': `\phi`,
+	'χ': `\chi`, 'ψ': `\psi`, 'ω': `\omega`, 'ϕ': `\varphi`, 'ϵ': `\varepsilon`,
+	'Γ': `\Gamma`, 'Δ': `\Delta`, 'Θ': `\Theta`, 'Λ': `\Lambda`, 'Ξ': `\Xi`,
+	'Π': `\Pi`, 'Σ': `\Sigma`, 'Φ': `\Phi`, 'Ψ': `\Psi`, 'Ω': `\Omega`,
+	// Operators and relations
+	'±': `\pm`, '∓': `\mp`, '×': `\times`, '÷': `\div`, '·': `\cdot`, '⋅': `\cdot`,
+	'−': `-`, '≤': `\leq`, '≥': `\geq`, '≠': `\neq`, '≈': `\approx`, '≡': `\equiv`,
+	'∼': `\sim`, '≅': `\cong`, '∝': `\propto`, '≪': `\ll`, '≫': `\gg`,
+	'∞': `\infty`, '∂': `\partial`, '∇': `\nabla`, '√': `\sqrt`, '∠': `\angle`,
+	'∑': `\sum`, '∏': `\prod`, '∫': `\int`, '∬': `\iint`, '∮': `\oint`,
+	'∈': `\in`, '∉': `\notin`, '⊂': `\subset`, '⊆': `\subseteq`, '∪': `\cup`,
+	'∩': `\cap`, '∅': `\emptyset`, '∀': `\forall`, '∃': `\exists`, '¬': `\neg`,
+	'∧': `\wedge`, '∨': `\vee`, '→': `\to`, '←': `\leftarrow`, '↔': `\leftrightarrow`,
+	'⇒': `\Rightarrow`, '⇐': `\Leftarrow`, '⇔': `\Leftrightarrow`, '°': `^\circ`,
+	'′': `'`, '″': `''`, 'ℏ': `\hbar`, 'ħ': `\hbar`, 'ℓ': `\ell`, '…': `\ldots`, '⋯': `\cdots`,
+	// Super- and subscript digits
+	'⁰': `^{0}`, '¹': `^{1}`, '²': `^{2}`, '³': `^{3}`, '⁴': `^{4}`,
+	'⁵': `^{5}`, '⁶': `^{6}`, '⁷': `^{7}`, '⁸': `^{8}`, '⁹': `^{9}`,
+	'⁺': `^{+}`, '⁻': `^{-}`, 'ⁿ': `^{n}`,
+	'₀': `_{0}`, '₁': `_{1}`, '₂': `_{2}`, '₃': `_{3}`, '₄': `_{4}`,
+	'₅': `_{5}`, '₆': `_{6}`, '₇': `_{7}`, '₈': `_{8}`, '₉': `_{9}`,
+}
+
+// latexFunctions are operator names LaTeX sets upright with a command
+var latexFunctions = map[string]bool{
+	"sin": true, "cos": true, "tan": true, "sec": true, "csc": true, "cot": true,
+	"sinh": true, "cosh": true, "tanh": true, "arcsin": true, "arccos": true, "arctan": true,
+	"log": true, "ln": true, "exp": true, "lim": true, "max": true, "min": true, "det": true,
+}
+
+// equationRelations are the symbols one of which an equation line must contain
+const equationRelations = "=<>≤≥≠≈≡∝→⇒⇔∑∏∫√∂∇"
+
+// latexText rewrites Unicode math symbols in text as LaTeX
+func latexText(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		command, ok := latexSymbols[r]
+		if !ok {
+			b.WriteRune(r)
+			continue
+		}
+		b.WriteString(command)
+		// Keep a command from running into letters that follow it, which
+		// may come from the next run of an equation
+		if last := command[len(command)-1]; strings.HasPrefix(command, `\`) && unicode.IsLetter(rune(last)) {
+			b.WriteString(" ")
+		}
+	}
+	return b.String()
+}
+
+// markEquations wraps lines of text that are equations, such as lines of a
+// derivation in lecture notes, in $$ delimiters as LaTeX. It returns the
+// text and the number of equations marked.
+func markEquations(text string) (string, int) {
+	lines := strings.Split(text, "\n")
+	count := 0
+	for i, line := range lines {
+		if isEquationLine(line) {
+			lines[i] = "$$" + strings.Join(strings.Fields(latexText(line)), " ") + "$$"
+			count++
+		}
+	}
+	return strings.Join(lines, "\n"), count
+}
+
+// isEquationLine reports whether a line of extracted text is an equation
+// rather than prose: it relates terms with a symbol and has at most a couple
+// of words in it
+func isEquationLine(line string) bool {
+	line = strings.TrimSpace(line)
+	if len(line) < 3 || len(line) > 200 || strings.HasPrefix(line, "|") {
+		return false
+	}
+	// Already LaTeX, or a web address
+	if strings.ContainsAny(line, `$\`) || strings.Contains(line, "://") {
+		return false
+	}
+	if !strings.ContainsAny(line, equationRelations) {
+		return false
+	}
+
+	words := 0
+	for _, word := range strings.FieldsFunc(line, func(r rune) bool { return !unicode.IsLetter(r) }) {
+		// Greek letters and operator names are symbols, not words
+		if len([]rune(word)) >= 4 && !latexFunctions[strings.ToLower(word)] && latexSymbols[[]rune(word)[0]] == "" {
+			words++
+		}
+	}
+	return words <= 2
+}
+
+// ommlNode is an element of an Office Math Markup tree
+type ommlNode struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Text    string     `xml:",chardata"`
+	Nodes   []ommlNode `xml:",any"`
+}
+
+// child returns the first child element with the given local name
+func (n *ommlNode) child(name string) *ommlNode {
+	for i := range n.Nodes {
+		if n.Nodes[i].XMLName.Local == name {
+			return &n.Nodes[i]
+		}
+	}
+	return nil
+}
+
+// property returns the val attribute of a property, such as the chr of a
+// nary operator's naryPr, or def when it isn't set
+func (n *ommlNode) property(properties, name, def string) string {
+	if pr := n.child(properties); pr != nil {
+		if p := pr.child(name); p != nil {
+			for _, attr := range p.Attrs {
+				if attr.Name.Local == "val" {
+					return attr.Value
+				}
+			}
+		}
+	}
+	return def
+}
+
+// latex converts the named child element, or returns "" when it is missing
+func (n *ommlNode) latex(name string) string {
+	if c := n.child(name); c != nil {
+		return ommlToLaTeX(c)
+	}
+	return ""
+}
+
+// ommlToLaTeX converts an Office Math element to LaTeX
+func ommlToLaTeX(n *ommlNode) string {
+	switch n.XMLName.Local {
+	case "t":
+		return latexText(n.Text)
+	case "f":
+		if n.property("fPr", "type", "bar") == "lin" {
+			return n.latex("num") + "/" + n.latex("den")
+		}
+		return `\frac{` + n.latex("num") + "}{" + n.latex("den") + "}"
+	case "sSup":
+		return "{" + n.latex("e") + "}^{" + n.latex("sup") + "}"
+	case "sSub":
+		return "{" + n.latex("e") + "}_{" + n.latex("sub") + "}"
+	case "sSubSup":
+		return "{" + n.latex("e") + "}_{" + n.latex("sub") + "}^{" + n.latex("sup") + "}"
+	case "sPre":
+		return "{}_{" + n.latex("sub") + "}^{" + n.latex("sup") + "}{" + n.latex("e") + "}"
+	case "rad":
+		if degree := n.latex("deg"); degree != "" {
+			return `\sqrt[` + degree + "]{" + n.latex("e") + "}"
+		}
+		return `\sqrt{` + n.latex("e") + "}"
+	case "nary":
+		operator := latexText(n.property("naryPr", "chr", "∫"))
+		if sub := n.latex("sub"); sub != "" {
+			operator += "_{" + sub + "}"
+		}
+		if sup := n.latex("sup"); sup != "" {
+			operator += "^{" + sup + "}"
+		}
+		return operator + " " + n.latex("e")
+	case "d":
+		var parts []string
+		for i := range n.Nodes {
+			if n.Nodes[i].XMLName.Local == "e" {
+				parts = append(parts, ommlToLaTeX(&n.Nodes[i]))
+			}
+		}
+		separator := n.property("dPr", "sepChr", "|")
+		return `\left` + latexDelimiter(n.property("dPr", "begChr", "(")) + " " +
+			strings.Join(parts, latexText(separator)) +
+			` \right` + latexDelimiter(n.property("dPr", "endChr", ")"))
+	case "func":
+		return strings.TrimSpace(latexOperator(n.latex("fName"))) + " " + n.latex("e")
+	case "acc":
+		return latexAccent(n.property("accPr", "chr", "̂")) + "{" + n.latex("e") + "}"
+	case "bar":
+		if n.property("barPr", "pos", "bot") == "top" {
+			return `\overline{` + n.latex("e") + "}"
+		}
+		return `\underline{` + n.latex("e") + "}"
+	case "limLow":
+		return "{" + strings.TrimSpace(latexOperator(n.latex("e"))) + "}_{" + n.latex("lim") + "}"
+	case "limUpp":
+		return "{" + n.latex("e") + "}^{" + n.latex("lim") + "}"
+	case "m":
+		var rows []string
+		for i := range n.Nodes {
+			if n.Nodes[i].XMLName.Local != "mr" {
+				continue
+			}
+			var cells []string
+			for j := range n.Nodes[i].Nodes {
+				if n.Nodes[i].Nodes[j].XMLName.Local == "e" {
+					cells = append(cells, ommlToLaTeX(&n.Nodes[i].Nodes[j]))
+				}
+			}
+			rows = append(rows, strings.Join(cells, " & "))
+		}
+		return `\begin{matrix} ` + strings.Join(rows, ` \\ `) + ` \end{matrix}`
+	case "eqArr":
+		var rows []string
+		for i := range n.Nodes {
+			if n.Nodes[i].XMLName.Local == "e" {
+				rows = append(rows, ommlToLaTeX(&n.Nodes[i]))
+			}
+		}
+		return `\begin{aligned} ` + strings.Join(rows, ` \\ `) + ` \end{aligned}`
+	case "oMathPara":
+		var lines []string
+		for i := range n.Nodes {
+			if n.Nodes[i].XMLName.Local == "oMath" {
+				lines = append(lines, ommlToLaTeX(&n.Nodes[i]))
+			}
+		}
+		return strings.Join(lines, ` \\ `)
+	}
+
+	// Property elements carry formatting only
+	if strings.HasSuffix(n.XMLName.Local, "Pr") {
+		return ""
+	}
+	// Runs and argument containers such as e, num and sub
+	var b strings.Builder
+	for i := range n.Nodes {
+		b.WriteString(ommlToLaTeX(&n.Nodes[i]))
+	}
+	return b.String()
+}
+
+// ommlEquation converts an oMath or oMathPara element to a delimited LaTeX
+// span: inline math in $ and display math in $$
+func ommlEquation(n *ommlNode) string {
+	latex := strings.Join(strings.Fields(ommlToLaTeX(n)), " ")
+	if latex == "" {
+		return ""
+	}
+	if n.XMLName.Local == "oMathPara" {
+		return "$$" + latex + "$$"
+	}
+	return "$" + latex + "$"
+}
+
+// latexDelimiter converts a delimiter character for use after \left or \right
+func latexDelimiter(chr string) string {
+	switch chr {
+	case "":
+		return "."
+	case "{":
+		return `\{`
+	case "}":
+		return `\}`
+	case "〈", "⟨":
+		return `\langle`
+	case "〉", "⟩":
+		return `\rangle`
+	case "‖":
+		return `\|`
+	case "⌊":
+		return `\lfloor`
+	case "⌋":
+		return `\rfloor`
+	case "⌈":
+		return `\lceil`
+	case "⌉":
+		return `\rceil`
+	}
+	return chr
+}
+
+// latexAccent returns the LaTeX command for a combining accent character
+func latexAccent(chr string) string {
+	switch chr {
+	case "̇":
+		return `\dot`
+	case "̈":
+		return `\ddot`
+	case "̃":
+		return `\tilde`
+	case "⃗", "→":
+		return `\vec`
+	case "̄", "̅":
+		return `\bar`
+	}
+	return `\hat`
+}
+
+// latexOperator turns an operator name such as "lim" into its command
+func latexOperator(text string) string {
+	if name := strings.TrimSpace(text); latexFunctions[name] {
+		return `\` + name
+	}
+	return text
+}
@@ -0,0 +1,143 @@
+package extract
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// OCR recognises text on PDF pages that have no extractable text layer,
+// such as scanned past-question papers
+type OCR interface {
+	RecognizePDFPage(ctx context.Context, pdfPath string, page int) (*OCRResult, error)
+}
+
+// OCRResult represents the text recognised on a single page
+type OCRResult struct {
+	Text       string  `json:"text"`
+	Confidence float64 `json:"confidence"` // Mean word confidence, 0-100
+}
+
+// TesseractOCR runs OCR with the tesseract CLI, rasterising pages with pdftoppm
+type TesseractOCR struct {
+	tesseractPath string
+	pdftoppmPath  string
+	language      string
+	dpi           int
+}
+
+// NewTesseractOCR creates a Tesseract-backed OCR engine
+func NewTesseractOCR(language string) *TesseractOCR {
+	if language == "" {
+		language = "eng"
+	}
+
+	return &TesseractOCR{
+		tesseractPath: "tesseract",
+		pdftoppmPath:  "pdftoppm",
+		language:      language,
+		dpi:           300,
+	}
+}
+
+// RecognizePDFPage rasterises a page and runs tesseract over the image
+func (t *TesseractOCR) RecognizePDFPage(ctx context.Context, pdfPath string, page int) (*OCRResult, error) {
+	workDir, err := os.MkdirTemp("", "edupro-ocr-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OCR work directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	// Rasterise the single page to PNG
+	imagePrefix := filepath.Join(workDir, "page")
+	pageArg := strconv.Itoa(page)
+	rasterise := exec.CommandContext(ctx, t.pdftoppmPath,
+		"-f", pageArg, "-l", pageArg,
+		"-r", strconv.Itoa(t.dpi),
+		"-png", "-singlefile",
+		pdfPath, imagePrefix,
+	)
+	if output, err := rasterise.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("failed to rasterise page %d: %w: %s", page, err, strings.TrimSpace(string(output)))
+	}
+
+	// TSV output gives per-word confidences alongside the text
+	var stdout, stderr bytes.Buffer
+	recognise := exec.CommandContext(ctx, t.tesseractPath, imagePrefix+".png", "stdout", "-l", t.language, "tsv")
+	recognise.Stdout = &stdout
+	recognise.Stderr = &stderr
+	if err := recognise.Run(); err != nil {
+		return nil, fmt.Errorf("tesseract failed on page %d: %w: %s", page, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return parseTesseractTSV(stdout.String()), nil
+}
+
+// parseTesseractTSV rebuilds line-broken text from tesseract TSV output and
+// averages the confidence of the recognised words
+func parseTesseractTSV(tsv string) *OCRResult {
+	var textBuilder strings.Builder
+	var lastLine string
+	var confidenceSum float64
+	words := 0
+
+	scanner := bufio.NewScanner(strings.NewReader(tsv))
+	for scanner.Scan() {
+		// level page_num block_num par_num line_num word_num left top width height conf text
+		fields := strings.SplitN(scanner.Text(), "\t", 12)
+		if len(fields) < 12 || fields[0] != "5" {
+			continue
+		}
+
+		word := strings.TrimSpace(fields[11])
+		confidence, err := strconv.ParseFloat(fields[10], 64)
+		if word == "" || err != nil || confidence < 0 {
+			continue
+		}
+
+		lineKey := strings.Join(fields[2:5], ".")
+		if textBuilder.Len() > 0 {
+			if lineKey != lastLine {
+				textBuilder.WriteString("\n")
+			} else {
+				textBuilder.WriteString(" ")
+			}
+		}
+		lastLine = lineKey
+
+		textBuilder.WriteString(word)
+		confidenceSum += confidence
+		words++
+	}
+
+	result := &OCRResult{Text: textBuilder.String()}
+	if words > 0 {
+		result.Confidence = confidenceSum / float64(words)
+	}
+	return result
+}
+
+// FakeOCR returns canned results per page. It lets the extraction pipeline
+// be exercised without tesseract installed.
+type FakeOCR struct {
+	Pages map[int]OCRResult
+	Err   error
+}
+
+// RecognizePDFPage returns the canned result for the page
+func (f *FakeOCR) RecognizePDFPage(ctx context.Context, pdfPath string, page int) (*OCRResult, error) {
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	result, ok := f.Pages[page]
+	if !ok {
+		return &OCRResult{}, nil
+	}
+	return &result, nil
+}
@@ -0,0 +1,143 @@
+package extract
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// buildPDF returns a PDF with one page per content stream, all set in
+// Helvetica. An empty stream makes an image-only page with no text layer.
+func buildPDF(contents ...string) []byte {
+	return buildPDFStreams("", contents...)
+}
+
+// buildPDFStreams is buildPDF with the content streams already encoded by
+// filter, such as FlateDecode
+func buildPDFStreams(filter string, contents ...string) []byte {
+	streamDict := ""
+	if filter != "" {
+		streamDict = " /Filter /" + filter
+	}
+
+	var objects []string
+	objects = append(objects, "<< /Type /Catalog /Pages 2 0 R >>")
+
+	kids := ""
+	for i := range contents {
+		kids += fmt.Sprintf("%d 0 R ", 4+2*i)
+	}
+	objects = append(objects, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", kids, len(contents)))
+	objects = append(objects, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	for i, content := range contents {
+		objects = append(objects, fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", 5+2*i))
+		objects = append(objects, fmt.Sprintf("<< /Length %d%s >>\nstream\n%s\nendstream", len(content), streamDict, content))
+	}
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return b.Bytes()
+}
+
+// textPage is a content stream that draws a line of text
+func textPage(text string) string {
+	return fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+}
+
+func TestExtractPDFWithFakeOCR(t *testing.T) {
+	document := buildPDF(
+		textPage("Heat flows from hot bodies to colder ones."),
+		"",
+		textPage("Entropy of an isolated system never decreases."),
+	)
+
+	tests := []struct {
+		name         string
+		ocr          *FakeOCR
+		wantPages    []int
+		wantOCRPages int
+		wantOCRText  string
+	}{
+		{
+			name: "image-only page is recognised",
+			ocr: &FakeOCR{Pages: map[int]OCRResult{
+				2: {Text: "Scanned notes on the second law", Confidence: 87.5},
+			}},
+			wantPages:    []int{1, 2, 3},
+			wantOCRPages: 1,
+			wantOCRText:  "Scanned notes on the second law",
+		},
+		{
+			name:      "blank OCR result skips the page",
+			ocr:       &FakeOCR{},
+			wantPages: []int{1, 3},
+		},
+		{
+			name:      "OCR error skips the page",
+			ocr:       &FakeOCR{Err: errors.New("tesseract not installed")},
+			wantPages: []int{1, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient()
+			client.SetOCR(tt.ocr)
+
+			_, metadata, sections, err := client.extractFromPDF(bytes.NewReader(document))
+			if err != nil {
+				t.Fatalf("extractFromPDF() error = %v", err)
+			}
+			if got := metadata["ocr_page_count"]; got != tt.wantOCRPages {
+				t.Errorf("ocr_page_count = %v, want %d", got, tt.wantOCRPages)
+			}
+
+			var pages []int
+			for _, section := range sections {
+				page := section.Metadata["page"].(int)
+				pages = append(pages, page)
+
+				ocr := section.Metadata["ocr"].(bool)
+				if page != 2 {
+					if ocr {
+						t.Errorf("page %d tagged as OCR", page)
+					}
+					if _, ok := section.Metadata["ocr_confidence"]; ok {
+						t.Errorf("page %d has an OCR confidence", page)
+					}
+					if !strings.Contains(section.Text, "isolated system") && !strings.Contains(section.Text, "hot bodies") {
+						t.Errorf("page %d text = %q", page, section.Text)
+					}
+					continue
+				}
+
+				if !ocr {
+					t.Errorf("page 2 not tagged as OCR")
+				}
+				if got := section.Metadata["ocr_confidence"]; got != tt.ocr.Pages[2].Confidence {
+					t.Errorf("page 2 ocr_confidence = %v, want %v", got, tt.ocr.Pages[2].Confidence)
+				}
+				if section.Text != tt.wantOCRText {
+					t.Errorf("page 2 text = %q, want %q", section.Text, tt.wantOCRText)
+				}
+			}
+			if fmt.Sprint(pages) != fmt.Sprint(tt.wantPages) {
+				t.Errorf("pages = %v, want %v", pages, tt.wantPages)
+			}
+		})
+	}
+}
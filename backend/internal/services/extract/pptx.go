@@ -0,0 +1,322 @@
+package extract
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// pptxShape represents the text of a single shape on a slide
+type pptxShape struct {
+	Placeholder string
+	Paragraphs  []string
+}
+
+// extractFromPPTX extracts slide titles, body text and speaker notes from PPTX files.
+// Each slide becomes its own section so chunks can be traced back to a slide number.
+func (c *Client) extractFromPPTX(reader io.Reader) (string, map[string]interface{}, []Section, error) {
+	// zip requires random access, so work from a file rather than memory
+	file, cleanup, err := fileFromReader(reader, "edupro-*.pptx")
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to read PPTX content: %w", err)
+	}
+	defer cleanup()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to read PPTX content: %w", err)
+	}
+
+	archive, err := zip.NewReader(file, info.Size())
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to open PPTX: %w", err)
+	}
+
+	files := make(map[string]*zip.File)
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+
+	slidePaths, err := c.pptxSlideOrder(files)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	var textBuilder strings.Builder
+	var sections []Section
+	notesCount := 0
+
+	for i, slidePath := range slidePaths {
+		slideNumber := i + 1
+
+		slideFile, ok := files[slidePath]
+		if !ok {
+			continue
+		}
+
+		shapes, err := c.parsePPTXShapes(slideFile)
+		if err != nil {
+			return "", nil, nil, fmt.Errorf("failed to parse slide %d: %w", slideNumber, err)
+		}
+
+		var title string
+		var body []string
+		for _, shape := range shapes {
+			switch shape.Placeholder {
+			case "title", "ctrTitle":
+				if title == "" {
+					title = strings.Join(shape.Paragraphs, " ")
+					continue
+				}
+			case "sldNum", "dt", "ftr":
+				// Slide furniture, not content
+				continue
+			}
+			body = append(body, shape.Paragraphs...)
+		}
+
+		notes, err := c.pptxSlideNotes(files, slidePath)
+		if err != nil {
+			return "", nil, nil, fmt.Errorf("failed to parse notes for slide %d: %w", slideNumber, err)
+		}
+		if notes != "" {
+			notesCount++
+		}
+
+		// A slide with nothing to read would only make an empty chunk
+		if title == "" && len(body) == 0 && notes == "" {
+			continue
+		}
+
+		var slideText strings.Builder
+		if title != "" {
+			slideText.WriteString(fmt.Sprintf("Slide %d: %s\n", slideNumber, title))
+		} else {
+			slideText.WriteString(fmt.Sprintf("Slide %d\n", slideNumber))
+		}
+		for _, line := range body {
+			slideText.WriteString(line)
+			slideText.WriteString("\n")
+		}
+		if notes != "" {
+			slideText.WriteString("Speaker notes: ")
+			slideText.WriteString(notes)
+			slideText.WriteString("\n")
+		}
+
+		sectionMetadata := map[string]interface{}{
+			"slide": slideNumber,
+		}
+		if title != "" {
+			sectionMetadata["slide_title"] = title
+		}
+
+		sections = append(sections, Section{
+			Text:     slideText.String(),
+			Metadata: sectionMetadata,
+		})
+
+		textBuilder.WriteString(slideText.String())
+		textBuilder.WriteString("\n")
+	}
+
+	metadata := map[string]interface{}{
+		"format":      "PPTX",
+		"slide_count": len(slidePaths),
+		"notes_count": notesCount,
+	}
+
+	return textBuilder.String(), metadata, sections, nil
+}
+
+// pptxSlideOrder returns slide part paths in presentation order
+func (c *Client) pptxSlideOrder(files map[string]*zip.File) ([]string, error) {
+	presentationFile, ok := files["ppt/presentation.xml"]
+	if !ok {
+		return nil, fmt.Errorf("invalid PPTX: missing ppt/presentation.xml")
+	}
+
+	var presentation struct {
+		SlideIDs []struct {
+			RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sldIdLst>sldId"`
+	}
+	if err := c.decodeZipXML(presentationFile, &presentation); err != nil {
+		return nil, fmt.Errorf("failed to parse PPTX presentation: %w", err)
+	}
+
+	rels, err := c.readRelationships(files, "ppt/presentation.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	var slidePaths []string
+	for _, slide := range presentation.SlideIDs {
+		if rel, ok := rels[slide.RelID]; ok {
+			slidePaths = append(slidePaths, rel.Target)
+		}
+	}
+	return slidePaths, nil
+}
+
+// pptxSlideNotes returns the speaker notes for a slide, if any
+func (c *Client) pptxSlideNotes(files map[string]*zip.File, slidePath string) (string, error) {
+	rels, err := c.readRelationships(files, slidePath)
+	if err != nil {
+		return "", err
+	}
+
+	for _, rel := range rels {
+		if !strings.HasSuffix(rel.Type, "/notesSlide") {
+			continue
+		}
+
+		notesFile, ok := files[rel.Target]
+		if !ok {
+			return "", nil
+		}
+
+		shapes, err := c.parsePPTXShapes(notesFile)
+		if err != nil {
+			return "", err
+		}
+
+		// The notes text lives in the body placeholder; the others hold
+		// the slide image and slide number
+		var notes []string
+		for _, shape := range shapes {
+			if shape.Placeholder == "body" {
+				notes = append(notes, shape.Paragraphs...)
+			}
+		}
+		return strings.Join(notes, " "), nil
+	}
+
+	return "", nil
+}
+
+// parsePPTXShapes collects the paragraphs of every shape and table in a slide part
+func (c *Client) parsePPTXShapes(f *zip.File) ([]pptxShape, error) {
+	rc, err := c.openZipPart(f)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	decoder := xml.NewDecoder(rc)
+
+	var shapes []pptxShape
+	var current *pptxShape
+	var paragraph strings.Builder
+	inText := false
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "sp", "graphicFrame":
+				current = &pptxShape{}
+			case "ph":
+				if current != nil {
+					current.Placeholder = attrValue(t, "type")
+					if current.Placeholder == "" {
+						// A placeholder without a type is a body placeholder
+						current.Placeholder = "body"
+					}
+				}
+			case "p":
+				paragraph.Reset()
+			case "t":
+				inText = true
+			case "br":
+				paragraph.WriteString(" ")
+			}
+		case xml.CharData:
+			if inText {
+				paragraph.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				text := strings.TrimSpace(paragraph.String())
+				if text != "" && current != nil {
+					current.Paragraphs = append(current.Paragraphs, text)
+				}
+			case "sp", "graphicFrame":
+				if current != nil && len(current.Paragraphs) > 0 {
+					shapes = append(shapes, *current)
+				}
+				current = nil
+			}
+		}
+	}
+
+	return shapes, nil
+}
+
+// relationship represents an entry in an OPC .rels part
+type relationship struct {
+	ID     string
+	Type   string
+	Target string // resolved to a path within the package
+}
+
+// readRelationships reads the relationships of a package part, resolving
+// targets relative to the part's directory
+func (c *Client) readRelationships(files map[string]*zip.File, partPath string) (map[string]relationship, error) {
+	dir, name := path.Split(partPath)
+	relsFile, ok := files[dir+"_rels/"+name+".rels"]
+	if !ok {
+		return map[string]relationship{}, nil
+	}
+
+	var rels struct {
+		Relationships []struct {
+			ID         string `xml:"Id,attr"`
+			Type       string `xml:"Type,attr"`
+			Target     string `xml:"Target,attr"`
+			TargetMode string `xml:"TargetMode,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := c.decodeZipXML(relsFile, &rels); err != nil {
+		return nil, fmt.Errorf("failed to parse relationships for %s: %w", partPath, err)
+	}
+
+	result := make(map[string]relationship, len(rels.Relationships))
+	for _, rel := range rels.Relationships {
+		if rel.TargetMode == "External" {
+			continue
+		}
+		target := rel.Target
+		if strings.HasPrefix(target, "/") {
+			target = strings.TrimPrefix(target, "/")
+		} else {
+			target = path.Clean(path.Join(dir, target))
+		}
+		result[rel.ID] = relationship{ID: rel.ID, Type: rel.Type, Target: target}
+	}
+	return result, nil
+}
+
+// decodeZipXML decodes an XML part of a zip archive into v
+func (c *Client) decodeZipXML(f *zip.File, v interface{}) error {
+	rc, err := c.openZipPart(f)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
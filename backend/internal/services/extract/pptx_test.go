@@ -0,0 +1,169 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	presentationNamespaces = `xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" ` +
+		`xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+	relationshipsNamespace = `xmlns="http://schemas.openxmlformats.org/package/2006/relationships"`
+	slideRelType           = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide"
+	notesRelType           = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/notesSlide"
+)
+
+// zipFiles indexes the parts of a zip archive by name
+func zipFiles(t *testing.T, content []byte) map[string]*zip.File {
+	t.Helper()
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]*zip.File)
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+	return files
+}
+
+// slideShape is a shape holding one paragraph per line, with an optional placeholder type
+func slideShape(placeholder string, lines ...string) string {
+	props := `<p:nvSpPr><p:nvPr/></p:nvSpPr>`
+	if placeholder != "" {
+		props = `<p:nvSpPr><p:nvPr><p:ph type="` + placeholder + `"/></p:nvPr></p:nvSpPr>`
+	}
+	var paragraphs strings.Builder
+	for _, line := range lines {
+		paragraphs.WriteString(`<a:p><a:r><a:t>` + line + `</a:t></a:r></a:p>`)
+	}
+	return `<p:sp>` + props + `<p:txBody>` + paragraphs.String() + `</p:txBody></p:sp>`
+}
+
+// slidePart wraps shapes in a slide or notes part
+func slidePart(shapes ...string) string {
+	return `<p:sld ` + presentationNamespaces + `><p:cSld><p:spTree>` + strings.Join(shapes, "") + `</p:spTree></p:cSld></p:sld>`
+}
+
+func TestExtractPPTX(t *testing.T) {
+	// The presentation lists slide3.xml first, so part names don't set the order
+	presentation := `<p:presentation ` + presentationNamespaces + `><p:sldIdLst>
+		<p:sldId id="256" r:id="rId3"/>
+		<p:sldId id="257" r:id="rId1"/>
+		<p:sldId id="258" r:id="rId2"/>
+	</p:sldIdLst></p:presentation>`
+	presentationRels := `<Relationships ` + relationshipsNamespace + `>
+		<Relationship Id="rId1" Type="` + slideRelType + `" Target="slides/slide1.xml"/>
+		<Relationship Id="rId2" Type="` + slideRelType + `" Target="slides/slide2.xml"/>
+		<Relationship Id="rId3" Type="` + slideRelType + `" Target="slides/slide3.xml"/>
+	</Relationships>`
+	slideRels := `<Relationships ` + relationshipsNamespace + `>
+		<Relationship Id="rId1" Type="` + notesRelType + `" Target="../notesSlides/notesSlide1.xml"/>
+	</Relationships>`
+
+	document := zipPackage(t, map[string]string{
+		"ppt/presentation.xml":            presentation,
+		"ppt/_rels/presentation.xml.rels": presentationRels,
+		"ppt/slides/slide3.xml": slidePart(
+			slideShape("ctrTitle", "Thermodynamics"),
+			slideShape("subTitle", "Week 1"),
+			slideShape("sldNum", "1"),
+		),
+		"ppt/slides/slide1.xml": slidePart(
+			slideShape("title", "First law"),
+			slideShape("body", "Energy is conserved", "Heat equals work"),
+			slideShape("ftr", "CSC 201"),
+		),
+		"ppt/slides/_rels/slide1.xml.rels": slideRels,
+		"ppt/notesSlides/notesSlide1.xml": slidePart(
+			slideShape("sldImg"),
+			slideShape("body", "Derive it on the board."),
+			slideShape("sldNum", "2"),
+		),
+		// Only slide furniture, so there's nothing to read
+		"ppt/slides/slide2.xml": slidePart(slideShape("sldNum", "3"), slideShape("dt", "1 October")),
+	})
+
+	text, metadata, sections, err := NewClient().extractFromPPTX(bytes.NewReader(document))
+	if err != nil {
+		t.Fatalf("extractFromPPTX() error = %v", err)
+	}
+
+	want := []struct {
+		text     string
+		metadata map[string]interface{}
+	}{
+		{
+			"Slide 1: Thermodynamics\nWeek 1\n",
+			map[string]interface{}{"slide": 1, "slide_title": "Thermodynamics"},
+		},
+		{
+			"Slide 2: First law\nEnergy is conserved\nHeat equals work\nSpeaker notes: Derive it on the board.\n",
+			map[string]interface{}{"slide": 2, "slide_title": "First law"},
+		},
+	}
+	if len(sections) != len(want) {
+		t.Fatalf("got %d sections, want %d: %+v", len(sections), len(want), sections)
+	}
+	for i, w := range want {
+		if sections[i].Text != w.text {
+			t.Errorf("section %d text = %q, want %q", i, sections[i].Text, w.text)
+		}
+		if !reflect.DeepEqual(sections[i].Metadata, w.metadata) {
+			t.Errorf("section %d metadata = %v, want %v", i, sections[i].Metadata, w.metadata)
+		}
+	}
+
+	if wantText := want[0].text + "\n" + want[1].text + "\n"; text != wantText {
+		t.Errorf("text = %q, want %q", text, wantText)
+	}
+	if metadata["slide_count"] != 3 || metadata["notes_count"] != 1 {
+		t.Errorf("metadata = %v", metadata)
+	}
+}
+
+func TestExtractPPTXWithoutPresentation(t *testing.T) {
+	document := zipPackage(t, map[string]string{"ppt/slides/slide1.xml": slidePart(slideShape("title", "Orphan"))})
+	if _, _, _, err := NewClient().extractFromPPTX(bytes.NewReader(document)); err == nil {
+		t.Error("extractFromPPTX() succeeded without ppt/presentation.xml")
+	}
+}
+
+func TestReadRelationships(t *testing.T) {
+	rels := `<Relationships ` + relationshipsNamespace + `>
+		<Relationship Id="rId1" Type="` + slideRelType + `" Target="slides/slide1.xml"/>
+		<Relationship Id="rId2" Type="` + notesRelType + `" Target="../notesSlides/notesSlide1.xml"/>
+		<Relationship Id="rId3" Type="` + slideRelType + `" Target="/ppt/slides/slide9.xml"/>
+		<Relationship Id="rId4" Type="hyperlink" Target="https://example.com" TargetMode="External"/>
+	</Relationships>`
+	document := zipPackage(t, map[string]string{"ppt/slides/_rels/slide1.xml.rels": rels})
+
+	client := NewClient()
+	files := zipFiles(t, document)
+
+	got, err := client.readRelationships(files, "ppt/slides/slide1.xml")
+	if err != nil {
+		t.Fatalf("readRelationships() error = %v", err)
+	}
+	targets := make(map[string]string)
+	for id, rel := range got {
+		targets[id] = rel.Target
+	}
+	want := map[string]string{
+		"rId1": "ppt/slides/slides/slide1.xml",
+		"rId2": "ppt/notesSlides/notesSlide1.xml",
+		"rId3": "ppt/slides/slide9.xml",
+	}
+	if !reflect.DeepEqual(targets, want) {
+		t.Errorf("targets = %v, want %v", targets, want)
+	}
+
+	// A part without relationships has none
+	if got, err := client.readRelationships(files, "ppt/slides/slide2.xml"); err != nil || len(got) != 0 {
+		t.Errorf("readRelationships(slide2) = %v, %v", got, err)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+
+package extract
+
+// limitAddressSpace is unsupported on this platform; only the timeout and
+// the garbage collector's soft limit apply
+func limitAddressSpace(limit int64) error {
+	return nil
+}
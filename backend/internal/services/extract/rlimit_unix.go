@@ -0,0 +1,11 @@
+//go:build linux || darwin
+
+package extract
+
+import "syscall"
+
+// limitAddressSpace caps the virtual memory of the current process and the
+// OCR tools it starts
+func limitAddressSpace(limit int64) error {
+	return syscall.Setrlimit(syscall.RLIMIT_AS, &syscall.Rlimit{Cur: uint64(limit), Max: uint64(limit)})
+}
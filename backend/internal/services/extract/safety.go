@@ -0,0 +1,149 @@
+package extract
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// ErrUnsafeDocument is returned for documents that exceed the safety limits
+// or that stall or exhaust memory during extraction. Retrying won't help.
+var ErrUnsafeDocument = errors.New("document exceeds safety limits")
+
+// ErrExtractionTimeout is returned when extraction runs past the timeout.
+// Unlike ErrUnsafeDocument it may be retried, as a large document can
+// simply have been slow on a busy host.
+var ErrExtractionTimeout = errors.New("extraction timed out")
+
+// minStreamBudget is the decompressed size every content stream may reach
+// regardless of its compression ratio, so small but legitimately well
+// compressed pages aren't rejected
+const minStreamBudget = 1 << 20
+
+// Limits bounds the resources extraction of a single document may use. Zero
+// values disable the corresponding limit.
+type Limits struct {
+	MaxPDFPages   int
+	MaxPDFObjects int64
+	// Decompressed to compressed size of a PDF page content stream
+	MaxPDFRatio float64
+	// Size of a plain text file, which is read into memory whole
+	MaxTextBytes int64
+	// Decompressed size and compression ratio of a single part of a DOCX
+	// or PPTX package, the same bounds applied to ZIP uploads
+	MaxZipPartSize  int64
+	MaxZipPartRatio float64
+	// Timeout and MemoryLimit apply to the extraction process; setting
+	// either runs extraction in a separate process
+	Timeout     time.Duration
+	MemoryLimit int64 // bytes
+}
+
+// checkPDFStructure enforces the page and object limits before any page is read
+func (c *Client) checkPDFStructure(reader *pdf.Reader) error {
+	if max := c.limits.MaxPDFPages; max > 0 && reader.NumPage() > max {
+		return fmt.Errorf("%w: %d pages, limit is %d", ErrUnsafeDocument, reader.NumPage(), max)
+	}
+	// The trailer's Size is one more than the highest object number
+	if max := c.limits.MaxPDFObjects; max > 0 {
+		if objects := reader.Trailer().Key("Size").Int64(); objects > max {
+			return fmt.Errorf("%w: %d objects, limit is %d", ErrUnsafeDocument, objects, max)
+		}
+	}
+	return nil
+}
+
+// checkPageStreams decompresses a page's content streams under a budget
+// derived from their compressed size, rejecting decompression bombs before
+// the text extractor reads them into memory
+func (c *Client) checkPageStreams(page pdf.Page, pageNumber int) (err error) {
+	if c.limits.MaxPDFRatio <= 0 {
+		return nil
+	}
+	// The pdf library panics on malformed streams; such pages are left to
+	// the text extractor, which skips them
+	defer func() {
+		if r := recover(); r != nil {
+			err = nil
+		}
+	}()
+
+	contents := page.V.Key("Contents")
+	streams := []pdf.Value{contents}
+	if contents.Kind() == pdf.Array {
+		streams = streams[:0]
+		for i := 0; i < contents.Len(); i++ {
+			streams = append(streams, contents.Index(i))
+		}
+	}
+
+	for _, stream := range streams {
+		if stream.Kind() != pdf.Stream {
+			continue
+		}
+		budget := int64(float64(stream.Key("Length").Int64()) * c.limits.MaxPDFRatio)
+		if budget < minStreamBudget {
+			budget = minStreamBudget
+		}
+
+		rc := stream.Reader()
+		n, _ := io.Copy(io.Discard, io.LimitReader(rc, budget+1))
+		rc.Close()
+		if n > budget {
+			return fmt.Errorf("%w: page %d content expands more than %.0f times", ErrUnsafeDocument, pageNumber, c.limits.MaxPDFRatio)
+		}
+	}
+	return nil
+}
+
+// openZipPart opens a part of an Office package. Parts whose declared size
+// or compression ratio exceeds the limits are rejected up front, and the
+// reader fails once it has decompressed past the size limit, since the
+// declared size can't be trusted.
+func (c *Client) openZipPart(f *zip.File) (io.ReadCloser, error) {
+	maxSize := c.limits.MaxZipPartSize
+	if maxSize > 0 && f.UncompressedSize64 > uint64(maxSize) {
+		return nil, fmt.Errorf("%w: %s expands to %d bytes, limit is %d", ErrUnsafeDocument, f.Name, f.UncompressedSize64, maxSize)
+	}
+	if max := c.limits.MaxZipPartRatio; max > 0 && f.CompressedSize64 > 0 &&
+		float64(f.UncompressedSize64)/float64(f.CompressedSize64) > max {
+		return nil, fmt.Errorf("%w: %s has a compression ratio above %.0f", ErrUnsafeDocument, f.Name, max)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	if maxSize <= 0 {
+		return rc, nil
+	}
+	return &limitedPart{ReadCloser: rc, name: f.Name, remaining: maxSize}, nil
+}
+
+// limitedPart is a zip part reader that fails once more than the limit has
+// been read from it
+type limitedPart struct {
+	io.ReadCloser
+	name      string
+	remaining int64
+}
+
+func (p *limitedPart) Read(b []byte) (int, error) {
+	if p.remaining < 0 {
+		return 0, fmt.Errorf("%w: %s expands past its size limit", ErrUnsafeDocument, p.name)
+	}
+	// Read one byte past the limit so an oversized part is detected
+	if int64(len(b)) > p.remaining+1 {
+		b = b[:p.remaining+1]
+	}
+	n, err := p.ReadCloser.Read(b)
+	p.remaining -= int64(n)
+	if p.remaining < 0 {
+		return 0, fmt.Errorf("%w: %s expands past its size limit", ErrUnsafeDocument, p.name)
+	}
+	return n, err
+}
@@ -0,0 +1,61 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// deflate compresses a content stream for FlateDecode
+func deflate(t *testing.T, content string) string {
+	t.Helper()
+	var b bytes.Buffer
+	w := zlib.NewWriter(&b)
+	if _, err := w.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestExtractPDFLimits(t *testing.T) {
+	threePages := buildPDF(textPage("One"), textPage("Two"), textPage("Three"))
+	// Two megabytes of whitespace squeeze into a couple of kilobytes
+	bomb := buildPDFStreams("FlateDecode", deflate(t, textPage("Bomb")+strings.Repeat(" ", 2<<20)))
+	compressed := buildPDFStreams("FlateDecode", deflate(t, textPage("Compressed but small")))
+
+	tests := []struct {
+		name     string
+		document []byte
+		limits   Limits
+		wantErr  bool
+	}{
+		{"no limits", threePages, Limits{}, false},
+		{"pages within limit", threePages, Limits{MaxPDFPages: 3}, false},
+		{"too many pages", threePages, Limits{MaxPDFPages: 2}, true},
+		// Three pages take nine objects, so the trailer Size is 10
+		{"objects within limit", threePages, Limits{MaxPDFObjects: 10}, false},
+		{"too many objects", threePages, Limits{MaxPDFObjects: 5}, true},
+		{"decompression bomb", bomb, Limits{MaxPDFRatio: 100}, true},
+		{"bomb without a ratio limit", bomb, Limits{}, false},
+		{"small streams always fit the budget", compressed, Limits{MaxPDFRatio: 1}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient()
+			client.SetLimits(tt.limits)
+			_, _, _, err := client.extractFromPDF(bytes.NewReader(tt.document))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("extractFromPDF() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrUnsafeDocument) {
+				t.Errorf("error %v is not ErrUnsafeDocument", err)
+			}
+		})
+	}
+}
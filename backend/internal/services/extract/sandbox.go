@@ -0,0 +1,192 @@
+package extract
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WorkerCommand is the argument that starts the binary as an extraction
+// worker process instead of the API server
+const WorkerCommand = "extract-worker"
+
+// Exit codes of the worker process
+const (
+	workerExitError  = 1
+	workerExitUnsafe = 3
+)
+
+// extractIsolated runs extraction in a child process of the current
+// binary, which is killed when it exceeds the timeout or ctx is cancelled
+// and whose address space is capped at the memory limit
+func (c *Client) extractIsolated(ctx context.Context, file *os.File, filename string) (*ExtractionResult, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate extraction worker: %w", err)
+	}
+
+	args := []string{WorkerCommand,
+		"-file", file.Name(),
+		"-name", filename,
+		"-max-pages", strconv.Itoa(c.limits.MaxPDFPages),
+		"-max-objects", strconv.FormatInt(c.limits.MaxPDFObjects, 10),
+		"-max-ratio", strconv.FormatFloat(c.limits.MaxPDFRatio, 'f', -1, 64),
+		"-memory-limit", strconv.FormatInt(c.limits.MemoryLimit, 10),
+		"-max-text", strconv.FormatInt(c.limits.MaxTextBytes, 10),
+		"-max-part", strconv.FormatInt(c.limits.MaxZipPartSize, 10),
+		"-max-part-ratio", strconv.FormatFloat(c.limits.MaxZipPartRatio, 'f', -1, 64),
+	}
+	// The worker recreates Tesseract OCR from its language
+	if tesseract, ok := c.ocr.(*TesseractOCR); ok {
+		args = append(args, "-ocr-language", tesseract.language)
+	}
+
+	workerCtx := ctx
+	if c.limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		workerCtx, cancel = context.WithTimeout(ctx, c.limits.Timeout)
+		defer cancel()
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(workerCtx, executable, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// OCR tools started by the worker must not outlive it
+	cmd.WaitDelay = 5 * time.Second
+
+	err = cmd.Run()
+	// The caller gave up, so the worker was killed rather than timed out
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if errors.Is(workerCtx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("%w: extraction took longer than %s", ErrExtractionTimeout, c.limits.Timeout)
+	}
+	if err != nil {
+		message := workerMessage(stderr.String())
+		var exitErr *exec.ExitError
+		switch {
+		case outOfMemory(stderr.String()):
+			return nil, fmt.Errorf("%w: extraction exceeded the %dMB memory limit", ErrUnsafeDocument, c.limits.MemoryLimit/(1024*1024))
+		case errors.As(err, &exitErr) && exitErr.ExitCode() == workerExitUnsafe:
+			return nil, unsafeDocumentError(message)
+		case message != "":
+			return nil, errors.New(message)
+		default:
+			return nil, fmt.Errorf("extraction worker failed: %w", err)
+		}
+	}
+
+	var result ExtractionResult
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		return nil, fmt.Errorf("failed to read extraction result: %w", err)
+	}
+	return &result, nil
+}
+
+// RunWorker is the entry point of the extraction worker process. It
+// extracts one file and writes the result to stdout as JSON, returning the
+// process exit code.
+func RunWorker(args []string) int {
+	flags := flag.NewFlagSet(WorkerCommand, flag.ContinueOnError)
+	path := flags.String("file", "", "file to extract")
+	name := flags.String("name", "", "original file name")
+	maxPages := flags.Int("max-pages", 0, "maximum PDF pages")
+	maxObjects := flags.Int64("max-objects", 0, "maximum PDF objects")
+	maxRatio := flags.Float64("max-ratio", 0, "maximum PDF stream decompression ratio")
+	memoryLimit := flags.Int64("memory-limit", 0, "address space limit in bytes")
+	maxText := flags.Int64("max-text", 0, "maximum plain text size in bytes")
+	maxPart := flags.Int64("max-part", 0, "maximum decompressed size of a DOCX or PPTX part")
+	maxPartRatio := flags.Float64("max-part-ratio", 0, "maximum compression ratio of a DOCX or PPTX part")
+	ocrLanguage := flags.String("ocr-language", "", "Tesseract language; OCR is disabled when empty")
+	if err := flags.Parse(args); err != nil {
+		return workerExitError
+	}
+
+	if *memoryLimit > 0 {
+		// The soft limit makes the garbage collector work harder before the
+		// hard limit is hit
+		debug.SetMemoryLimit(*memoryLimit / 2)
+		if err := limitAddressSpace(*memoryLimit); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to set memory limit: %v\n", err)
+			return workerExitError
+		}
+	}
+
+	client := NewClient()
+	client.limits = Limits{
+		MaxPDFPages:   *maxPages,
+		MaxPDFObjects: *maxObjects,
+		MaxPDFRatio:   *maxRatio,
+		MaxTextBytes:  *maxText,
+
+		MaxZipPartSize:  *maxPart,
+		MaxZipPartRatio: *maxPartRatio,
+	}
+	if *ocrLanguage != "" {
+		client.SetOCR(NewTesseractOCR(*ocrLanguage))
+	}
+
+	file, err := os.Open(*path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return workerExitError
+	}
+	defer file.Close()
+
+	if *name == "" {
+		*name = filepath.Base(*path)
+	}
+	result, err := client.extractText(file, *name)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if errors.Is(err, ErrUnsafeDocument) {
+			return workerExitUnsafe
+		}
+		return workerExitError
+	}
+
+	if err := json.NewEncoder(os.Stdout).Encode(result); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return workerExitError
+	}
+	return 0
+}
+
+// unsafeDocumentError carries a worker's message for an unsafe document
+type unsafeDocumentError string
+
+func (e unsafeDocumentError) Error() string { return string(e) }
+
+func (e unsafeDocumentError) Unwrap() error { return ErrUnsafeDocument }
+
+// workerMessage returns the error a worker reported: the runtime's message
+// if it crashed, otherwise its last line of output
+func workerMessage(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	for _, line := range lines {
+		if strings.HasPrefix(line, "panic: ") || strings.HasPrefix(line, "fatal error: ") {
+			return strings.TrimSpace(line)
+		}
+	}
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// outOfMemory reports whether a worker crashed on hitting its memory limit.
+// The runtime reports failed allocations in several ways.
+func outOfMemory(output string) bool {
+	return strings.Contains(output, "out of memory") ||
+		strings.Contains(output, "cannot allocate memory") ||
+		strings.Contains(output, "errno=12") // ENOMEM creating a thread
+}
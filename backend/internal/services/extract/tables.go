@@ -0,0 +1,315 @@
+package extract
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// ChunkTypeTable marks sections (and the chunks cut from them) that hold a
+// table serialised as Markdown
+const ChunkTypeTable = "table"
+
+// Table detection thresholds. Gaps are measured in multiples of the font size.
+const (
+	minTableRows       = 3
+	maxTableCellLength = 40 // mean runes per cell; longer cells are prose columns
+	wordGapFactor      = 0.15
+	cellGapFactor      = 1.2
+	defaultFontSize    = 10
+)
+
+// layoutCell is a run of text on a line, separated from its neighbours by
+// a gap wide enough to be a column gutter
+type layoutCell struct {
+	X    float64
+	End  float64
+	Text string
+}
+
+// layoutLine is a line of text on a PDF page
+type layoutLine struct {
+	Y     float64
+	Cells []layoutCell
+}
+
+// Text joins the line's cells as plain text
+func (l layoutLine) Text() string {
+	parts := make([]string, len(l.Cells))
+	for i, cell := range l.Cells {
+		parts[i] = cell.Text
+	}
+	return strings.Join(parts, " ")
+}
+
+// pageSegment is a part of a page: either running text or a table
+type pageSegment struct {
+	Text  string
+	Table [][]string
+}
+
+// pageSegments splits a PDF page into text and table segments in reading
+// order. It returns nil when the page has no tables, so the page's plain
+// text can be used as is.
+func pageSegments(page pdf.Page) []pageSegment {
+	lines := pageLines(pageContent(page))
+
+	var segments []pageSegment
+	var text []string
+	flushText := func() {
+		if len(text) > 0 {
+			segments = append(segments, pageSegment{Text: strings.Join(text, "\n")})
+			text = nil
+		}
+	}
+
+	found := false
+	for i := 0; i < len(lines); {
+		// A candidate table is a run of lines that each have several cells
+		end := i
+		for end < len(lines) && len(lines[end].Cells) >= 2 {
+			end++
+		}
+		if end-i >= minTableRows {
+			if table := buildTable(lines[i:end]); table != nil {
+				flushText()
+				segments = append(segments, pageSegment{Table: table})
+				found = true
+				i = end
+				continue
+			}
+		}
+		if end == i {
+			end = i + 1
+		}
+		for _, line := range lines[i:end] {
+			text = append(text, line.Text())
+		}
+		i = end
+	}
+	flushText()
+
+	if !found {
+		return nil
+	}
+	return segments
+}
+
+// pageContent returns the positioned glyphs of a page. The pdf library
+// panics on malformed content streams; such pages yield no glyphs.
+func pageContent(page pdf.Page) (content pdf.Content) {
+	defer func() {
+		if r := recover(); r != nil {
+			content = pdf.Content{}
+		}
+	}()
+	return page.Content()
+}
+
+// pageLines groups glyphs into lines from the top of the page down, and
+// each line's glyphs into cells
+func pageLines(content pdf.Content) []layoutLine {
+	glyphs := make([]pdf.Text, 0, len(content.Text))
+	for _, g := range content.Text {
+		if g.S != "" {
+			if g.FontSize <= 0 {
+				g.FontSize = defaultFontSize
+			}
+			glyphs = append(glyphs, g)
+		}
+	}
+	sort.SliceStable(glyphs, func(i, j int) bool { return glyphs[i].Y > glyphs[j].Y })
+
+	var lines []layoutLine
+	for start := 0; start < len(glyphs); {
+		// Glyphs within half a font size of the first share its line, which
+		// keeps sub- and superscripts with their baseline
+		end := start + 1
+		for end < len(glyphs) && glyphs[start].Y-glyphs[end].Y <= glyphs[start].FontSize/2 {
+			end++
+		}
+		line := glyphs[start:end]
+		sort.SliceStable(line, func(i, j int) bool { return line[i].X < line[j].X })
+
+		if cells := lineCells(line); len(cells) > 0 {
+			lines = append(lines, layoutLine{Y: glyphs[start].Y, Cells: cells})
+		}
+		start = end
+	}
+	return lines
+}
+
+// lineCells joins a line's glyphs into words and words into cells. Spaces
+// are inferred from gaps, since many PDFs position words instead of
+// drawing space glyphs.
+func lineCells(glyphs []pdf.Text) []layoutCell {
+	var cells []layoutCell
+	var current *layoutCell
+	var text strings.Builder
+	var prev *pdf.Text
+	pendingSpace := false
+
+	closeCell := func() {
+		if current != nil {
+			current.Text = strings.TrimSpace(text.String())
+			if current.Text != "" {
+				cells = append(cells, *current)
+			}
+			current = nil
+			text.Reset()
+		}
+	}
+
+	for i := range glyphs {
+		g := &glyphs[i]
+		if strings.TrimFunc(g.S, unicode.IsSpace) == "" {
+			pendingSpace = true
+			continue
+		}
+		// Bold text is sometimes drawn by printing each glyph twice. Fonts
+		// without a widths table don't advance, so this only applies when
+		// glyph widths are known.
+		if prev != nil && g.W > 0 && g.S == prev.S && g.X-prev.X < 0.5 {
+			continue
+		}
+
+		if prev != nil && current != nil {
+			gap := g.X - current.End
+			switch {
+			case gap > g.FontSize*cellGapFactor:
+				closeCell()
+			case pendingSpace || gap > g.FontSize*wordGapFactor:
+				text.WriteString(" ")
+			}
+		}
+		if current == nil {
+			current = &layoutCell{X: g.X}
+		}
+		text.WriteString(g.S)
+		switch {
+		case g.W > 0:
+			current.End = math.Max(current.End, g.X+g.W)
+		case g.X < current.End:
+			// No advance was reported; estimate an average glyph width
+			current.End += g.FontSize / 2
+		default:
+			current.End = g.X + g.FontSize/2
+		}
+		prev = g
+		pendingSpace = false
+	}
+	closeCell()
+
+	return cells
+}
+
+// buildTable lays a run of multi-cell lines out as a table, or returns nil
+// when they don't look like one. Columns are the gutters left by merging
+// the horizontal extents of every cell, which copes with left, right and
+// centre aligned columns alike.
+func buildTable(lines []layoutLine) [][]string {
+	var extents [][2]float64
+	cellCount, textLength := 0, 0
+	for _, line := range lines {
+		for _, cell := range line.Cells {
+			extents = append(extents, [2]float64{cell.X, cell.End})
+			cellCount++
+			textLength += utf8.RuneCountInString(cell.Text)
+		}
+	}
+	// Two columns of running text also line up; their cells are long
+	if textLength/cellCount > maxTableCellLength {
+		return nil
+	}
+
+	sort.Slice(extents, func(i, j int) bool { return extents[i][0] < extents[j][0] })
+	var columns [][2]float64
+	for _, extent := range extents {
+		if n := len(columns); n > 0 && extent[0] <= columns[n-1][1] {
+			if extent[1] > columns[n-1][1] {
+				columns[n-1][1] = extent[1]
+			}
+			continue
+		}
+		columns = append(columns, extent)
+	}
+	if len(columns) < 2 {
+		return nil
+	}
+
+	rows := make([][]string, 0, len(lines))
+	for _, line := range lines {
+		row := make([]string, len(columns))
+		for _, cell := range line.Cells {
+			column := 0
+			for column < len(columns)-1 && cell.X > columns[column][1] {
+				column++
+			}
+			if row[column] != "" {
+				row[column] += " "
+			}
+			row[column] += cell.Text
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// markdownTable serialises rows as a Markdown table with the first row as
+// its header
+func markdownTable(rows [][]string) string {
+	columns := 0
+	for _, row := range rows {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+
+	var b strings.Builder
+	writeRow := func(row []string) {
+		b.WriteString("|")
+		for i := 0; i < columns; i++ {
+			cell := ""
+			if i < len(row) {
+				cell = strings.Join(strings.Fields(row[i]), " ")
+				cell = strings.ReplaceAll(cell, "|", `\|`)
+			}
+			b.WriteString(" ")
+			b.WriteString(cell)
+			b.WriteString(" |")
+		}
+		b.WriteString("\n")
+	}
+
+	for i, row := range rows {
+		writeRow(row)
+		if i == 0 {
+			b.WriteString("|")
+			b.WriteString(strings.Repeat(" --- |", columns))
+			b.WriteString("\n")
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// tableMetadata returns a table section's metadata, extending base
+func tableMetadata(base map[string]interface{}, rows [][]string) map[string]interface{} {
+	metadata := make(map[string]interface{}, len(base)+3)
+	for k, v := range base {
+		metadata[k] = v
+	}
+	columns := 0
+	for _, row := range rows {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+	metadata["chunk_type"] = ChunkTypeTable
+	metadata["table_rows"] = len(rows)
+	metadata["table_columns"] = columns
+	return metadata
+}
@@ -0,0 +1,112 @@
+package extract
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMarkdownTable(t *testing.T) {
+	tests := []struct {
+		name string
+		rows [][]string
+		want string
+	}{
+		{
+			"header and rows",
+			[][]string{{"Course", "Credits"}, {"CSC 201", "3"}, {"MTH 101", "4"}},
+			"| Course | Credits |\n| --- | --- |\n| CSC 201 | 3 |\n| MTH 101 | 4 |",
+		},
+		{
+			"ragged rows are padded",
+			[][]string{{"A", "B", "C"}, {"1"}},
+			"| A | B | C |\n| --- | --- | --- |\n| 1 |  |  |",
+		},
+		{
+			"pipes are escaped and whitespace collapsed",
+			[][]string{{"Expr"}, {"a | b"}, {"line\none  two"}},
+			"| Expr |\n| --- |\n| a \\| b |\n| line one two |",
+		},
+		{
+			"header only",
+			[][]string{{"Only"}},
+			"| Only |\n| --- |",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := markdownTable(tt.rows); got != tt.want {
+				t.Errorf("markdownTable() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTableMetadata(t *testing.T) {
+	base := map[string]interface{}{"page": 3}
+	got := tableMetadata(base, [][]string{{"A", "B"}, {"1", "2", "3"}})
+
+	want := map[string]interface{}{
+		"page":          3,
+		"chunk_type":    ChunkTypeTable,
+		"table_rows":    2,
+		"table_columns": 3,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tableMetadata() = %v, want %v", got, want)
+	}
+	if _, ok := base["chunk_type"]; ok {
+		t.Error("tableMetadata() modified its base")
+	}
+}
+
+func TestBuildTable(t *testing.T) {
+	cell := func(x, end float64, text string) layoutCell {
+		return layoutCell{X: x, End: end, Text: text}
+	}
+
+	t.Run("aligned columns", func(t *testing.T) {
+		lines := []layoutLine{
+			{Cells: []layoutCell{cell(10, 60, "Course"), cell(100, 140, "Credits")}},
+			{Cells: []layoutCell{cell(10, 55, "CSC 201"), cell(110, 120, "3")}},
+			{Cells: []layoutCell{cell(10, 58, "MTH 101"), cell(110, 120, "4")}},
+		}
+		want := [][]string{{"Course", "Credits"}, {"CSC 201", "3"}, {"MTH 101", "4"}}
+		if got := buildTable(lines); !reflect.DeepEqual(got, want) {
+			t.Errorf("buildTable() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("missing cell", func(t *testing.T) {
+		lines := []layoutLine{
+			{Cells: []layoutCell{cell(10, 40, "A"), cell(100, 130, "B"), cell(200, 230, "C")}},
+			{Cells: []layoutCell{cell(10, 40, "1"), cell(200, 230, "3")}},
+		}
+		want := [][]string{{"A", "B", "C"}, {"1", "", "3"}}
+		if got := buildTable(lines); !reflect.DeepEqual(got, want) {
+			t.Errorf("buildTable() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("prose columns are not a table", func(t *testing.T) {
+		long := "a long line of running text that fills the whole column width"
+		lines := []layoutLine{
+			{Cells: []layoutCell{cell(10, 250, long), cell(300, 550, long)}},
+			{Cells: []layoutCell{cell(10, 250, long), cell(300, 550, long)}},
+			{Cells: []layoutCell{cell(10, 250, long), cell(300, 550, long)}},
+		}
+		if got := buildTable(lines); got != nil {
+			t.Errorf("buildTable() = %q, want nil", got)
+		}
+	})
+
+	t.Run("overlapping cells make one column", func(t *testing.T) {
+		lines := []layoutLine{
+			{Cells: []layoutCell{cell(10, 100, "wide")}},
+			{Cells: []layoutCell{cell(50, 150, "shifted")}},
+		}
+		if got := buildTable(lines); got != nil {
+			t.Errorf("buildTable() = %q, want nil", got)
+		}
+	})
+}
@@ -0,0 +1,271 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kinyichukwu/edu-pro-backend/internal/utils"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrInvalidURL is returned for URLs that are not absolute http(s) URLs
+	ErrInvalidURL = errors.New("invalid URL")
+	// ErrBlockedHost is returned when a URL resolves to a private, loopback
+	// or otherwise internal address
+	ErrBlockedHost = errors.New("host is not allowed")
+	// ErrTooLarge is returned when the content exceeds the size limit
+	ErrTooLarge = errors.New("content is too large")
+	// ErrUnsupportedType is returned for content types that can't be ingested
+	ErrUnsupportedType = errors.New("unsupported content type")
+)
+
+// maxRedirects is the number of redirects followed before giving up
+const maxRedirects = 5
+
+// contentTypeExtensions maps accepted content types to file extensions
+var contentTypeExtensions = map[string]string{
+	"application/pdf":       ".pdf",
+	"text/html":             ".html",
+	"application/xhtml+xml": ".html",
+	"text/plain":            ".txt",
+	"text/markdown":         ".txt",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document":   ".docx",
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": ".pptx",
+}
+
+// urlExtensions are trusted from the URL path when the server sends a
+// generic content type, as many do for PDFs
+var urlExtensions = map[string]bool{
+	".pdf":  true,
+	".docx": true,
+	".pptx": true,
+	".txt":  true,
+}
+
+// Client downloads documents from the web
+type Client struct {
+	httpClient *http.Client
+	userAgent  string
+}
+
+// NewClient creates a fetch client. Unless allowPrivate is set, connections
+// to private and loopback addresses are refused so imports can't reach
+// internal services.
+func NewClient(timeout time.Duration, allowPrivate bool) *Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if !allowPrivate {
+		// Checked at connect time, after DNS resolution, so redirects and
+		// DNS rebinding can't reach internal addresses either
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isInternalIP(ip) {
+				return fmt.Errorf("%w: %s", ErrBlockedHost, host)
+			}
+			return nil
+		}
+	}
+
+	transport := &http.Transport{
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+
+	return &Client{
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   timeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= maxRedirects {
+					return fmt.Errorf("stopped after %d redirects", maxRedirects)
+				}
+				if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+					return fmt.Errorf("%w: redirect to %s", ErrInvalidURL, req.URL.Scheme)
+				}
+				return nil
+			},
+		},
+		userAgent: "EduPro-Importer/1.0",
+	}
+}
+
+// Fetch downloads a URL to a temporary file, enforcing the size limit while
+// streaming. The caller must Close the result.
+func (c *Client) Fetch(ctx context.Context, rawURL string, maxSize int64) (*Result, error) {
+	logger := utils.GetLogger()
+
+	target, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, ErrInvalidURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, ErrInvalidURL
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("Accept", "application/pdf, text/html;q=0.9, text/plain;q=0.8, */*;q=0.5")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrBlockedHost) || errors.Is(err, ErrInvalidURL) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to fetch URL: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch URL: HTTP %d", resp.StatusCode)
+	}
+
+	ext, contentType := fileType(resp.Header.Get("Content-Type"), resp.Request.URL)
+	if ext == "" {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, contentType)
+	}
+
+	if maxSize > 0 && resp.ContentLength > maxSize {
+		return nil, ErrTooLarge
+	}
+
+	file, err := os.CreateTemp("", "edupro-import-*"+ext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	result := &Result{
+		File:        file,
+		Filename:    filename(resp, ext),
+		ContentType: contentType,
+		FinalURL:    resp.Request.URL.String(),
+	}
+
+	// Servers may omit or understate Content-Length, so the limit is
+	// enforced on the bytes actually received
+	body := io.Reader(resp.Body)
+	if maxSize > 0 {
+		body = io.LimitReader(resp.Body, maxSize+1)
+	}
+	result.Size, err = io.Copy(file, body)
+	if err != nil {
+		result.Close()
+		return nil, fmt.Errorf("failed to download URL: %w", err)
+	}
+	if maxSize > 0 && result.Size > maxSize {
+		result.Close()
+		return nil, ErrTooLarge
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		result.Close()
+		return nil, fmt.Errorf("failed to read downloaded file: %w", err)
+	}
+
+	logger.Info("URL fetched",
+		zap.String("url", result.FinalURL),
+		zap.String("content_type", contentType),
+		zap.Int64("size", result.Size),
+	)
+
+	return result, nil
+}
+
+// fileType picks the file extension for a response from its content type,
+// falling back to the URL's extension for generic binary types
+func fileType(header string, u *url.URL) (string, string) {
+	contentType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		contentType = ""
+	}
+	contentType = strings.ToLower(contentType)
+
+	if ext, ok := contentTypeExtensions[contentType]; ok {
+		return ext, contentType
+	}
+	if contentType == "" || contentType == "application/octet-stream" || contentType == "binary/octet-stream" {
+		if ext := strings.ToLower(path.Ext(u.Path)); urlExtensions[ext] {
+			return ext, contentType
+		}
+	}
+	return "", contentType
+}
+
+// filename derives a file name from Content-Disposition or the URL, with the
+// extension of the detected file type
+func filename(resp *http.Response, ext string) string {
+	var name string
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		name = params["filename"]
+	}
+	if name == "" {
+		if base, err := url.PathUnescape(path.Base(resp.Request.URL.Path)); err == nil && base != "/" && base != "." {
+			name = base
+		}
+	}
+	if name == "" {
+		name = resp.Request.URL.Hostname()
+	}
+
+	// Keep the name usable as a storage key
+	name = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r < 0x20 {
+			return '_'
+		}
+		return r
+	}, filepath.Base(name))
+	if len(name) > 200 {
+		name = name[:200]
+	}
+
+	current := strings.ToLower(filepath.Ext(name))
+	if current != ext && !(ext == ".html" && current == ".htm") {
+		name += ext
+	}
+	return name
+}
+
+// isInternalIP reports whether an address is loopback, private, link-local
+// or otherwise not publicly routable
+func isInternalIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+	// Carrier-grade NAT range, used by some cloud metadata services
+	if ip4 := ip.To4(); ip4 != nil && ip4[0] == 100 && ip4[1]&0xc0 == 64 {
+		return true
+	}
+	return false
+}
+
+// Result is a downloaded document
+type Result struct {
+	File        *os.File
+	Filename    string
+	ContentType string
+	Size        int64
+	FinalURL    string // URL after redirects
+}
+
+// Close closes and removes the downloaded file
+func (r *Result) Close() {
+	r.File.Close()
+	os.Remove(r.File.Name())
+}
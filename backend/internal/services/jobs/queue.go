@@ -0,0 +1,324 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/kinyichukwu/edu-pro-backend/internal/utils"
+	"go.uber.org/zap"
+)
+
+// Job kinds
+const (
+	KindProcessDocument = "process_document"
+	KindReembedDocument = "reembed_document" // Re-embed a processed document with the active model
+)
+
+// Job statuses
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusDead      = "dead"
+)
+
+// ErrLeaseLost is returned when a job's lease expired and it was re-claimed
+// or requeued, so the worker no longer owns it and must not record an outcome
+var ErrLeaseLost = errors.New("job lease lost")
+
+// Client represents the Postgres-backed ingestion job queue
+type Client struct {
+	pool        *pgxpool.Pool
+	maxAttempts int
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+}
+
+// NewClient creates a new job queue client
+func NewClient(pool *pgxpool.Pool, maxAttempts int) *Client {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	return &Client{
+		pool:        pool,
+		maxAttempts: maxAttempts,
+		baseBackoff: 30 * time.Second,
+		maxBackoff:  30 * time.Minute,
+	}
+}
+
+// Enqueue adds a new job for a document to the queue. A document has at most
+// one queued or running job of each kind; if there already is one, its id is
+// returned instead and no job is added.
+func (c *Client) Enqueue(ctx context.Context, kind, documentID string, payload interface{}) (string, error) {
+	logger := utils.GetLogger()
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal job payload: %w", err)
+	}
+
+	var jobID string
+	err = c.pool.QueryRow(ctx, `
+		WITH inserted AS (
+			INSERT INTO ingestion_jobs (kind, document_id, payload, max_attempts)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (document_id, kind) WHERE status IN ('queued', 'running') DO NOTHING
+			RETURNING id
+		)
+		SELECT id FROM inserted
+		UNION ALL
+		SELECT id FROM ingestion_jobs
+		WHERE document_id = $2 AND kind = $1 AND status IN ('queued', 'running')
+		LIMIT 1
+	`, kind, documentID, payloadJSON, c.maxAttempts).Scan(&jobID)
+	if err != nil {
+		logger.Error("Failed to enqueue job",
+			zap.Error(err),
+			zap.String("kind", kind),
+			zap.String("document_id", documentID),
+		)
+		return "", fmt.Errorf("failed to enqueue job: %w", err)
+	}
+
+	logger.Info("Job enqueued",
+		zap.String("job_id", jobID),
+		zap.String("kind", kind),
+		zap.String("document_id", documentID),
+	)
+
+	return jobID, nil
+}
+
+// Claim locks the next runnable job for the given worker. It returns nil when
+// the queue is empty. Concurrent workers never receive the same job because
+// the candidate row is selected with FOR UPDATE SKIP LOCKED.
+func (c *Client) Claim(ctx context.Context, workerID string) (*Job, error) {
+	job := &Job{}
+	err := c.pool.QueryRow(ctx, `
+		UPDATE ingestion_jobs
+		SET status = 'running',
+			attempts = attempts + 1,
+			locked_by = $1,
+			locked_at = NOW(),
+			updated_at = NOW()
+		WHERE id = (
+			SELECT id
+			FROM ingestion_jobs
+			WHERE status = 'queued' AND run_at <= NOW() AND attempts < max_attempts
+			ORDER BY run_at
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING id, kind, document_id, payload, attempts, max_attempts
+	`, workerID).Scan(&job.ID, &job.Kind, &job.DocumentID, &job.Payload, &job.Attempts, &job.MaxAttempts)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim job: %w", err)
+	}
+	job.workerID = workerID
+
+	return job, nil
+}
+
+// Heartbeat extends the lease on a running job. It returns ErrLeaseLost if
+// the worker no longer holds the job.
+func (c *Client) Heartbeat(ctx context.Context, job *Job) error {
+	tag, err := c.pool.Exec(ctx, `
+		UPDATE ingestion_jobs
+		SET locked_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND locked_by = $2 AND status = 'running'
+	`, job.ID, job.workerID)
+	if err != nil {
+		return fmt.Errorf("failed to extend job lease: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// Complete marks a job as completed. It returns ErrLeaseLost if the worker
+// no longer holds the job.
+func (c *Client) Complete(ctx context.Context, job *Job) error {
+	tag, err := c.pool.Exec(ctx, `
+		UPDATE ingestion_jobs
+		SET status = 'completed', last_error = NULL, locked_by = NULL, locked_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND locked_by = $2 AND status = 'running'
+	`, job.ID, job.workerID)
+	if err != nil {
+		return fmt.Errorf("failed to complete job: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// Release puts a running job back in the queue without counting the attempt,
+// for jobs aborted by shutdown rather than by a failure of their own. The
+// document of a processing job goes back to 'queued' with it.
+func (c *Client) Release(ctx context.Context, job *Job) error {
+	var released int
+	err := c.pool.QueryRow(ctx, `
+		WITH released AS (
+			UPDATE ingestion_jobs
+			SET status = 'queued', attempts = GREATEST(attempts - 1, 0), run_at = NOW(),
+				locked_by = NULL, locked_at = NULL, updated_at = NOW()
+			WHERE id = $1 AND locked_by = $2 AND status = 'running'
+			RETURNING document_id, kind
+		), documents_updated AS (
+			UPDATE documents
+			SET processing_status = 'queued'
+			WHERE id IN (SELECT document_id FROM released WHERE kind = $3)
+		)
+		SELECT count(*) FROM released
+	`, job.ID, job.workerID, KindProcessDocument).Scan(&released)
+	if err != nil {
+		return fmt.Errorf("failed to release job: %w", err)
+	}
+	if released == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// Fail records a failed attempt. The job is rescheduled with exponential
+// backoff until it runs out of attempts, after which it is dead-lettered.
+// It reports whether the job was dead-lettered.
+func (c *Client) Fail(ctx context.Context, job *Job, jobErr error) (bool, error) {
+	logger := utils.GetLogger()
+
+	dead := job.Attempts >= job.MaxAttempts || IsPermanent(jobErr)
+	status := StatusQueued
+	runAt := time.Now().Add(c.backoff(job.Attempts))
+	if dead {
+		status = StatusDead
+		runAt = time.Now()
+	}
+
+	tag, err := c.pool.Exec(ctx, `
+		UPDATE ingestion_jobs
+		SET status = $2, last_error = $3, run_at = $4, locked_by = NULL, locked_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND locked_by = $5 AND status = 'running'
+	`, job.ID, status, jobErr.Error(), runAt, job.workerID)
+	if err != nil {
+		return dead, fmt.Errorf("failed to record job failure: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, ErrLeaseLost
+	}
+
+	if dead {
+		logger.Error("Job dead-lettered",
+			zap.String("job_id", job.ID),
+			zap.String("document_id", job.DocumentID),
+			zap.Int("attempts", job.Attempts),
+			zap.Error(jobErr),
+		)
+	} else {
+		logger.Warn("Job failed, retry scheduled",
+			zap.String("job_id", job.ID),
+			zap.String("document_id", job.DocumentID),
+			zap.Int("attempts", job.Attempts),
+			zap.Time("run_at", runAt),
+			zap.Error(jobErr),
+		)
+	}
+
+	return dead, nil
+}
+
+// RecoverStale requeues running jobs whose lease has expired, e.g. because the
+// instance that claimed them crashed or was restarted mid-job. A job that has
+// used all its attempts is dead-lettered instead, so a document that crashes
+// the process every time isn't retried forever. The documents being
+// processed by them are moved back to 'queued', or to 'failed', in the same
+// statement. It returns the number of jobs recovered or dead-lettered.
+func (c *Client) RecoverStale(ctx context.Context, lease time.Duration) (int64, error) {
+	var recovered int64
+	err := c.pool.QueryRow(ctx, `
+		WITH stale AS (
+			UPDATE ingestion_jobs
+			SET status = CASE WHEN attempts >= max_attempts THEN 'dead' ELSE 'queued' END,
+				last_error = CASE WHEN attempts >= max_attempts
+					THEN 'lease expired on the last attempt; the worker may have crashed'
+					ELSE last_error END,
+				locked_by = NULL, locked_at = NULL, run_at = NOW(), updated_at = NOW()
+			WHERE status = 'running' AND locked_at < NOW() - make_interval(secs => $1)
+			RETURNING document_id, kind, status
+		), documents_updated AS (
+			UPDATE documents d
+			SET processing_status = CASE WHEN s.status = 'dead' THEN 'failed' ELSE 'queued' END,
+				error = CASE WHEN s.status = 'dead' THEN 'Processing did not finish after repeated attempts' ELSE d.error END
+			FROM stale s
+			WHERE d.id = s.document_id AND s.kind = $2
+		)
+		SELECT count(*) FROM stale
+	`, lease.Seconds(), KindProcessDocument).Scan(&recovered)
+	if err != nil {
+		return 0, fmt.Errorf("failed to recover stale jobs: %w", err)
+	}
+	return recovered, nil
+}
+
+// backoff returns the delay before the next attempt, with jitter
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.baseBackoff
+	for i := 1; i < attempt && delay < c.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > c.maxBackoff {
+		delay = c.maxBackoff
+	}
+
+	// Add up to 20% jitter so retries from a burst of failures spread out
+	jitter := time.Duration(rand.Int63n(int64(delay) / 5))
+	return delay + jitter
+}
+
+// Job represents a claimed ingestion job
+type Job struct {
+	ID          string          `json:"id"`
+	Kind        string          `json:"kind"`
+	DocumentID  string          `json:"document_id"`
+	Payload     json.RawMessage `json:"payload"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+
+	workerID string // worker holding the lease
+}
+
+// IsLastAttempt reports whether a failure of this attempt will dead-letter the job
+func (j *Job) IsLastAttempt() bool {
+	return j.Attempts >= j.MaxAttempts
+}
+
+// permanentError marks a failure that retrying can't fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps a job error so the job is dead-lettered without retries
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether a job error was marked with Permanent
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
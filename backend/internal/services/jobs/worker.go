@@ -0,0 +1,240 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kinyichukwu/edu-pro-backend/internal/utils"
+	"go.uber.org/zap"
+)
+
+// HandlerFunc processes a single claimed job
+type HandlerFunc func(ctx context.Context, job *Job) error
+
+// WorkerPool runs a fixed number of workers that claim and process jobs
+type WorkerPool struct {
+	client       *Client
+	handler      HandlerFunc
+	concurrency  int
+	pollInterval time.Duration
+	lease        time.Duration
+	instanceID   string
+	// stopGrace is how long Stop waits for in-flight jobs before aborting them
+	stopGrace time.Duration
+
+	// cancel stops claiming new jobs; cancelJobs aborts the ones running.
+	// They are separate so shutdown can let in-flight jobs finish.
+	cancel     context.CancelFunc
+	cancelJobs context.CancelFunc
+	wg         sync.WaitGroup
+}
+
+// NewWorkerPool creates a new worker pool
+func NewWorkerPool(client *Client, handler HandlerFunc, concurrency int) *WorkerPool {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	return &WorkerPool{
+		client:       client,
+		handler:      handler,
+		concurrency:  concurrency,
+		pollInterval: 2 * time.Second,
+		lease:        5 * time.Minute,
+		stopGrace:    25 * time.Second,
+		instanceID:   fmt.Sprintf("%s-%s", hostname, uuid.New().String()[:8]),
+	}
+}
+
+// Start launches the workers and the stale-job reaper
+func (p *WorkerPool) Start() {
+	logger := utils.GetLogger()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	jobCtx, cancelJobs := context.WithCancel(context.Background())
+	p.cancelJobs = cancelJobs
+
+	for i := 0; i < p.concurrency; i++ {
+		workerID := fmt.Sprintf("%s/%d", p.instanceID, i)
+		p.wg.Add(1)
+		go p.runWorker(ctx, jobCtx, workerID)
+	}
+
+	p.wg.Add(1)
+	go p.runReaper(ctx)
+
+	logger.Info("Ingestion workers started",
+		zap.String("instance_id", p.instanceID),
+		zap.Int("concurrency", p.concurrency),
+	)
+}
+
+// Stop stops claiming jobs and gives in-flight jobs the grace period to
+// finish. Jobs still running after it are aborted and put back in the queue
+// without counting the attempt.
+func (p *WorkerPool) Stop() {
+	if p.cancel == nil {
+		return
+	}
+	p.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(p.stopGrace):
+		utils.GetLogger().Warn("Aborting in-flight jobs", zap.String("instance_id", p.instanceID))
+		p.cancelJobs()
+		<-done
+	}
+	p.cancelJobs()
+
+	utils.GetLogger().Info("Ingestion workers stopped", zap.String("instance_id", p.instanceID))
+}
+
+// runWorker claims jobs until ctx is cancelled, running them under jobCtx
+func (p *WorkerPool) runWorker(ctx, jobCtx context.Context, workerID string) {
+	defer p.wg.Done()
+	logger := utils.GetLogger()
+
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		job, err := p.client.Claim(ctx, workerID)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Error("Failed to claim job", zap.Error(err), zap.String("worker_id", workerID))
+			}
+		}
+
+		if job == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(p.pollInterval):
+			}
+			continue
+		}
+
+		p.runJob(jobCtx, workerID, job)
+	}
+}
+
+// runJob executes a single job while keeping its lease alive
+func (p *WorkerPool) runJob(ctx context.Context, workerID string, job *Job) {
+	logger := utils.GetLogger()
+
+	logger.Info("Job claimed",
+		zap.String("job_id", job.ID),
+		zap.String("kind", job.Kind),
+		zap.String("document_id", job.DocumentID),
+		zap.String("worker_id", workerID),
+		zap.Int("attempt", job.Attempts),
+	)
+
+	// The handler is cancelled if the lease is lost, as another worker may
+	// already be running the job
+	handlerCtx, cancelHandler := context.WithCancel(ctx)
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		p.heartbeat(handlerCtx, job, cancelHandler)
+	}()
+
+	err := p.handler(handlerCtx, job)
+	leaseLost := handlerCtx.Err() != nil && ctx.Err() == nil
+	cancelHandler()
+	<-heartbeatDone
+
+	// Record the outcome even if shutdown has started, so the job isn't left
+	// running until its lease expires
+	recordCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	switch {
+	case leaseLost:
+		logger.Warn("Job lease lost, abandoning job", zap.String("job_id", job.ID))
+		return
+	case err != nil && ctx.Err() != nil:
+		// Aborted by shutdown, not a failure of the job itself
+		if releaseErr := p.client.Release(recordCtx, job); releaseErr != nil {
+			logger.Error("Failed to release job", zap.Error(releaseErr), zap.String("job_id", job.ID))
+		} else {
+			logger.Info("Job released for another worker", zap.String("job_id", job.ID))
+		}
+		return
+	case err != nil:
+		if _, failErr := p.client.Fail(recordCtx, job, err); failErr != nil {
+			logger.Error("Failed to record job failure", zap.Error(failErr), zap.String("job_id", job.ID))
+		}
+		return
+	}
+
+	if err := p.client.Complete(recordCtx, job); err != nil {
+		logger.Error("Failed to complete job", zap.Error(err), zap.String("job_id", job.ID))
+	}
+}
+
+// heartbeat periodically extends the lease of a running job until ctx is
+// done, calling lost if the lease turns out to have expired
+func (p *WorkerPool) heartbeat(ctx context.Context, job *Job, lost context.CancelFunc) {
+	ticker := time.NewTicker(p.lease / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := p.client.Heartbeat(ctx, job)
+			if errors.Is(err, ErrLeaseLost) {
+				lost()
+				return
+			}
+			if err != nil && ctx.Err() == nil {
+				utils.GetLogger().Warn("Failed to extend job lease", zap.Error(err), zap.String("job_id", job.ID))
+			}
+		}
+	}
+}
+
+// runReaper periodically requeues jobs whose lease has expired
+func (p *WorkerPool) runReaper(ctx context.Context) {
+	defer p.wg.Done()
+	logger := utils.GetLogger()
+
+	ticker := time.NewTicker(p.lease / 2)
+	defer ticker.Stop()
+
+	for {
+		recovered, err := p.client.RecoverStale(ctx, p.lease)
+		if err != nil && ctx.Err() == nil {
+			logger.Error("Failed to recover stale jobs", zap.Error(err))
+		} else if recovered > 0 {
+			logger.Warn("Recovered stale jobs", zap.Int64("count", recovered))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package progress
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/kinyichukwu/edu-pro-backend/internal/utils"
+	"go.uber.org/zap"
+)
+
+// Stage is a step of document processing
+type Stage string
+
+const (
+	StageQueued      Stage = "queued"
+	StageProcessing  Stage = "processing" // snapshot of a document whose current step is unknown
+	StageDownloading Stage = "downloading"
+	StageExtracting  Stage = "extracting"
+	StageChunking    Stage = "chunking"
+	StageEmbedding   Stage = "embedding"
+	StageStoring     Stage = "storing"
+	StageCompleted   Stage = "completed"
+	StageFailed      Stage = "failed"
+)
+
+// Done reports whether no further events follow this stage
+func (s Stage) Done() bool {
+	return s == StageCompleted || s == StageFailed
+}
+
+// channel is the Postgres notification channel events are published on
+const channel = "document_progress"
+
+// maxErrorLength keeps notification payloads well under Postgres' 8000 byte limit
+const maxErrorLength = 1000
+
+// subscriberBuffer is the number of events held for a slow subscriber
+const subscriberBuffer = 32
+
+// Event is a processing stage transition of a document
+type Event struct {
+	DocumentID string    `json:"document_id"`
+	Stage      Stage     `json:"stage"`
+	Completed  int       `json:"completed,omitempty"` // embedding progress
+	Total      int       `json:"total,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Time       time.Time `json:"time"`
+}
+
+// Broker publishes processing events through Postgres LISTEN/NOTIFY, so
+// clients see progress from workers on any instance, and fans them out to
+// local subscribers
+type Broker struct {
+	pool *pgxpool.Pool
+
+	mu          sync.Mutex
+	subscribers map[string]map[chan Event]struct{}
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewBroker creates a broker. Call Start to receive events.
+func NewBroker(pool *pgxpool.Pool) *Broker {
+	return &Broker{
+		pool:        pool,
+		subscribers: make(map[string]map[chan Event]struct{}),
+	}
+}
+
+// Publish sends an event to subscribers on all instances. Failures are
+// logged; progress reporting never fails processing.
+func (b *Broker) Publish(ctx context.Context, event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	if len(event.Error) > maxErrorLength {
+		event.Error = event.Error[:maxErrorLength]
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	if _, err := b.pool.Exec(ctx, "SELECT pg_notify($1, $2)", channel, string(payload)); err != nil {
+		utils.GetLogger().Warn("Failed to publish progress event",
+			zap.String("document_id", event.DocumentID),
+			zap.String("stage", string(event.Stage)),
+			zap.Error(err),
+		)
+	}
+}
+
+// Subscribe returns a channel of events for a document and a function that
+// ends the subscription. The channel is closed when the broker stops.
+func (b *Broker) Subscribe(documentID string) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.subscribers[documentID] == nil {
+		b.subscribers[documentID] = make(map[chan Event]struct{})
+	}
+	b.subscribers[documentID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if subs, ok := b.subscribers[documentID]; ok {
+				if _, ok := subs[ch]; ok {
+					delete(subs, ch)
+					close(ch)
+				}
+				if len(subs) == 0 {
+					delete(b.subscribers, documentID)
+				}
+			}
+		})
+	}
+}
+
+// Start begins listening for events
+func (b *Broker) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	b.cancel = cancel
+
+	b.wg.Add(1)
+	go b.run(ctx)
+}
+
+// Stop stops listening and closes all subscriber channels
+func (b *Broker) Stop() {
+	if b.cancel == nil {
+		return
+	}
+	b.cancel()
+	b.wg.Wait()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for documentID, subs := range b.subscribers {
+		for ch := range subs {
+			close(ch)
+		}
+		delete(b.subscribers, documentID)
+	}
+}
+
+// run listens until stopped, reconnecting after connection failures
+func (b *Broker) run(ctx context.Context) {
+	defer b.wg.Done()
+	logger := utils.GetLogger()
+
+	backoff := time.Second
+	for {
+		err := b.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		logger.Warn("Progress listener disconnected, retrying",
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+// listen holds a dedicated connection on the notification channel and
+// dispatches events until the connection fails or ctx is cancelled
+func (b *Broker) listen(ctx context.Context) error {
+	pooled, err := b.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// The connection stays in LISTEN mode, so it must not go back to the pool
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+channel); err != nil {
+		return err
+	}
+	utils.GetLogger().Info("Progress listener started")
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var event Event
+		if err := json.Unmarshal([]byte(notification.Payload), &event); err != nil {
+			continue
+		}
+		b.dispatch(event)
+	}
+}
+
+// dispatch delivers an event to the document's subscribers. A subscriber that
+// falls behind loses its oldest event rather than blocking the listener.
+func (b *Broker) dispatch(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers[event.DocumentID] {
+		select {
+		case ch <- event:
+			continue
+		default:
+		}
+		select {
+		case <-ch:
+		default:
+		}
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package rerank
+
+import (
+	"context"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// bigramWeight is the share of the lexical score given to matching adjacent
+// query term pairs, which rewards exact phrases such as "CSC 201"
+const bigramWeight = 0.3
+
+// stopwords are ignored when matching, as they say nothing about relevance
+var stopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "can": true, "do": true, "does": true, "for": true,
+	"from": true, "how": true, "in": true, "is": true, "it": true, "of": true,
+	"on": true, "or": true, "the": true, "this": true, "to": true, "what": true,
+	"when": true, "which": true, "who": true, "why": true, "with": true,
+}
+
+// LexicalReranker scores passages by how many of the query's terms they
+// contain, weighting rare terms above ones most candidates share. It runs
+// locally and needs no model.
+type LexicalReranker struct{}
+
+// Name returns the reranker name
+func (LexicalReranker) Name() string {
+	return ProviderLexical
+}
+
+// Rerank scores each passage from 0 to 1 by IDF-weighted coverage of the
+// query's terms and adjacent term pairs
+func (LexicalReranker) Rerank(ctx context.Context, query string, passages []string) ([]float64, error) {
+	queryTerms := terms(query)
+	if len(queryTerms) == 0 || len(passages) == 0 {
+		return nil, nil
+	}
+
+	var queryBigrams []string
+	for i := 1; i < len(queryTerms); i++ {
+		queryBigrams = append(queryBigrams, queryTerms[i-1]+" "+queryTerms[i])
+	}
+
+	passageFeatures := make([]map[string]bool, len(passages))
+	for i, passage := range passages {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		passageFeatures[i] = features(terms(passage))
+	}
+
+	idf := func(feature string) float64 {
+		df := 0
+		for _, set := range passageFeatures {
+			if set[feature] {
+				df++
+			}
+		}
+		return math.Log(1 + float64(len(passages))/float64(df+1))
+	}
+
+	termWeights := make(map[string]float64)
+	for _, term := range queryTerms {
+		termWeights[term] = idf(term)
+	}
+	bigramWeights := make(map[string]float64)
+	for _, bigram := range queryBigrams {
+		bigramWeights[bigram] = idf(bigram)
+	}
+
+	scores := make([]float64, len(passages))
+	for i, set := range passageFeatures {
+		score := coverage(set, termWeights)
+		if len(bigramWeights) > 0 {
+			score = (1-bigramWeight)*score + bigramWeight*coverage(set, bigramWeights)
+		}
+		scores[i] = score
+	}
+
+	return scores, nil
+}
+
+// coverage returns the weighted share of features present in set
+func coverage(set map[string]bool, weights map[string]float64) float64 {
+	var matched, total float64
+	for feature, weight := range weights {
+		total += weight
+		if set[feature] {
+			matched += weight
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return matched / total
+}
+
+// terms lowercases text and splits it into words, dropping stopwords
+func terms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	kept := words[:0]
+	for _, word := range words {
+		if !stopwords[word] {
+			kept = append(kept, word)
+		}
+	}
+	return kept
+}
+
+// features returns the set of words and adjacent word pairs
+func features(words []string) map[string]bool {
+	set := make(map[string]bool, len(words)*2)
+	for i, word := range words {
+		set[word] = true
+		if i > 0 {
+			set[words[i-1]+" "+word] = true
+		}
+	}
+	return set
+}
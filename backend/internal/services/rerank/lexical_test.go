@@ -0,0 +1,104 @@
+package rerank
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestLexicalReranker(t *testing.T) {
+	passages := []string{
+		"The course CSC 201 covers data structures.",
+		"CSC students take 201 credits of electives.",
+		"Thermodynamics is the study of heat.",
+		"Data structures: trees, heaps and graphs.",
+	}
+
+	scores, err := LexicalReranker{}.Rerank(context.Background(), "What does CSC 201 cover?", passages)
+	if err != nil {
+		t.Fatalf("Rerank() error = %v", err)
+	}
+	if len(scores) != len(passages) {
+		t.Fatalf("got %d scores for %d passages", len(scores), len(passages))
+	}
+	for i, score := range scores {
+		if score < 0 || score > 1 {
+			t.Errorf("score %d = %v, outside 0 to 1", i, score)
+		}
+	}
+
+	// The exact phrase beats the same words apart; unrelated text scores nothing
+	if got := Order(scores, len(passages)); !reflect.DeepEqual(got[:2], []int{0, 1}) {
+		t.Errorf("order = %v, scores %v", got, scores)
+	}
+	if scores[0] <= scores[1] {
+		t.Errorf("phrase match %v doesn't beat scattered terms %v", scores[0], scores[1])
+	}
+	if scores[2] != 0 {
+		t.Errorf("unrelated passage scored %v", scores[2])
+	}
+}
+
+func TestLexicalRerankerWeightsRareTerms(t *testing.T) {
+	// Every passage mentions heat, so matching entropy counts for more
+	passages := []string{
+		"Heat flows from hot to cold.",
+		"Heat and entropy are related.",
+		"Heat engines do work.",
+	}
+	scores, err := LexicalReranker{}.Rerank(context.Background(), "heat entropy", passages)
+	if err != nil {
+		t.Fatalf("Rerank() error = %v", err)
+	}
+	if scores[1] <= scores[0] || scores[0] != scores[2] {
+		t.Errorf("scores = %v", scores)
+	}
+	if scores[0] >= 0.5*(1-bigramWeight) {
+		t.Errorf("common term alone scored %v", scores[0])
+	}
+}
+
+func TestLexicalRerankerNoScores(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		passages []string
+	}{
+		{"only stopwords", "what is the", []string{"The answer is here."}},
+		{"no passages", "entropy", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scores, err := LexicalReranker{}.Rerank(context.Background(), tt.query, tt.passages)
+			if scores != nil || err != nil {
+				t.Errorf("Rerank() = %v, %v; want no scores", scores, err)
+			}
+		})
+	}
+}
+
+func TestLexicalRerankerCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := (LexicalReranker{}).Rerank(ctx, "entropy", []string{"entropy"}); err == nil {
+		t.Error("Rerank() ignored the cancelled context")
+	}
+}
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"What is CSC-201?", []string{"csc", "201"}},
+		{"The Théorème of Gödel", []string{"théorème", "gödel"}},
+		{"is the a", []string{}},
+	}
+
+	for _, tt := range tests {
+		if got := terms(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("terms(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package rerank
+
+import "math"
+
+// MMR selects up to k candidates by Maximal Marginal Relevance: each pick
+// maximises lambda*relevance - (1-lambda)*similarity to the closest
+// candidate already picked, so near-duplicates of a chosen chunk (such as
+// its overlapping neighbours) lose out to material covering something else.
+// relevance is on a 0 to 1 scale; a candidate with a nil vector is treated
+// as unlike every other. Returns the chosen indexes in pick order.
+func MMR(relevance []float64, vectors [][]float32, k int, lambda float64) []int {
+	n := len(relevance)
+	if k > n {
+		k = n
+	}
+
+	selected := make([]int, 0, k)
+	picked := make([]bool, n)
+	// Highest similarity of each candidate to anything selected so far
+	maxSimilarity := make([]float64, n)
+
+	for len(selected) < k {
+		best := -1
+		bestScore := math.Inf(-1)
+		for i := 0; i < n; i++ {
+			if picked[i] {
+				continue
+			}
+			score := lambda*relevance[i] - (1-lambda)*maxSimilarity[i]
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+
+		picked[best] = true
+		selected = append(selected, best)
+
+		for i := 0; i < n; i++ {
+			if picked[i] {
+				continue
+			}
+			if similarity := cosineSimilarity(vectors[i], vectors[best]); similarity > maxSimilarity[i] {
+				maxSimilarity[i] = similarity
+			}
+		}
+	}
+
+	return selected
+}
+
+// cosineSimilarity returns the cosine similarity of two vectors, or 0 when
+// either is missing or they differ in length
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
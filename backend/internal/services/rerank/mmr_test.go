@@ -0,0 +1,65 @@
+package rerank
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestMMR(t *testing.T) {
+	// 0 and 1 are overlapping neighbours; 2 covers something else
+	relevance := []float64{0.9, 0.85, 0.6, 0.3}
+	vectors := [][]float32{
+		{1, 0, 0},
+		{0.99, 0.1, 0},
+		{0, 1, 0},
+		{0, 0, 1},
+	}
+
+	tests := []struct {
+		name    string
+		vectors [][]float32
+		k       int
+		lambda  float64
+		want    []int
+	}{
+		{"relevance only", vectors, 3, 1, []int{0, 1, 2}},
+		{"diversity skips the duplicate", vectors, 3, 0.5, []int{0, 2, 3}},
+		{"k beyond candidates", vectors, 10, 1, []int{0, 1, 2, 3}},
+		{"k zero", vectors, 0, 0.5, []int{}},
+		{"missing vectors are unlike everything", [][]float32{{1, 0, 0}, nil, {0, 1, 0}, nil}, 3, 0.5, []int{0, 1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MMR(relevance, tt.vectors, tt.k, tt.lambda)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MMR() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float32
+		want float64
+	}{
+		{"identical", []float32{1, 2, 3}, []float32{1, 2, 3}, 1},
+		{"scaled", []float32{1, 2}, []float32{2, 4}, 1},
+		{"orthogonal", []float32{1, 0}, []float32{0, 1}, 0},
+		{"opposite", []float32{1, 0}, []float32{-1, 0}, -1},
+		{"missing", nil, []float32{1, 0}, 0},
+		{"length mismatch", []float32{1, 0}, []float32{1, 0, 0}, 0},
+		{"zero vector", []float32{0, 0}, []float32{1, 0}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cosineSimilarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("cosineSimilarity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package rerank
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kinyichukwu/edu-pro-backend/internal/services/ai"
+)
+
+// Reranker provider names
+const (
+	ProviderLLM     = "llm"
+	ProviderLexical = "lexical"
+	ProviderNone    = "none"
+)
+
+// Reranker scores retrieved passages against a query, so the final context
+// can be chosen from a larger candidate pool than is sent to the model
+type Reranker interface {
+	Name() string
+	// Rerank returns one score per passage, higher is more relevant. A nil
+	// result keeps the retrieval order.
+	Rerank(ctx context.Context, query string, passages []string) ([]float64, error)
+}
+
+// NewReranker creates the reranker selected by provider
+func NewReranker(provider string, aiClient ai.Service) (Reranker, error) {
+	switch strings.ToLower(provider) {
+	case "", ProviderNone:
+		return NoopReranker{}, nil
+	case ProviderLexical:
+		return LexicalReranker{}, nil
+	case ProviderLLM:
+		return NewLLMReranker(aiClient), nil
+	default:
+		return nil, fmt.Errorf("unknown reranker: %s", provider)
+	}
+}
+
+// Order returns passage indexes sorted by descending score, keeping the
+// retrieval order for ties and when scores is nil
+func Order(scores []float64, n int) []int {
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	if scores == nil {
+		return order
+	}
+
+	sort.SliceStable(order, func(i, j int) bool { return scores[order[i]] > scores[order[j]] })
+	return order
+}
+
+// NoopReranker keeps the retrieval order
+type NoopReranker struct{}
+
+// Name returns the reranker name
+func (NoopReranker) Name() string {
+	return ProviderNone
+}
+
+// Rerank returns no scores
+func (NoopReranker) Rerank(ctx context.Context, query string, passages []string) ([]float64, error) {
+	return nil, nil
+}
+
+// LLMReranker asks the AI service to score each passage
+type LLMReranker struct {
+	aiClient ai.Service
+}
+
+// NewLLMReranker creates a reranker backed by the AI service
+func NewLLMReranker(aiClient ai.Service) *LLMReranker {
+	return &LLMReranker{aiClient: aiClient}
+}
+
+// Name returns the reranker name
+func (r *LLMReranker) Name() string {
+	return ProviderLLM
+}
+
+// Rerank scores all passages in a single model call
+func (r *LLMReranker) Rerank(ctx context.Context, query string, passages []string) ([]float64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return r.aiClient.ScoreRelevance(query, passages)
+}
@@ -0,0 +1,62 @@
+package rerank
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestNewReranker(t *testing.T) {
+	tests := []struct {
+		provider string
+		want     string
+		wantErr  bool
+	}{
+		{"", ProviderNone, false},
+		{"none", ProviderNone, false},
+		{"Lexical", ProviderLexical, false},
+		{"llm", ProviderLLM, false},
+		{"cohere", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			reranker, err := NewReranker(tt.provider, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewReranker(%q) error = %v, wantErr %v", tt.provider, err, tt.wantErr)
+			}
+			if err == nil && reranker.Name() != tt.want {
+				t.Errorf("NewReranker(%q).Name() = %q, want %q", tt.provider, reranker.Name(), tt.want)
+			}
+		})
+	}
+}
+
+func TestOrder(t *testing.T) {
+	tests := []struct {
+		name   string
+		scores []float64
+		n      int
+		want   []int
+	}{
+		{"nil keeps retrieval order", nil, 3, []int{0, 1, 2}},
+		{"descending score", []float64{0.2, 0.9, 0.5}, 3, []int{1, 2, 0}},
+		{"ties keep retrieval order", []float64{0.5, 0.9, 0.5, 0.5}, 4, []int{1, 0, 2, 3}},
+		{"empty", nil, 0, []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Order(tt.scores, tt.n); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Order() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNoopReranker(t *testing.T) {
+	scores, err := NoopReranker{}.Rerank(context.Background(), "heat", []string{"a", "b"})
+	if scores != nil || err != nil {
+		t.Errorf("Rerank() = %v, %v; want no scores", scores, err)
+	}
+}
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/kinyichukwu/edu-pro-backend/internal/config"
+)
+
+// Storage driver names
+const (
+	DriverSupabase = "supabase"
+	DriverS3       = "s3"
+	DriverLocal    = "local"
+)
+
+// ErrNotFound is returned when a blob does not exist
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore defines the interface of a storage backend. Keys are slash
+// separated paths such as "<user id>/<file name>".
+type BlobStore interface {
+	Driver() string
+	Put(ctx context.Context, key string, reader io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+	// Walk calls fn for every blob under the prefix folder, or the whole
+	// store for an empty prefix; an error from fn stops the walk
+	Walk(ctx context.Context, prefix string, fn func(BlobInfo) error) error
+}
+
+// BlobInfo describes a stored blob
+type BlobInfo struct {
+	Key      string
+	Size     int64
+	Modified time.Time
+}
+
+// NewBlobStore creates the storage backend selected in the configuration
+func NewBlobStore(cfg *config.Config) (BlobStore, error) {
+	switch strings.ToLower(cfg.StorageDriver) {
+	case "", DriverSupabase:
+		return NewSupabaseStore(cfg)
+	case DriverS3:
+		return NewS3Store(cfg)
+	case DriverLocal:
+		return NewLocalStore(cfg.StorageLocalDir, cfg.StoragePublicURL)
+	default:
+		return nil, fmt.Errorf("unknown storage driver: %s", cfg.StorageDriver)
+	}
+}
+
+// publicURL joins a configured public base URL and a key
+func publicURL(baseURL, key string) string {
+	return strings.TrimSuffix(baseURL, "/") + "/" + key
+}
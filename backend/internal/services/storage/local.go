@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStore stores blobs on the local filesystem. It needs no external
+// services, which makes it the driver for development and CI.
+type LocalStore struct {
+	root      string
+	publicURL string
+}
+
+// NewLocalStore creates a filesystem backend rooted at dir. Without a public
+// base URL, blob URLs are file:// URLs.
+func NewLocalStore(dir, publicURL string) (*LocalStore, error) {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid storage directory: %w", err)
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	return &LocalStore{
+		root:      root,
+		publicURL: publicURL,
+	}, nil
+}
+
+// Driver returns the driver name
+func (s *LocalStore) Driver() string {
+	return DriverLocal
+}
+
+// Put writes a blob. The data goes to a temporary file that is renamed into
+// place, so readers never see a partial blob.
+func (s *LocalStore) Put(ctx context.Context, key string, reader io.Reader, size int64, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create blob directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create blob: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, reader); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write blob: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write blob: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write blob: %w", err)
+	}
+	return nil
+}
+
+// Get opens a blob for reading
+func (s *LocalStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open blob: %w", err)
+	}
+	return file, nil
+}
+
+// Delete removes a blob; deleting a missing blob is not an error
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete blob: %w", err)
+	}
+	return nil
+}
+
+// URL returns the public URL of a blob
+func (s *LocalStore) URL(key string) string {
+	if s.publicURL != "" {
+		return publicURL(s.publicURL, key)
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(filepath.Join(s.root, key))}).String()
+}
+
+// Walk calls fn for every file under the prefix folder of the root
+func (s *LocalStore) Walk(ctx context.Context, prefix string, fn func(BlobInfo) error) error {
+	dir := filepath.Join(s.root, filepath.FromSlash(prefix))
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		// Nothing has been stored under the prefix yet
+		if path == dir && errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(s.root, path)
+		if err != nil {
+			return err
+		}
+		return fn(BlobInfo{
+			Key:      filepath.ToSlash(rel),
+			Size:     info.Size(),
+			Modified: info.ModTime(),
+		})
+	})
+}
+
+// path maps a key to a file under the root, rejecting keys that escape it
+func (s *LocalStore) path(key string) (string, error) {
+	cleaned := filepath.Clean(filepath.FromSlash(key))
+	if key == "" || filepath.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid blob key: %q", key)
+	}
+	return filepath.Join(s.root, cleaned), nil
+}
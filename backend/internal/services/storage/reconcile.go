@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/kinyichukwu/edu-pro-backend/internal/utils"
+	"go.uber.org/zap"
+)
+
+// ReferencedFunc reports which of the given keys belong to a document
+type ReferencedFunc func(ctx context.Context, keys []string) (map[string]bool, error)
+
+// Reconciler periodically removes orphaned blobs: files that no document
+// references, left behind by failed uploads or by deletes whose storage step
+// failed. Only blobs under the app's key prefix are considered, and blobs
+// younger than the grace period are skipped so an upload that has not yet
+// inserted its document row is never touched. In dry-run mode orphans are
+// only logged.
+type Reconciler struct {
+	blobs       BlobStore
+	referenced  ReferencedFunc
+	prefix      string
+	interval    time.Duration
+	gracePeriod time.Duration
+	dryRun      bool
+	batchSize   int
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewReconciler creates an orphaned blob reconciler for the blobs under
+// prefix
+func NewReconciler(blobs BlobStore, referenced ReferencedFunc, prefix string, interval, gracePeriod time.Duration, dryRun bool) *Reconciler {
+	return &Reconciler{
+		blobs:       blobs,
+		referenced:  referenced,
+		prefix:      prefix,
+		interval:    interval,
+		gracePeriod: gracePeriod,
+		dryRun:      dryRun,
+		batchSize:   500,
+	}
+}
+
+// Start runs a reconciliation pass every interval. A zero interval disables it.
+func (r *Reconciler) Start() {
+	if r.interval <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		logger := utils.GetLogger()
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				removed, err := r.RunOnce(ctx)
+				if err != nil && ctx.Err() == nil {
+					logger.Error("Storage reconciliation failed", zap.Error(err))
+				} else if removed > 0 && r.dryRun {
+					logger.Info("Orphaned blobs found (dry run)", zap.Int("count", removed))
+				} else if removed > 0 {
+					logger.Info("Orphaned blobs removed", zap.Int("count", removed))
+				}
+			}
+		}
+	}()
+
+	utils.GetLogger().Info("Storage reconciler started",
+		zap.String("driver", r.blobs.Driver()),
+		zap.String("prefix", r.prefix),
+		zap.Duration("interval", r.interval),
+		zap.Bool("dry_run", r.dryRun),
+	)
+}
+
+// Stop stops the reconciler and waits for a running pass to return
+func (r *Reconciler) Stop() {
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	r.wg.Wait()
+}
+
+// RunOnce walks the prefix and deletes unreferenced blobs older than the
+// grace period, returning how many were removed, or in dry-run mode how many
+// would have been
+func (r *Reconciler) RunOnce(ctx context.Context) (int, error) {
+	cutoff := time.Now().Add(-r.gracePeriod)
+	removed := 0
+	batch := make([]string, 0, r.batchSize)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		referenced, err := r.referenced(ctx, batch)
+		if err != nil {
+			return err
+		}
+		for _, key := range batch {
+			if referenced[key] {
+				continue
+			}
+			if r.dryRun {
+				utils.GetLogger().Info("Orphaned blob found (dry run)", zap.String("key", key))
+				removed++
+				continue
+			}
+			if err := r.blobs.Delete(ctx, key); err != nil {
+				utils.GetLogger().Warn("Failed to delete orphaned blob", zap.String("key", key), zap.Error(err))
+				continue
+			}
+			utils.GetLogger().Info("Orphaned blob deleted", zap.String("key", key))
+			removed++
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	err := r.blobs.Walk(ctx, r.prefix, func(info BlobInfo) error {
+		// An unknown modification time is treated as recent
+		if info.Modified.IsZero() || info.Modified.After(cutoff) {
+			return nil
+		}
+		batch = append(batch, info.Key)
+		if len(batch) >= r.batchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return removed, err
+	}
+	return removed, flush()
+}
@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/kinyichukwu/edu-pro-backend/internal/config"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Store stores blobs in an S3-compatible bucket such as AWS S3 or MinIO
+type S3Store struct {
+	client    *minio.Client
+	bucket    string
+	publicURL string
+}
+
+// NewS3Store creates an S3-compatible storage backend
+func NewS3Store(cfg *config.Config) (*S3Store, error) {
+	if cfg.S3Endpoint == "" {
+		return nil, fmt.Errorf("S3_ENDPOINT is required for the s3 storage driver")
+	}
+
+	client, err := minio.New(cfg.S3Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.S3AccessKeyID, cfg.S3SecretAccessKey, ""),
+		Secure: cfg.S3UseSSL,
+		Region: cfg.S3Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 client: %w", err)
+	}
+
+	publicURL := cfg.StoragePublicURL
+	if publicURL == "" {
+		publicURL = client.EndpointURL().String() + "/" + cfg.BucketName
+	}
+
+	return &S3Store{
+		client:    client,
+		bucket:    cfg.BucketName,
+		publicURL: publicURL,
+	}, nil
+}
+
+// Driver returns the driver name
+func (s *S3Store) Driver() string {
+	return DriverS3
+}
+
+// Put uploads a blob. A known size lets the client stream it in one request.
+func (s *S3Store) Put(ctx context.Context, key string, reader io.Reader, size int64, contentType string) error {
+	_, err := s.client.PutObject(ctx, s.bucket, key, reader, size, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload to S3: %w", err)
+	}
+	return nil
+}
+
+// Get streams a blob from the bucket
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	object, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download from S3: %w", err)
+	}
+
+	// GetObject is lazy; Stat surfaces a missing key before any reads
+	if _, err := object.Stat(); err != nil {
+		object.Close()
+		if minio.ToErrorResponse(err).Code == minio.NoSuchKey {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+		}
+		return nil, fmt.Errorf("failed to download from S3: %w", err)
+	}
+	return object, nil
+}
+
+// Delete removes a blob from the bucket
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	if err := s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to delete from S3: %w", err)
+	}
+	return nil
+}
+
+// Walk calls fn for every object under the prefix folder of the bucket
+func (s *S3Store) Walk(ctx context.Context, prefix string, fn func(BlobInfo) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if prefix != "" {
+		prefix += "/"
+	}
+	for object := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if object.Err != nil {
+			return fmt.Errorf("failed to list S3 objects: %w", object.Err)
+		}
+		if err := fn(BlobInfo{Key: object.Key, Size: object.Size, Modified: object.LastModified}); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+// URL returns the public URL of a blob
+func (s *S3Store) URL(key string) string {
+	return publicURL(s.publicURL, key)
+}
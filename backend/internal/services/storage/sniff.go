@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// ErrUnsupportedType is returned for files whose extension isn't one of the
+// supported document types
+var ErrUnsupportedType = errors.New("unsupported file type")
+
+// ErrContentMismatch is returned when a file's bytes don't match the type
+// its extension claims
+var ErrContentMismatch = errors.New("file content does not match its type")
+
+// sniffLength covers the PDF header, which may appear anywhere in the first
+// 1024 bytes
+const sniffLength = 1024
+
+// officeParts are the package parts every document of an Office Open XML
+// type has
+var officeParts = map[string][]string{
+	".docx": {"[Content_Types].xml", "word/document.xml"},
+	".pptx": {"[Content_Types].xml", "ppt/presentation.xml"},
+}
+
+// sniffContent checks a file's content against its extension and returns a
+// reader that still yields the whole file, and a function to call once it
+// has been read
+func sniffContent(reader io.Reader, ext string) (io.Reader, func(), error) {
+	if _, ok := officeParts[ext]; ok {
+		return sniffPackage(reader, ext)
+	}
+
+	buffered := bufio.NewReaderSize(reader, sniffLength)
+	head, err := buffered.Peek(sniffLength)
+	if err != nil && err != io.EOF {
+		return nil, nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	if err := checkContent(ext, head); err != nil {
+		return nil, nil, err
+	}
+	return buffered, func() {}, nil
+}
+
+// sniffPackage checks an Office Open XML package. Its parts are listed in
+// the ZIP central directory at the end of the file, so a file that isn't
+// already on disk is spooled to a temporary one first.
+func sniffPackage(reader io.Reader, ext string) (io.Reader, func(), error) {
+	cleanup := func() {}
+	file, ok := reader.(*os.File)
+	if !ok {
+		tmp, err := os.CreateTemp("", "edupro-upload-*"+ext)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create temp file: %w", err)
+		}
+		cleanup = func() {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+		if _, err := io.Copy(tmp, reader); err != nil {
+			cleanup()
+			return nil, nil, fmt.Errorf("failed to read file: %w", err)
+		}
+		if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+			cleanup()
+			return nil, nil, fmt.Errorf("failed to read file: %w", err)
+		}
+		file = tmp
+	}
+
+	// Read from the current position without moving it, so the file is
+	// then stored whole
+	offset, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	if err := checkPackage(ext, io.NewSectionReader(file, offset, info.Size()-offset)); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return file, cleanup, nil
+}
+
+// checkPackage requires a ZIP package with the parts of its Office type, so
+// an arbitrary archive renamed to .docx or .pptx is rejected
+func checkPackage(ext string, r *io.SectionReader) error {
+	head := make([]byte, sniffLength)
+	n, err := r.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	if err := checkContent(ext, head[:n]); err != nil {
+		return err
+	}
+
+	archive, err := zip.NewReader(r, r.Size())
+	if err != nil {
+		return fmt.Errorf("%w: %s file is not an Office Open XML package", ErrContentMismatch, ext)
+	}
+	names := make(map[string]bool, len(archive.File))
+	for _, f := range archive.File {
+		names[f.Name] = true
+	}
+	for _, part := range officeParts[ext] {
+		if !names[part] {
+			return fmt.Errorf("%w: %s file has no %s part", ErrContentMismatch, ext, part)
+		}
+	}
+	return nil
+}
+
+// checkContent matches the magic bytes of supported types
+func checkContent(ext string, head []byte) error {
+	switch ext {
+	case ".pdf":
+		if !bytes.Contains(head, []byte("%PDF-")) {
+			return fmt.Errorf("%w: %s file has no PDF header", ErrContentMismatch, ext)
+		}
+	case ".docx", ".pptx":
+		// Office Open XML documents are ZIP packages; sniffPackage also
+		// checks their parts
+		if !bytes.HasPrefix(head, []byte("PK\x03\x04")) {
+			return fmt.Errorf("%w: %s file is not an Office Open XML package", ErrContentMismatch, ext)
+		}
+	case ".txt", ".html", ".htm":
+		if detected := http.DetectContentType(head); !strings.HasPrefix(detected, "text/") {
+			return fmt.Errorf("%w: %s file contains %s data", ErrContentMismatch, ext, strings.Split(detected, ";")[0])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// zipOf returns a ZIP archive holding an empty file for each name
+func zipOf(t *testing.T, names ...string) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	w := zip.NewWriter(&b)
+	for _, name := range names {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte("<xml/>"))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestCheckContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		ext     string
+		head    []byte
+		wantErr bool
+	}{
+		{"pdf", ".pdf", []byte("%PDF-1.7\n"), false},
+		{"pdf header after junk", ".pdf", []byte("\x00\x00%PDF-1.4"), false},
+		{"pdf without header", ".pdf", []byte("<html></html>"), true},
+		{"docx zip", ".docx", []byte("PK\x03\x04rest"), false},
+		{"docx not a zip", ".docx", []byte("%PDF-1.4"), true},
+		{"text", ".txt", []byte("Lecture notes\nWeek 1"), false},
+		{"text with binary", ".txt", []byte("\x7fELF\x02\x01\x01\x00"), true},
+		{"html", ".html", []byte("<!DOCTYPE html><html>"), false},
+		{"html as png", ".htm", []byte("\x89PNG\r\n\x1a\n"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkContent(tt.ext, tt.head)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkContent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrContentMismatch) {
+				t.Errorf("error %v is not ErrContentMismatch", err)
+			}
+		})
+	}
+}
+
+func TestSniffPackage(t *testing.T) {
+	docx := zipOf(t, "[Content_Types].xml", "word/document.xml", "word/styles.xml")
+	pptx := zipOf(t, "[Content_Types].xml", "ppt/presentation.xml", "ppt/slides/slide1.xml")
+	plainZip := zipOf(t, "notes.txt", "images/figure.png")
+
+	tests := []struct {
+		name    string
+		ext     string
+		content []byte
+		wantErr bool
+	}{
+		{"docx", ".docx", docx, false},
+		{"pptx", ".pptx", pptx, false},
+		{"zip renamed to docx", ".docx", plainZip, true},
+		{"zip renamed to pptx", ".pptx", plainZip, true},
+		{"pptx renamed to docx", ".docx", pptx, true},
+		{"truncated package", ".docx", docx[:len(docx)/2], true},
+		{"not a zip", ".pptx", []byte("plain text"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Streams are spooled to disk; files are checked in place
+			file, err := os.CreateTemp(t.TempDir(), "upload-*"+tt.ext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+			file.Write(tt.content)
+			file.Seek(0, io.SeekStart)
+
+			sources := map[string]io.Reader{
+				"stream": bytes.NewReader(tt.content),
+				"file":   file,
+			}
+			for source, reader := range sources {
+				got, cleanup, err := sniffContent(reader, tt.ext)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("%s: sniffContent() error = %v, wantErr %v", source, err, tt.wantErr)
+				}
+				if err != nil {
+					if !errors.Is(err, ErrContentMismatch) {
+						t.Errorf("%s: error %v is not ErrContentMismatch", source, err)
+					}
+					continue
+				}
+
+				// The whole file is still there to be stored
+				content, err := io.ReadAll(got)
+				cleanup()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(content, tt.content) {
+					t.Errorf("%s: read %d bytes, want %d", source, len(content), len(tt.content))
+				}
+			}
+		})
+	}
+}
+
+func TestSniffContentKeepsWholeFile(t *testing.T) {
+	text := strings.Repeat("Thermodynamics lecture notes. ", 100)
+	reader, cleanup, err := sniffContent(strings.NewReader(text), ".txt")
+	if err != nil {
+		t.Fatalf("sniffContent() error = %v", err)
+	}
+	defer cleanup()
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != text {
+		t.Errorf("read %d bytes, want %d", len(content), len(text))
+	}
+}
@@ -1,7 +1,9 @@
 package storage
 
 import (
-	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -12,46 +14,41 @@ import (
 	"github.com/google/uuid"
 	"github.com/kinyichukwu/edu-pro-backend/internal/config"
 	"github.com/kinyichukwu/edu-pro-backend/internal/utils"
-	"github.com/supabase-community/supabase-go"
 	"go.uber.org/zap"
 )
 
-// Client represents the storage client
+// KeyPrefix is the folder every uploaded file is stored under. Only blobs
+// under it are considered by the orphan reconciler, so a bucket shared with
+// other data is safe.
+const KeyPrefix = "documents"
+
+// Client represents the storage client. Files are kept in the BlobStore
+// selected by STORAGE_DRIVER.
 type Client struct {
-	supabase   *supabase.Client
-	bucketName string
+	blobs BlobStore
 }
 
 // NewClient creates a new storage client
 func NewClient(cfg *config.Config) (*Client, error) {
 	logger := utils.GetLogger()
 
-	supabaseClient, err := supabase.NewClient(cfg.SupabaseURL, cfg.SupabaseKey, &supabase.ClientOptions{})
+	blobs, err := NewBlobStore(cfg)
 	if err != nil {
-		logger.Error("Failed to create Supabase client", zap.Error(err))
-		return nil, fmt.Errorf("failed to create Supabase client: %w", err)
+		logger.Error("Failed to create blob store", zap.Error(err))
+		return nil, fmt.Errorf("failed to create blob store: %w", err)
 	}
 
+	logger.Info("Storage backend initialized", zap.String("driver", blobs.Driver()))
+
 	return &Client{
-		supabase:   supabaseClient,
-		bucketName: cfg.BucketName,
+		blobs: blobs,
 	}, nil
 }
 
-// UploadFile uploads a file to Supabase storage
-func (c *Client) UploadFile(file *multipart.FileHeader, userID string) (*UploadResult, error) {
+// UploadFile uploads a multipart file to storage
+func (c *Client) UploadFile(file *multipart.FileHeader, userID string, maxSize int64) (*UploadResult, error) {
 	logger := utils.GetLogger()
 
-	// Validate file type
-	if !c.isValidFileType(file.Filename) {
-		return nil, fmt.Errorf("unsupported file type: %s", filepath.Ext(file.Filename))
-	}
-
-	// Validate file size (10MB limit)
-	if file.Size > 10*1024*1024 {
-		return nil, fmt.Errorf("file size exceeds 10MB limit")
-	}
-
 	// Open the file
 	src, err := file.Open()
 	if err != nil {
@@ -60,78 +57,126 @@ func (c *Client) UploadFile(file *multipart.FileHeader, userID string) (*UploadR
 	}
 	defer src.Close()
 
-	// Read file content
-	fileContent, err := io.ReadAll(src)
+	return c.UploadStream(src, file.Filename, file.Size, userID, maxSize)
+}
+
+// UploadStream streams a file of known size to storage without
+// buffering it in memory. The SHA-256 checksum is computed on the way through.
+func (c *Client) UploadStream(reader io.Reader, filename string, size int64, userID string, maxSize int64) (*UploadResult, error) {
+	logger := utils.GetLogger()
+
+	// Validate file type
+	if !c.isValidFileType(filename) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, filepath.Ext(filename))
+	}
+
+	// Validate file size against the caller's limit
+	if maxSize > 0 && size > maxSize {
+		return nil, fmt.Errorf("file size exceeds %dMB limit", maxSize/(1024*1024))
+	}
+
+	// Check the bytes match the claimed type before storing anything
+	ext := filepath.Ext(filename)
+	reader, cleanup, err := sniffContent(reader, strings.ToLower(ext))
 	if err != nil {
-		logger.Error("Failed to read file content", zap.Error(err))
-		return nil, fmt.Errorf("failed to read file content: %w", err)
+		logger.Warn("Rejected file with mismatched content",
+			zap.String("filename", filename),
+			zap.Error(err),
+		)
+		return nil, err
 	}
+	defer cleanup()
 
 	// Generate unique filename
-	ext := filepath.Ext(file.Filename)
-	baseFilename := strings.TrimSuffix(file.Filename, ext)
-	uniqueFilename := fmt.Sprintf("%s/%s_%s%s",
+	baseFilename := strings.TrimSuffix(filename, ext)
+	uniqueFilename := fmt.Sprintf("%s/%s/%s_%s%s",
+		KeyPrefix,
 		userID,
 		baseFilename,
 		uuid.New().String()[:8],
 		ext,
 	)
 
-	// Upload to Supabase storage with proper content type
-	uploadResp, err := c.supabase.Storage.UploadFile(c.bucketName, uniqueFilename, bytes.NewReader(fileContent))
+	// Content hash used to detect re-uploads of the same file
+	hasher := sha256.New()
+	counter := &countingReader{reader: io.TeeReader(reader, hasher)}
+
+	// Upload to storage
+	mimeType := c.getMimeType(filename)
+	err = c.blobs.Put(context.Background(), uniqueFilename, counter, size, mimeType)
 	if err != nil {
-		logger.Error("Failed to upload file to Supabase",
+		logger.Error("Failed to upload file to storage",
 			zap.Error(err),
 			zap.String("filename", uniqueFilename),
 		)
 		return nil, fmt.Errorf("failed to upload file: %w", err)
 	}
-
-	// Get public URL
-	publicURLResp := c.supabase.Storage.GetPublicUrl(c.bucketName, uniqueFilename)
-	publicURL := publicURLResp.SignedURL
+	if counter.count != size {
+		logger.Error("Uploaded file size mismatch",
+			zap.String("filename", uniqueFilename),
+			zap.Int64("expected", size),
+			zap.Int64("actual", counter.count),
+		)
+		c.DeleteFile(uniqueFilename)
+		return nil, fmt.Errorf("file size mismatch: expected %d bytes, got %d", size, counter.count)
+	}
 
 	result := &UploadResult{
-		Filename:    file.Filename,
+		Filename:    filename,
 		StoragePath: uniqueFilename,
-		PublicURL:   publicURL,
-		MimeType:    c.getMimeType(file.Filename),
-		Size:        file.Size,
+		PublicURL:   c.blobs.URL(uniqueFilename),
+		MimeType:    mimeType,
+		Size:        size,
 		UploadedAt:  time.Now(),
-		Key:         uploadResp.Key,
+		Key:         uniqueFilename,
+		Checksum:    hex.EncodeToString(hasher.Sum(nil)),
 	}
 
 	logger.Info("File uploaded successfully",
-		zap.String("filename", file.Filename),
+		zap.String("filename", filename),
 		zap.String("storage_path", uniqueFilename),
-		zap.Int64("size", file.Size),
+		zap.Int64("size", size),
+		zap.String("checksum", result.Checksum),
 	)
 
 	return result, nil
 }
 
-// GetSignedURL generates a signed URL for private file access
-func (c *Client) GetSignedURL(path string, expiresIn int) (string, error) {
-	logger := utils.GetLogger()
+// countingReader counts the bytes read through it
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.count += int64(n)
+	return n, err
+}
+
+// IsValidFileType reports whether files with this name can be uploaded
+func (c *Client) IsValidFileType(filename string) bool {
+	return c.isValidFileType(filename)
+}
 
-	signedURLResp, err := c.supabase.Storage.CreateSignedUrl(c.bucketName, path, expiresIn)
+// Open streams a stored file. The caller must close the reader.
+func (c *Client) Open(ctx context.Context, path string) (io.ReadCloser, error) {
+	reader, err := c.blobs.Get(ctx, path)
 	if err != nil {
-		logger.Error("Failed to create signed URL",
+		utils.GetLogger().Error("Failed to open file",
 			zap.Error(err),
 			zap.String("path", path),
 		)
-		return "", fmt.Errorf("failed to create signed URL: %w", err)
+		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
-
-	return signedURLResp.SignedURL, nil
+	return reader, nil
 }
 
 // DeleteFile deletes a file from storage
 func (c *Client) DeleteFile(path string) error {
 	logger := utils.GetLogger()
 
-	_, err := c.supabase.Storage.RemoveFile(c.bucketName, []string{path})
-	if err != nil {
+	if err := c.blobs.Delete(context.Background(), path); err != nil {
 		logger.Error("Failed to delete file",
 			zap.Error(err),
 			zap.String("path", path),
@@ -147,9 +192,12 @@ func (c *Client) DeleteFile(path string) error {
 func (c *Client) isValidFileType(filename string) bool {
 	ext := strings.ToLower(filepath.Ext(filename))
 	validTypes := map[string]bool{
-		".pdf": true,
-		".txt": true,
-		// ".docx": true, // Disabled for now
+		".pdf":  true,
+		".txt":  true,
+		".docx": true,
+		".pptx": true,
+		".html": true,
+		".htm":  true,
 	}
 	return validTypes[ext]
 }
@@ -158,9 +206,12 @@ func (c *Client) isValidFileType(filename string) bool {
 func (c *Client) getMimeType(filename string) string {
 	ext := strings.ToLower(filepath.Ext(filename))
 	mimeTypes := map[string]string{
-		".pdf": "application/pdf",
-		".txt": "text/plain",
-		// ".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document", // Disabled
+		".pdf":  "application/pdf",
+		".txt":  "text/plain",
+		".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+		".html": "text/html",
+		".htm":  "text/html",
 	}
 
 	if mimeType, exists := mimeTypes[ext]; exists {
@@ -178,4 +229,5 @@ type UploadResult struct {
 	Size        int64     `json:"size"`
 	UploadedAt  time.Time `json:"uploaded_at"`
 	Key         string    `json:"key"`
+	Checksum    string    `json:"checksum"` // SHA-256 of the file content, hex encoded
 }
@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kinyichukwu/edu-pro-backend/internal/config"
+	storage_go "github.com/supabase-community/storage-go"
+)
+
+// SupabaseStore stores blobs in a Supabase Storage bucket
+type SupabaseStore struct {
+	client  *storage_go.Client
+	baseURL string
+	bucket  string
+}
+
+// NewSupabaseStore creates a Supabase Storage backend
+func NewSupabaseStore(cfg *config.Config) (*SupabaseStore, error) {
+	if cfg.SupabaseURL == "" || cfg.SupabaseKey == "" {
+		return nil, fmt.Errorf("SUPABASE_URL and SUPABASE_KEY are required for the supabase storage driver")
+	}
+
+	baseURL := strings.TrimSuffix(cfg.SupabaseURL, "/") + "/storage/v1"
+	return &SupabaseStore{
+		client:  storage_go.NewClient(baseURL, cfg.SupabaseKey, nil),
+		baseURL: baseURL,
+		bucket:  cfg.BucketName,
+	}, nil
+}
+
+// Driver returns the driver name
+func (s *SupabaseStore) Driver() string {
+	return DriverSupabase
+}
+
+// Put uploads a blob. The body is streamed rather than buffered.
+func (s *SupabaseStore) Put(ctx context.Context, key string, reader io.Reader, size int64, contentType string) error {
+	_, err := s.client.UploadFile(s.bucket, key, reader, storage_go.FileOptions{
+		ContentType: &contentType,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload to supabase: %w", err)
+	}
+	return nil
+}
+
+// Get streams a blob from the bucket
+func (s *SupabaseStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := s.client.NewRequest(http.MethodGet, s.baseURL+"/object/"+s.bucket+"/"+key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create download request: %w", err)
+	}
+
+	resp, err := s.client.Do(req.WithContext(ctx), nil)
+	if err != nil {
+		if resp != nil {
+			resp.Body.Close()
+			// Supabase reports missing objects as 400 with a "not found" message
+			if resp.StatusCode == http.StatusNotFound || strings.Contains(strings.ToLower(err.Error()), "not found") {
+				return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+			}
+		}
+		return nil, fmt.Errorf("failed to download from supabase: %w", err)
+	}
+	return resp.Body, nil
+}
+
+// Delete removes a blob from the bucket
+func (s *SupabaseStore) Delete(ctx context.Context, key string) error {
+	if _, err := s.client.RemoveFile(s.bucket, []string{key}); err != nil {
+		return fmt.Errorf("failed to delete from supabase: %w", err)
+	}
+	return nil
+}
+
+// URL returns the public URL of a blob
+func (s *SupabaseStore) URL(key string) string {
+	return s.client.GetPublicUrl(s.bucket, key).SignedURL
+}
+
+// Walk calls fn for every object under the prefix folder of the bucket.
+// Supabase lists one folder at a time, so folders are walked recursively.
+func (s *SupabaseStore) Walk(ctx context.Context, prefix string, fn func(BlobInfo) error) error {
+	return s.walk(ctx, prefix, fn)
+}
+
+// walk lists a folder page by page
+func (s *SupabaseStore) walk(ctx context.Context, prefix string, fn func(BlobInfo) error) error {
+	const pageSize = 100
+
+	for offset := 0; ; offset += pageSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		objects, err := s.client.ListFiles(s.bucket, prefix, storage_go.FileSearchOptions{
+			Limit:  pageSize,
+			Offset: offset,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list supabase objects: %w", err)
+		}
+
+		for _, object := range objects {
+			key := object.Name
+			if prefix != "" {
+				key = prefix + "/" + object.Name
+			}
+
+			// Folders have no id
+			if object.Id == "" {
+				if err := s.walk(ctx, key, fn); err != nil {
+					return err
+				}
+				continue
+			}
+
+			info := BlobInfo{Key: key}
+			info.Modified, _ = time.Parse(time.RFC3339, object.UpdatedAt)
+			if metadata, ok := object.Metadata.(map[string]interface{}); ok {
+				if size, ok := metadata["size"].(float64); ok {
+					info.Size = int64(size)
+				}
+			}
+			if err := fn(info); err != nil {
+				return err
+			}
+		}
+
+		if len(objects) < pageSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package uploads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/kinyichukwu/edu-pro-backend/internal/utils"
+	"go.uber.org/zap"
+)
+
+// Session statuses
+const (
+	StatusActive    = "active"
+	StatusCompleted = "completed"
+)
+
+var (
+	// ErrNotFound is returned for unknown, expired or foreign sessions
+	ErrNotFound = errors.New("upload session not found")
+	// ErrOffsetMismatch is returned when a chunk does not start where the
+	// previous one ended; the client should query the offset and resume
+	ErrOffsetMismatch = errors.New("upload offset mismatch")
+	// ErrNotActive is returned when appending to a completed session
+	ErrNotActive = errors.New("upload session is not active")
+	// ErrBusy is returned when another request is already appending to the
+	// session
+	ErrBusy = errors.New("upload session is busy")
+)
+
+// Manager tracks resumable upload sessions. Session state lives in Postgres
+// and received bytes are staged in a local directory until the upload is
+// complete, so every instance serving uploads must share that directory.
+type Manager struct {
+	pool       *pgxpool.Pool
+	stagingDir string
+	ttl        time.Duration
+}
+
+// NewManager creates an upload session manager
+func NewManager(pool *pgxpool.Pool, stagingDir string, ttl time.Duration) (*Manager, error) {
+	if err := os.MkdirAll(stagingDir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create upload staging directory: %w", err)
+	}
+
+	return &Manager{
+		pool:       pool,
+		stagingDir: stagingDir,
+		ttl:        ttl,
+	}, nil
+}
+
+// Create starts a new upload session for a file of the given size
+func (m *Manager) Create(ctx context.Context, userID, filename string, size int64, chatID string) (*Session, error) {
+	logger := utils.GetLogger()
+
+	// Expired sessions are swept whenever a new one starts
+	if removed, err := m.CleanupExpired(ctx); err != nil {
+		logger.Warn("Failed to clean up expired upload sessions", zap.Error(err))
+	} else if removed > 0 {
+		logger.Info("Expired upload sessions removed", zap.Int("count", removed))
+	}
+
+	var chat *string
+	if chatID != "" {
+		chat = &chatID
+	}
+
+	session := &Session{}
+	err := m.pool.QueryRow(ctx, `
+		INSERT INTO upload_sessions (user_id, filename, size, chat_id, expires_at)
+		VALUES ($1, $2, $3, $4, NOW() + make_interval(secs => $5))
+		RETURNING id, user_id, filename, size, upload_offset, COALESCE(chat_id::text, ''), status, expires_at
+	`, userID, filename, size, chat, m.ttl.Seconds()).Scan(
+		&session.ID, &session.UserID, &session.Filename, &session.Size,
+		&session.Offset, &session.ChatID, &session.Status, &session.ExpiresAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload session: %w", err)
+	}
+
+	file, err := os.OpenFile(m.partPath(session.ID), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload staging file: %w", err)
+	}
+	file.Close()
+
+	logger.Info("Upload session created",
+		zap.String("upload_id", session.ID),
+		zap.String("filename", filename),
+		zap.Int64("size", size),
+	)
+
+	return session, nil
+}
+
+// Get returns a user's unexpired session
+func (m *Manager) Get(ctx context.Context, id, userID string) (*Session, error) {
+	session := &Session{}
+	err := m.pool.QueryRow(ctx, `
+		SELECT id, user_id, filename, size, upload_offset, COALESCE(chat_id::text, ''), status, expires_at
+		FROM upload_sessions
+		WHERE id = $1 AND user_id = $2 AND expires_at > NOW()
+	`, id, userID).Scan(
+		&session.ID, &session.UserID, &session.Filename, &session.Size,
+		&session.Offset, &session.ChatID, &session.Status, &session.ExpiresAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upload session: %w", err)
+	}
+
+	return session, nil
+}
+
+// Append writes the next chunk of the file. offset must equal the session's
+// current offset; bytes beyond the declared size are rejected. It returns the
+// new offset. The session row stays locked while the chunk is written, so a
+// concurrent request for the same session fails with ErrBusy instead of
+// writing to the staged file at the same time.
+func (m *Manager) Append(ctx context.Context, session *Session, offset int64, reader io.Reader) (int64, error) {
+	// The request context is cancelled when the client disconnects, and what
+	// arrived until then is still recorded
+	dbCtx := context.WithoutCancel(ctx)
+
+	tx, err := m.pool.Begin(ctx)
+	if err != nil {
+		return session.Offset, fmt.Errorf("failed to begin upload transaction: %w", err)
+	}
+	defer tx.Rollback(dbCtx)
+
+	err = tx.QueryRow(ctx, `
+		SELECT upload_offset, status
+		FROM upload_sessions
+		WHERE id = $1
+		FOR UPDATE SKIP LOCKED
+	`, session.ID).Scan(&session.Offset, &session.Status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return session.Offset, ErrBusy
+	}
+	if err != nil {
+		return session.Offset, fmt.Errorf("failed to lock upload session: %w", err)
+	}
+
+	if session.Status != StatusActive {
+		return session.Offset, ErrNotActive
+	}
+	if offset != session.Offset {
+		return session.Offset, ErrOffsetMismatch
+	}
+
+	file, err := os.OpenFile(m.partPath(session.ID), os.O_WRONLY, 0o600)
+	if err != nil {
+		return session.Offset, fmt.Errorf("failed to open upload staging file: %w", err)
+	}
+	defer file.Close()
+
+	// Anything written past the offset by an interrupted request is discarded
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return session.Offset, fmt.Errorf("failed to seek upload staging file: %w", err)
+	}
+
+	remaining := session.Size - offset
+	written, err := io.Copy(file, io.LimitReader(reader, remaining+1))
+	if written > remaining {
+		file.Truncate(offset)
+		return session.Offset, fmt.Errorf("chunk exceeds declared upload size")
+	}
+
+	// Keep what arrived even if the connection dropped, so the client can resume
+	newOffset := offset + written
+	if truncErr := file.Truncate(newOffset); truncErr != nil {
+		return session.Offset, fmt.Errorf("failed to truncate upload staging file: %w", truncErr)
+	}
+	if written > 0 {
+		if syncErr := file.Sync(); syncErr != nil {
+			return session.Offset, fmt.Errorf("failed to sync upload staging file: %w", syncErr)
+		}
+		_, updateErr := tx.Exec(dbCtx, `
+			UPDATE upload_sessions
+			SET upload_offset = $2, updated_at = NOW()
+			WHERE id = $1
+		`, session.ID, newOffset)
+		if updateErr != nil {
+			return session.Offset, fmt.Errorf("failed to update upload offset: %w", updateErr)
+		}
+		if commitErr := tx.Commit(dbCtx); commitErr != nil {
+			return session.Offset, fmt.Errorf("failed to update upload offset: %w", commitErr)
+		}
+		session.Offset = newOffset
+	}
+
+	if err != nil {
+		return session.Offset, fmt.Errorf("failed to write upload chunk: %w", err)
+	}
+	return session.Offset, nil
+}
+
+// Open opens the staged file of a fully received session for reading
+func (m *Manager) Open(session *Session) (*os.File, error) {
+	if session.Offset != session.Size {
+		return nil, fmt.Errorf("upload is incomplete: %d of %d bytes received", session.Offset, session.Size)
+	}
+	return os.Open(m.partPath(session.ID))
+}
+
+// Complete marks a session completed and removes its staged file
+func (m *Manager) Complete(ctx context.Context, session *Session, documentID string) error {
+	_, err := m.pool.Exec(ctx, `
+		UPDATE upload_sessions
+		SET status = 'completed', document_id = $2, updated_at = NOW()
+		WHERE id = $1
+	`, session.ID, documentID)
+	if err != nil {
+		return fmt.Errorf("failed to complete upload session: %w", err)
+	}
+
+	session.Status = StatusCompleted
+	os.Remove(m.partPath(session.ID))
+	return nil
+}
+
+// Delete aborts a session and removes its staged file
+func (m *Manager) Delete(ctx context.Context, session *Session) error {
+	if _, err := m.pool.Exec(ctx, "DELETE FROM upload_sessions WHERE id = $1", session.ID); err != nil {
+		return fmt.Errorf("failed to delete upload session: %w", err)
+	}
+	os.Remove(m.partPath(session.ID))
+	return nil
+}
+
+// CleanupExpired deletes expired sessions and their staged files
+func (m *Manager) CleanupExpired(ctx context.Context) (int, error) {
+	rows, err := m.pool.Query(ctx, `
+		DELETE FROM upload_sessions
+		WHERE expires_at <= NOW()
+		RETURNING id
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired upload sessions: %w", err)
+	}
+	defer rows.Close()
+
+	removed := 0
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return removed, fmt.Errorf("failed to scan upload session id: %w", err)
+		}
+		os.Remove(m.partPath(id))
+		removed++
+	}
+	return removed, rows.Err()
+}
+
+// partPath returns the staging file path for a session
+func (m *Manager) partPath(id string) string {
+	return filepath.Join(m.stagingDir, id+".part")
+}
+
+// Session represents a resumable upload
+type Session struct {
+	ID        string    `json:"upload_id"`
+	UserID    string    `json:"-"`
+	Filename  string    `json:"filename"`
+	Size      int64     `json:"size"`
+	Offset    int64     `json:"offset"`
+	ChatID    string    `json:"chat_id,omitempty"`
+	Status    string    `json:"status"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -22,7 +22,7 @@ func ValidateStruct(s interface{}) error {
 		for _, err := range err.(validator.ValidationErrors) {
 			errors = append(errors, fmt.Sprintf("Field '%s' failed validation: %s", err.Field(), err.Tag()))
 		}
-		return fmt.Errorf(strings.Join(errors, ", "))
+		return fmt.Errorf("%s", strings.Join(errors, ", "))
 	}
 	return nil
 }
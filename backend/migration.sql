@@ -21,3 +21,156 @@ ADD COLUMN IF NOT EXISTS title TEXT;
 CREATE INDEX IF NOT EXISTS idx_documents_created_at ON documents(created_at);
 CREATE INDEX IF NOT EXISTS idx_documents_processing_status ON documents(processing_status);
 CREATE INDEX IF NOT EXISTS idx_chunks_created_at ON chunks(created_at);
+
+-- Durable ingestion job queue
+CREATE TABLE IF NOT EXISTS ingestion_jobs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    document_id UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    payload JSONB,
+    status TEXT NOT NULL CHECK (status IN ('queued','running','completed','dead')) DEFAULT 'queued',
+    attempts INT NOT NULL DEFAULT 0,
+    max_attempts INT NOT NULL DEFAULT 5,
+    last_error TEXT,
+    run_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    locked_by TEXT,
+    locked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ingestion_jobs_status_run_at ON ingestion_jobs(status, run_at);
+CREATE INDEX IF NOT EXISTS idx_ingestion_jobs_document_id ON ingestion_jobs(document_id);
+
+-- Content-hash lookups for upload deduplication
+CREATE INDEX IF NOT EXISTS idx_documents_checksum ON documents(checksum);
+CREATE INDEX IF NOT EXISTS idx_documents_user_checksum ON documents(user_id, checksum);
+
+-- Record which embedding model produced each chunk's vector
+ALTER TABLE chunks 
+ADD COLUMN IF NOT EXISTS embedding_model TEXT;
+
+ALTER TABLE chunks 
+ADD COLUMN IF NOT EXISTS embedding_version TEXT;
+
+ALTER TABLE chunks 
+ADD COLUMN IF NOT EXISTS embedding_dimension INT;
+
+-- Chunks stored before versioning were embedded with Gemini embedding-001
+UPDATE chunks
+SET embedding_model = 'gemini/models/embedding-001', embedding_version = '1', embedding_dimension = 768
+WHERE embedding_model IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_chunks_embedding_model ON chunks(embedding_model, embedding_version);
+
+-- Subscription plan, used for per-plan upload limits
+ALTER TABLE users 
+ADD COLUMN IF NOT EXISTS plan TEXT DEFAULT 'free';
+
+-- Resumable upload sessions; received bytes are staged on disk
+CREATE TABLE IF NOT EXISTS upload_sessions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    filename TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    upload_offset BIGINT NOT NULL DEFAULT 0,
+    chat_id UUID,
+    status TEXT NOT NULL CHECK (status IN ('active','completed')) DEFAULT 'active',
+    document_id UUID REFERENCES documents(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_upload_sessions_user_id ON upload_sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_upload_sessions_expires_at ON upload_sessions(expires_at);
+
+-- Storage path of each document's file, used to delete it with the document
+-- and to find orphaned files
+ALTER TABLE documents 
+ADD COLUMN IF NOT EXISTS storage_path TEXT;
+
+-- Documents uploaded before this column existed have a Supabase public URL
+UPDATE documents
+SET storage_path = regexp_replace(source_url, '^.*/object/public/[^/]+/', '')
+WHERE storage_path IS NULL AND source_url LIKE '%/object/public/%';
+
+CREATE INDEX IF NOT EXISTS idx_documents_storage_path ON documents(storage_path);
+
+-- Batches group the documents expanded from one uploaded archive
+CREATE TABLE IF NOT EXISTS document_batches (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    filename TEXT NOT NULL,
+    skipped JSONB,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_document_batches_user_id ON document_batches(user_id);
+
+ALTER TABLE documents 
+ADD COLUMN IF NOT EXISTS batch_id UUID REFERENCES document_batches(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_documents_batch_id ON documents(batch_id);
+
+-- Embedding tokens used per user and calendar month, for quotas
+CREATE TABLE IF NOT EXISTS embedding_usage (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    month DATE NOT NULL,
+    tokens BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (user_id, month)
+);
+
+-- Full-text index of chunk content, searched alongside the embeddings
+ALTER TABLE chunks 
+ADD COLUMN IF NOT EXISTS content_tsv tsvector GENERATED ALWAYS AS (to_tsvector('english', content)) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_chunks_content_tsv ON chunks USING GIN (content_tsv);
+
+-- At most one queued or running job per document and kind; older duplicates
+-- are retired first so the index can be built
+UPDATE ingestion_jobs
+SET status = 'dead', last_error = 'superseded by a duplicate job', locked_by = NULL, locked_at = NULL, updated_at = NOW()
+WHERE id IN (
+    SELECT id FROM (
+        SELECT id, row_number() OVER (
+            PARTITION BY document_id, kind
+            ORDER BY status = 'running' DESC, created_at
+        ) AS rn
+        FROM ingestion_jobs
+        WHERE status IN ('queued','running')
+    ) ranked
+    WHERE rn > 1
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ingestion_jobs_active_document
+ON ingestion_jobs(document_id, kind) WHERE status IN ('queued','running');
+
+-- Let chunks.embedding hold vectors of any dimension, so a model with another
+-- EMBEDDING_DIMENSION can be re-indexed alongside the current one. ANN
+-- indexes on the fixed-width column cannot survive the change; the API
+-- rebuilds them as partial expression indexes for the configured dimension.
+DO $$
+DECLARE
+    index_name TEXT;
+BEGIN
+    IF (SELECT atttypmod FROM pg_attribute
+        WHERE attrelid = 'chunks'::regclass AND attname = 'embedding') <> -1 THEN
+        FOR index_name IN
+            SELECT ic.relname
+            FROM pg_index i
+            JOIN pg_class ic ON ic.oid = i.indexrelid
+            JOIN pg_am am ON am.oid = ic.relam
+            WHERE i.indrelid = 'chunks'::regclass AND am.amname IN ('hnsw', 'ivfflat')
+        LOOP
+            EXECUTE format('DROP INDEX IF EXISTS %I', index_name);
+        END LOOP;
+
+        ALTER TABLE chunks ALTER COLUMN embedding TYPE vector;
+    END IF;
+END $$;
+
+UPDATE chunks
+SET embedding_dimension = vector_dims(embedding)
+WHERE embedding_dimension IS NULL AND embedding IS NOT NULL;